@@ -0,0 +1,517 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+/* authConfig holds the credentials of the crawl, read from the AUTH_CONFIG file or the auth object of a job
+	Hosts: The credentials and headers sent to every host, a key starting with *. matches the subdomains of
+		the domain that follows it
+	CookiesFile: A Netscape cookies.txt file whose cookies are loaded into the cookie jar before the crawl
+	Login: A form submitted before the crawl, the cookies it sets are sent with the requests of the crawl
+	cookies: The cookies read from CookiesFile
+ */
+
+type authConfig struct {
+	Hosts       map[string]hostAuth `json:"hosts,omitempty"`
+	CookiesFile string              `json:"cookies_file,omitempty"`
+	Login       *formLogin          `json:"login,omitempty"`
+	cookies     []seedCookie
+}
+
+/* hostAuth holds the credentials and headers sent to a host
+	Username, Password: The credentials of the HTTP basic authentication
+	BearerToken: The token sent in the Authorization header, it can not be combined with Username
+	Headers: Headers added to every request
+ */
+
+type hostAuth struct {
+	Username    string            `json:"username,omitempty"`
+	Password    string            `json:"password,omitempty"`
+	BearerToken string            `json:"bearer_token,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+/* formLogin is a login form submitted before the crawl begins
+	URL: The uri of the login page
+	Form: A CSS selector of the form, the first form with a password field is used if empty. The page is
+		posted to directly if it does not have a form
+	Fields: The values filled into the inputs of the form by name. The other inputs such as CSRF tokens keep
+		the values of the page
+	Check: A text the page returned by the login must contain, e.g. the label of the logout link
+ */
+
+type formLogin struct {
+	URL    string            `json:"url"`
+	Form   string            `json:"form,omitempty"`
+	Fields map[string]string `json:"fields"`
+	Check  string            `json:"check,omitempty"`
+	form   cascadia.Sel
+}
+
+/* seedCookie is a cookie loaded into the cookie jar with the uri it is set for
+ */
+
+type seedCookie struct {
+	uri    *url.URL
+	cookie *http.Cookie
+}
+
+/* authTransport adds the credentials and headers of the host of every request, including the redirects
+	and the requests of the sitemap and the broken link checker
+ */
+
+type authTransport struct {
+	base  http.RoundTripper
+	hosts map[string]hostAuth
+}
+
+//The value that replaces the secrets of a job in the responses of the control API
+const redactedValue = "***"
+
+//A value of the auth file that is entirely a $VAR or ${VAR} reference to an env variable
+var envReference = regexp.MustCompile(`^\$(?:\{([A-Za-z_][A-Za-z0-9_]*)\}|([A-Za-z_][A-Za-z0-9_]*))$`)
+
+/*  The function reads the file of the AUTH_CONFIG env variable. The credentials, headers and login fields
+	that are a $VAR or ${VAR} reference are replaced with the values of the env variables so that the secrets
+	do not have to be saved in the file. If the file can not be read or is invalid an error message is
+	generated and the program exits.
+	Returns:
+		An authConfig with the credentials set by the user
+ */
+
+func getAuthConfig() authConfig {
+	var config authConfig
+	if os.Getenv("AUTH_CONFIG") == "" {
+		return config
+	}
+	data, err := os.ReadFile(os.Getenv("AUTH_CONFIG"))
+	if err == nil {
+		err = json.Unmarshal(data, &config)
+	}
+	if err == nil {
+		for host, credentials := range config.Hosts {
+			credentials.Username = expandReference(credentials.Username)
+			credentials.Password = expandReference(credentials.Password)
+			credentials.BearerToken = expandReference(credentials.BearerToken)
+			for name, value := range credentials.Headers {
+				credentials.Headers[name] = expandReference(value)
+			}
+			config.Hosts[host] = credentials
+		}
+		if config.Login != nil {
+			for name, value := range config.Login.Fields {
+				config.Login.Fields[name] = expandReference(value)
+			}
+		}
+		config, err = newAuthConfig(config)
+	}
+	if err != nil {
+		logger.Error("invalid auth file for AUTH_CONFIG env variable", "path", os.Getenv("AUTH_CONFIG"), "error", err)
+		os.Exit(1)
+	}
+	return config
+}
+
+/*  The function replaces a value of the auth file that is entirely a $VAR or ${VAR} reference with the value
+	of the env variable. The other values are kept as they are, so that the secrets can contain a $, and a
+	leading $$ is replaced with $ for the values that would otherwise be read as a reference
+	Arguments:
+		value: The value of the auth file
+	Returns:
+		A string with the value of the env variable or the value
+ */
+
+func expandReference(value string) string {
+	if strings.HasPrefix(value, "$$") {
+		return value[1:]
+	}
+	match := envReference.FindStringSubmatch(value)
+	if match == nil {
+		return value
+	}
+	return os.Getenv(match[1] + match[2])
+}
+
+/*  The function validates the credentials, reads the cookies file and compiles the selector of the login form
+	Arguments:
+		config: The authConfig read from the auth file or the job request
+	Returns:
+		The authConfig ready to be used by a Crawler
+		An error naming the first invalid option
+ */
+
+func newAuthConfig(config authConfig) (authConfig, error) {
+	for host, credentials := range config.Hosts {
+		if strings.TrimPrefix(host, "*.") == "" {
+			return config, errors.New("empty host in hosts")
+		}
+		if credentials.Username != "" && credentials.BearerToken != "" {
+			return config, errors.New("host " + host + ": username and bearer_token can not be combined")
+		}
+	}
+	if config.CookiesFile != "" {
+		file, err := os.Open(config.CookiesFile)
+		if err != nil {
+			return config, err
+		}
+		config.cookies, err = parseCookiesFile(file)
+		file.Close()
+		if err != nil {
+			return config, fmt.Errorf("cookies_file: %w", err)
+		}
+	}
+	if config.Login != nil {
+		login := *config.Login
+		loginURL, err := url.Parse(login.URL)
+		if err != nil || (loginURL.Scheme != "http" && loginURL.Scheme != "https") || loginURL.Host == "" {
+			return config, errors.New("login: invalid url " + login.URL)
+		}
+		if len(login.Fields) == 0 {
+			return config, errors.New("login: fields are required")
+		}
+		if login.Form != "" {
+			if login.form, err = cascadia.Parse(login.Form); err != nil {
+				return config, fmt.Errorf("login: invalid form selector: %w", err)
+			}
+		}
+		config.Login = &login
+	}
+	return config, nil
+}
+
+/*  The function returns a copy of the authConfig that can be shown in the status of a job. The passwords,
+	bearer tokens, header values and login field values are replaced with ***
+	Returns:
+		A pointer to the redacted authConfig
+ */
+
+func (config authConfig) redacted() *authConfig {
+	redacted := authConfig{CookiesFile: config.CookiesFile}
+	if config.Hosts != nil {
+		redacted.Hosts = map[string]hostAuth{}
+	}
+	for host, credentials := range config.Hosts {
+		hidden := hostAuth{Username: credentials.Username, Headers: redactValues(credentials.Headers)}
+		if credentials.Password != "" {
+			hidden.Password = redactedValue
+		}
+		if credentials.BearerToken != "" {
+			hidden.BearerToken = redactedValue
+		}
+		redacted.Hosts[host] = hidden
+	}
+	if config.Login != nil {
+		login := *config.Login
+		login.Fields = redactValues(login.Fields)
+		redacted.Login = &login
+	}
+	return &redacted
+}
+
+/*  The function returns a copy of a map with every value replaced with ***
+	Arguments:
+		values: The map of names to secret values
+	Returns:
+		The redacted map, nil if values is nil
+ */
+
+func redactValues(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(values))
+	for name := range values {
+		redacted[name] = redactedValue
+	}
+	return redacted
+}
+
+/*  The function parses a cookies file in the Netscape format used by curl and the browser extensions
+	Every line holds the domain, the subdomain flag, the path, the secure flag, the expiry as a unix time,
+	the name and the value of a cookie separated by tabs. The #HttpOnly_ prefix marks http only cookies
+	Arguments:
+		r: An io.Reader with the content of the file
+	Returns:
+		The seedCookies of the file
+		An error with the number of the first invalid line
+ */
+
+func parseCookiesFile(r io.Reader) ([]seedCookie, error) {
+	var cookies []seedCookie
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, errors.New("line " + strconv.Itoa(number) + ": expected 7 fields separated by tabs")
+		}
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, errors.New("line " + strconv.Itoa(number) + ": invalid expiry " + fields[4])
+		}
+		cookie := &http.Cookie{Name: fields[5], Value: fields[6], Path: fields[2],
+			Secure: strings.EqualFold(fields[3], "TRUE"), HttpOnly: httpOnly}
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = fields[0]
+		}
+		if expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+		}
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		cookies = append(cookies, seedCookie{
+			uri:    &url.URL{Scheme: scheme, Host: strings.TrimPrefix(fields[0], "."), Path: fields[2]},
+			cookie: cookie,
+		})
+	}
+	return cookies, scanner.Err()
+}
+
+/*  The function returns the credentials of a host
+	Arguments:
+		hosts: The credentials by host
+		host: The hostname of the request
+	Returns:
+		The hostAuth of the host or of the closest *. domain
+		A false value if no credentials are set for the host
+ */
+
+func hostCredentials(hosts map[string]hostAuth, host string) (hostAuth, bool) {
+	if credentials, ok := hosts[host]; ok {
+		return credentials, true
+	}
+	for domain := host; strings.Contains(domain, "."); {
+		domain = domain[strings.Index(domain, ".")+1:]
+		if credentials, ok := hosts["*."+domain]; ok {
+			return credentials, true
+		}
+	}
+	return hostAuth{}, false
+}
+
+/*  The function adds the credentials and headers of the host to a request. The headers already set on the
+	request, e.g. by a requestHook, are kept
+	Arguments:
+		req: The request
+	Returns:
+		The response of the base http.RoundTripper
+ */
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	credentials, ok := hostCredentials(t.hosts, req.URL.Hostname())
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for name, value := range credentials.Headers {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, value)
+		}
+	}
+	if req.Header.Get("Authorization") == "" {
+		if credentials.Username != "" {
+			req.SetBasicAuth(credentials.Username, credentials.Password)
+		} else if credentials.BearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+credentials.BearerToken)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+/*  The function submits the login form. The inputs of the form are filled with the fields of the login and
+	the form is sent to its action with its method, the cookies set by the responses are kept in the jar
+	Arguments:
+		ctx: The context of the crawl
+	Returns:
+		An error if the login page or the form could not be fetched, returned an error status or the page
+		returned by the login does not contain the check text
+ */
+
+func (c *Crawler) login(ctx context.Context) error {
+	login := c.config.auth.Login
+	action, method, values := login.URL, http.MethodPost, url.Values{}
+	body, err := c.loginRequest(ctx, http.MethodGet, login.URL, nil)
+	if err != nil {
+		return err
+	}
+	if form := loginForm(parseDocument(strings.NewReader(body)), login.form); form != nil {
+		if value, ok := nodeAttr(form, "action"); ok && strings.TrimSpace(value) != "" {
+			action = absoluteURL(strings.TrimSpace(value), login.URL)
+		}
+		value, _ := nodeAttr(form, "method")
+		method = strings.ToUpper(strings.TrimSpace(value))
+		if method != http.MethodPost {
+			method = http.MethodGet
+		}
+		values = formValues(form)
+	}
+	for name, value := range login.Fields {
+		values.Set(name, value)
+	}
+	body, err = c.loginRequest(ctx, method, action, values)
+	if err != nil {
+		return err
+	}
+	if login.Check != "" && !strings.Contains(body, login.Check) {
+		return errors.New("the page returned by the login does not contain " + strconv.Quote(login.Check))
+	}
+	logger.Info("logged in", "uri", action)
+	return nil
+}
+
+/*  The function sends a request of the login
+	Arguments:
+		ctx: The context of the crawl
+		method: GET or POST
+		uri: The uri of the request
+		values: The form values sent in the query of a GET request or the body of a POST request
+	Returns:
+		A string with the body of the response
+		An error if the request failed or returned a 4xx or 5xx status code
+ */
+
+func (c *Crawler) loginRequest(ctx context.Context, method string, uri string, values url.Values) (string, error) {
+	var body io.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader(values.Encode())
+	} else if values != nil {
+		parsed, err := url.Parse(uri)
+		if err != nil {
+			return "", err
+		}
+		parsed.RawQuery = values.Encode()
+		uri = parsed.String()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, uri, body)
+	if err != nil {
+		return "", err
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("%s %s returned status code %d", method, uri, resp.StatusCode)
+	}
+	content, _ := readBody(resp.Body, c.config.content.maxBodySize)
+	return content, nil
+}
+
+/*  The function finds the login form of a page
+	Arguments:
+		doc: The parsed login page
+		selector: The selector of the form, nil for the first form with a password field
+	Returns:
+		The form element, nil if the page does not have a matching form
+ */
+
+func loginForm(doc *html.Node, selector cascadia.Sel) *html.Node {
+	if selector != nil {
+		return cascadia.Query(doc, selector)
+	}
+	for _, form := range cascadia.QueryAll(doc, cascadia.MustCompile("form")) {
+		if cascadia.Query(form, cascadia.MustCompile(`input[type=password]`)) != nil {
+			return form
+		}
+	}
+	return nil
+}
+
+/*  The function returns the values a browser would submit for a form before the user fills it
+	Arguments:
+		form: The form element
+	Returns:
+		The url.Values of the named inputs, checked checkboxes and radio buttons, textareas and selects
+ */
+
+func formValues(form *html.Node) url.Values {
+	values := url.Values{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			name, _ := nodeAttr(n, "name")
+			switch {
+			case name == "":
+			case n.DataAtom == atom.Input:
+				inputType, _ := nodeAttr(n, "type")
+				value, _ := nodeAttr(n, "value")
+				_, checked := nodeAttr(n, "checked")
+				switch strings.ToLower(inputType) {
+				case "submit", "button", "image", "reset", "file":
+				case "checkbox", "radio":
+					if checked {
+						if value == "" {
+							value = "on"
+						}
+						values.Add(name, value)
+					}
+				default:
+					values.Add(name, value)
+				}
+			case n.DataAtom == atom.Textarea:
+				values.Add(name, nodeText(n))
+			case n.DataAtom == atom.Select:
+				if option := selectedOption(n); option != nil {
+					value, ok := nodeAttr(option, "value")
+					if !ok {
+						value = nodeText(option)
+					}
+					values.Add(name, value)
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(form)
+	return values
+}
+
+/*  The function returns the option of a select element submitted by default
+	Arguments:
+		n: The select element
+	Returns:
+		The selected option or the first option, nil if the select has no options
+ */
+
+func selectedOption(n *html.Node) *html.Node {
+	options := cascadia.QueryAll(n, cascadia.MustCompile("option"))
+	for _, option := range options {
+		if _, selected := nodeAttr(option, "selected"); selected {
+			return option
+		}
+	}
+	if len(options) > 0 {
+		return options[0]
+	}
+	return nil
+}
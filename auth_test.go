@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseCookiesFile1(t *testing.T) {
+	testCookies, err := parseCookiesFile(strings.NewReader("# Netscape HTTP Cookie File\n\n" +
+		".example.com\tTRUE\t/\tTRUE\t2000000000\tsession\tabc\n" +
+		"#HttpOnly_www.example.com\tFALSE\t/app\tFALSE\t0\tconsent\t\r\n"))
+	if err != nil || len(testCookies) != 2 {
+		fmt.Println("parseCookiesFile returned invalid cookies")
+		fmt.Println(testCookies, err)
+		t.Fail()
+		return
+	}
+	testFirst, testSecond := testCookies[0], testCookies[1]
+	if testFirst.uri.String() != "https://example.com/" || testFirst.cookie.Domain != ".example.com" ||
+		testFirst.cookie.Expires.Unix() != 2000000000 || testSecond.uri.String() != "http://www.example.com/app" ||
+		testSecond.cookie.Domain != "" || !testSecond.cookie.HttpOnly || testSecond.cookie.Value != "" {
+		fmt.Println("parseCookiesFile returned invalid cookies")
+		fmt.Println(testFirst.uri, testFirst.cookie, testSecond.uri, testSecond.cookie)
+		t.Fail()
+	} else if _, err := parseCookiesFile(strings.NewReader("example.com\tFALSE\t/\n")); err == nil ||
+		err.Error() != "line 1: expected 7 fields separated by tabs" {
+		fmt.Println("parseCookiesFile accepted an invalid line")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for parseCookiesFile passed")
+	}
+}
+
+func TestNewAuthConfig1(t *testing.T) {
+	testCases := map[string]authConfig{
+		"host api.test.com: username and bearer_token can not be combined": {Hosts: map[string]hostAuth{"api.test.com": {Username: "a", BearerToken: "b"}}},
+		"empty host in hosts":        {Hosts: map[string]hostAuth{"*.": {BearerToken: "b"}}},
+		"login: invalid url /login":  {Login: &formLogin{URL: "/login", Fields: map[string]string{"user": "a"}}},
+		"login: fields are required": {Login: &formLogin{URL: "https://test.com/login"}},
+	}
+	for testExpected, testConfig := range testCases {
+		if _, err := newAuthConfig(testConfig); err == nil || err.Error() != testExpected {
+			fmt.Println("newAuthConfig did not return " + testExpected)
+			fmt.Println(err)
+			t.Fail()
+			return
+		}
+	}
+	testCredentials, ok := hostCredentials(map[string]hostAuth{"*.test.com": {BearerToken: "a"}, "www.test.com": {BearerToken: "b"}}, "api.eu.test.com")
+	if !ok || testCredentials.BearerToken != "a" {
+		fmt.Println("hostCredentials did not match the wildcard host")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for newAuthConfig passed")
+	}
+}
+
+func TestGetAuthConfig1(t *testing.T) {
+	testPath := filepath.Join(t.TempDir(), "auth.json")
+	_ = os.WriteFile(testPath, []byte(`{"hosts": {`+
+		`"wiki.test.com": {"username": "crawler", "password": "pa$$word"},`+
+		`"api.test.com": {"bearer_token": "${TEST_API_TOKEN}", "headers": {"X-Key": "$TEST_API_TOKEN", "X-Price": "$$5"}}},`+
+		`"login": {"url": "https://test.com/login", "fields": {"password": "a$b${c}"}}}`), 0644)
+	t.Setenv("AUTH_CONFIG", testPath)
+	t.Setenv("TEST_API_TOKEN", "token")
+	testConfig := getAuthConfig()
+	testWiki, testAPI := testConfig.Hosts["wiki.test.com"], testConfig.Hosts["api.test.com"]
+	if testWiki.Password != "pa$$word" || testConfig.Login.Fields["password"] != "a$b${c}" {
+		fmt.Println("getAuthConfig changed a value with a $")
+		fmt.Println(testWiki.Password, testConfig.Login.Fields)
+		t.Fail()
+	} else if testAPI.BearerToken != "token" || testAPI.Headers["X-Key"] != "token" || testAPI.Headers["X-Price"] != "$5" {
+		fmt.Println("getAuthConfig did not replace the env variable references")
+		fmt.Println(testAPI)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for getAuthConfig passed")
+	}
+}
+
+func TestAuthTransport1(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "crawler" || password != "secret" || r.Header.Get("X-Team") != "search" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			_, _ = w.Write([]byte(`<a href="/a">A</a>`))
+		}
+	}))
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.auth, _ = newAuthConfig(authConfig{Hosts: map[string]hostAuth{
+		"127.0.0.1": {Username: "crawler", Password: "secret", Headers: map[string]string{"X-Team": "search"}},
+	}})
+	testCrawler := newCrawler(testConfig)
+	testCrawler.output = io.Discard
+	var testLock sync.Mutex
+	testCodes := map[string]int{}
+	testCrawler.onPage = func(page pageResult) {
+		testLock.Lock()
+		defer testLock.Unlock()
+		testCodes[strings.TrimPrefix(page.URI, testServer.URL)] = page.StatusCode
+	}
+	testCrawler.Run(context.Background())
+	if len(testCodes) != 2 || testCodes[""] != 200 || testCodes["/a"] != 200 {
+		fmt.Println("The credentials were not sent to the host")
+		fmt.Println(testCodes)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for authTransport passed")
+	}
+}
+
+func TestLogin1(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<form action="/search"><input name="q"></form>` +
+			`<form method="post" action="/session"><input type="hidden" name="csrf" value="token">` +
+			`<input name="user"><input type="password" name="password"><input type="checkbox" name="remember">` +
+			`<input type="submit" name="go" value="Sign in"></form>`))
+	})
+	mux.HandleFunc("/session", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Method != http.MethodPost || r.PostForm.Encode() != (url.Values{"csrf": {"token"}, "user": {"crawler"}, "password": {"secret"}}).Encode() {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "ok", Path: "/"})
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "ok" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<a href="/logout">Logout</a>`))
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.auth, _ = newAuthConfig(authConfig{Login: &formLogin{URL: testServer.URL + "/login",
+		Fields: map[string]string{"user": "crawler", "password": "secret"}, Check: "Logout"}})
+	testCrawler := newCrawler(testConfig)
+	testCrawler.output = io.Discard
+	var testLock sync.Mutex
+	testCodes := map[string]int{}
+	testCrawler.onPage = func(page pageResult) {
+		testLock.Lock()
+		defer testLock.Unlock()
+		testCodes[strings.TrimPrefix(page.URI, testServer.URL)] = page.StatusCode
+	}
+	testCrawler.Run(context.Background())
+	testFailed := newCrawler(defaultCrawlConfig(testServer.URL))
+	testFailed.config.auth, _ = newAuthConfig(authConfig{Login: &formLogin{URL: testServer.URL + "/login",
+		Fields: map[string]string{"user": "crawler", "password": "wrong"}}})
+	if len(testCodes) != 2 || testCodes[""] != 200 || testCodes["/logout"] != 200 {
+		fmt.Println("The crawl did not use the session of the login")
+		fmt.Println(testCodes)
+		t.Fail()
+	} else if err := testFailed.login(context.Background()); err == nil || !strings.Contains(err.Error(), "status code 403") {
+		fmt.Println("login did not fail with a wrong password")
+		fmt.Println(err)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for login passed")
+	}
+}
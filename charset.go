@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+/*  The function detects the character encoding of a response body and converts it to UTF-8.
+	The encoding is detected from a byte order mark, the charset parameter of the Content-Type header
+	and a <meta charset> or <meta http-equiv> element in the first 1024 bytes, in that order.
+	A body without a declared encoding is treated as UTF-8 if it is valid UTF-8 and as windows-1252 otherwise
+	Arguments:
+		body: A string with the response body as it was received
+		contentTypeHeader: A string with the value of the Content-Type header
+	Returns:
+		A string with the body converted to UTF-8
+		A string with the name of the original encoding
+ */
+
+func transcodeBody(body string, contentTypeHeader string) (string, string) {
+	encoding, name, certain := charset.DetermineEncoding([]byte(body), contentTypeHeader)
+	if !certain && name == "windows-1252" && utf8.ValidString(body) {
+		return strings.TrimPrefix(body, "\ufeff"), "utf-8"
+	}
+	if name == "utf-8" {
+		return strings.TrimPrefix(body, "\ufeff"), name
+	}
+	decoded, err := encoding.NewDecoder().String(body)
+	if err != nil {
+		logger.Warn("error converting the response to utf-8", "charset", name, "error", err)
+		return body, name
+	}
+	return strings.TrimPrefix(decoded, "\ufeff"), name
+}
+
+/*  The function checks if a response is text that should be converted to UTF-8
+	Arguments:
+		contentType: The media type of the response
+	Returns:
+		A true value for HTML, XHTML and other text responses
+ */
+
+func isText(contentType string) bool {
+	return isHTML(contentType) || strings.HasPrefix(contentType, "text/")
+}
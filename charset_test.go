@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTranscodeBody1(t *testing.T) {
+	testBody, testCharset := transcodeBody("<p>caf\xe9</p>", "text/html; charset=ISO-8859-1")
+	if testBody != "<p>café</p>" || testCharset != "windows-1252" {
+		fmt.Println("transcodeBody did not convert the body using the Content-Type header")
+		fmt.Println(testBody, testCharset)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for transcodeBody passed")
+	}
+}
+
+func TestTranscodeBody2(t *testing.T) {
+	testBody, testCharset := transcodeBody(`<meta charset="shift_jis"><a href="/">`+"\x93\xfa\x96\x7b"+`</a>`, "text/html")
+	if testBody != `<meta charset="shift_jis"><a href="/">日本</a>` || testCharset != "shift_jis" {
+		fmt.Println("transcodeBody did not convert the body using the meta element")
+		fmt.Println(testBody, testCharset)
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for transcodeBody passed")
+	}
+}
+
+func TestTranscodeBody3(t *testing.T) {
+	testBody, testCharset := transcodeBody("\xef\xbb\xbf<p>plain</p>", "text/html; charset=windows-1252")
+	if testBody != "<p>plain</p>" || testCharset != "utf-8" {
+		fmt.Println("transcodeBody did not use the byte order mark")
+		fmt.Println(testBody, testCharset)
+		t.Fail()
+	}
+	testBody, testCharset = transcodeBody("<p>plain</p>", "text/html")
+	if testBody != "<p>plain</p>" || testCharset != "utf-8" {
+		fmt.Println("transcodeBody returned an invalid value for a body without an encoding")
+		fmt.Println(testBody, testCharset)
+		t.Fail()
+	} else {
+		fmt.Println("Test 3 for transcodeBody passed")
+	}
+}
+
+func TestFetchPageCharset1(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=windows-1252")
+		_, _ = w.Write([]byte("<a href=\"/\">\x93quoted\x94</a>"))
+	}))
+	defer testServer.Close()
+	testCrawler := newCrawler(defaultCrawlConfig(testServer.URL))
+	testResult := testCrawler.fetchPage(context.Background(), testServer.URL)
+	testAnchors := getAllAnchorsHTML(strings.NewReader(testResult.body))
+	if testResult.charset != "windows-1252" || len(testAnchors) != 1 || testAnchors[0].text != "“quoted”" {
+		fmt.Println("fetchPage did not convert the response to utf-8")
+		fmt.Println(testResult.charset, testAnchors)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for fetchPage charset passed")
+	}
+}
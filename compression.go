@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+/* compressionConfig holds the options for compressed transfers and compressed storage
+	acceptEncoding: Set from ACCEPT_ENCODING, the value of the Accept-Encoding header sent with every request
+	store: Set from STORE_COMPRESSION, the algorithm used to compress the responses saved on disk if any
+ */
+
+type compressionConfig struct {
+	acceptEncoding string
+	store          string
+}
+
+/* countingReader counts the bytes read from the underlying reader
+ */
+
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+const defaultAcceptEncoding = "br, zstd, gzip, deflate"
+
+//The file suffix used for each of the algorithms supported by STORE_COMPRESSION
+var compressionSuffixes = map[string]string{"gzip": ".gz", "zstd": ".zst", "br": ".br"}
+
+/*  The function reads the ACCEPT_ENCODING and STORE_COMPRESSION env variables. If an unsupported encoding
+	or algorithm is specified an error message is generated and the program exits.
+	Defaults to br, zstd, gzip, deflate and no compression on disk
+	Returns:
+		A compressionConfig with the options set by the user
+ */
+
+func getCompressionConfig() compressionConfig {
+	config, err := newCompressionConfig(os.Getenv("ACCEPT_ENCODING"), os.Getenv("STORE_COMPRESSION"))
+	if err != nil {
+		logger.Error("invalid value for ACCEPT_ENCODING or STORE_COMPRESSION env variable", "error", err)
+		os.Exit(1)
+	}
+	return config
+}
+
+/*  The function validates the accepted encodings and the compression algorithm of the responses saved on disk
+	Arguments:
+		acceptEncoding: A comma separated list of encodings, an empty string uses the default encodings
+		store: The algorithm used to compress the responses saved on disk, gzip, zstd, br or an empty string
+	Returns:
+		A compressionConfig with the options
+		An error if an unsupported encoding or algorithm is specified
+ */
+
+func newCompressionConfig(acceptEncoding string, store string) (compressionConfig, error) {
+	config := compressionConfig{acceptEncoding: defaultAcceptEncoding}
+	if acceptEncoding != "" {
+		var encodings []string
+		for _, encoding := range strings.Split(acceptEncoding, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			switch encoding {
+			case "br", "zstd", "gzip", "deflate", "identity":
+				encodings = append(encodings, encoding)
+			default:
+				return config, fmt.Errorf("unsupported encoding %q, supported values are br, zstd, gzip, deflate and identity", encoding)
+			}
+		}
+		config.acceptEncoding = strings.Join(encodings, ", ")
+	}
+	if store != "" {
+		config.store = strings.ToLower(store)
+		if _, ok := compressionSuffixes[config.store]; !ok {
+			return config, fmt.Errorf("unsupported compression %q, supported values are gzip, zstd and br", store)
+		}
+	}
+	return config, nil
+}
+
+func (counter *countingReader) Read(p []byte) (int, error) {
+	n, err := counter.reader.Read(p)
+	counter.count += int64(n)
+	return n, err
+}
+
+/*  The function wraps the response body with the decoders for the Content-Encoding of the response.
+	Encodings are removed in the reverse order they were applied
+	Arguments:
+		httpBody: An io.Reader with the response body as it was received
+		contentEncoding: A string with the value of the Content-Encoding header
+	Returns:
+		An io.Reader with the decoded response body that should be closed if it implements io.Closer
+		An error if an encoding is not supported or the body is not valid for the encoding
+ */
+
+func decodeContent(httpBody io.Reader, contentEncoding string) (io.Reader, error) {
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var err error
+		switch encoding := strings.ToLower(strings.TrimSpace(encodings[i])); encoding {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			httpBody, err = gzip.NewReader(httpBody)
+		case "deflate":
+			httpBody, err = newDeflateReader(httpBody)
+		case "br":
+			httpBody = brotli.NewReader(httpBody)
+		case "zstd":
+			var decoder *zstd.Decoder
+			if decoder, err = zstd.NewReader(httpBody); err == nil {
+				httpBody = decoder.IOReadCloser()
+			}
+		default:
+			err = fmt.Errorf("unsupported content encoding %q", encoding)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return httpBody, nil
+}
+
+/*  The function returns a reader for a deflate encoded body. The deflate encoding should be zlib wrapped
+	but some servers send raw deflate data so the zlib header is checked first
+	Arguments:
+		httpBody: An io.Reader with the deflate encoded body
+	Returns:
+		An io.Reader with the decoded body
+ */
+
+func newDeflateReader(httpBody io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(httpBody)
+	header, err := buffered.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+/*  The function wraps the file with a writer for the algorithm set in STORE_COMPRESSION
+	Arguments:
+		out: The io.Writer of the file
+		algorithm: A string with the compression algorithm or an empty string to write the file uncompressed
+	Returns:
+		An io.WriteCloser that must be closed before the file is closed
+ */
+
+func compressWriter(out io.Writer, algorithm string) io.WriteCloser {
+	switch algorithm {
+	case "gzip":
+		return gzip.NewWriter(out)
+	case "zstd":
+		encoder, _ := zstd.NewWriter(out)
+		return encoder
+	case "br":
+		return brotli.NewWriter(out)
+	}
+	return nopWriteCloser{out}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+var compressionTestBody = strings.Repeat(`<p><a href="/compressed">compressed link</a></p>`, 50)
+
+func compressTestBody(encoding string) []byte {
+	var buf bytes.Buffer
+	writer := compressWriter(&buf, encoding)
+	_, _ = writer.Write([]byte(compressionTestBody))
+	_ = writer.Close()
+	return buf.Bytes()
+}
+
+func TestDecodeContent1(t *testing.T) {
+	for _, encoding := range []string{"gzip", "br", "zstd"} {
+		testReader, err := decodeContent(bytes.NewReader(compressTestBody(encoding)), encoding)
+		if err != nil {
+			fmt.Println("decodeContent returned an error for " + encoding)
+			fmt.Println(err)
+			t.Fail()
+			continue
+		}
+		if testBody, _ := readBody(testReader, defaultMaxBodySize); testBody != compressionTestBody {
+			fmt.Println("decodeContent returned an invalid body for " + encoding)
+			t.Fail()
+		}
+	}
+	if _, err := decodeContent(strings.NewReader(""), "compress"); err == nil {
+		fmt.Println("decodeContent did not return an error for an unsupported encoding")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for decodeContent passed")
+	}
+}
+
+func TestFetchPageCompression1(t *testing.T) {
+	var testAcceptEncoding string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testAcceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "br")
+		_, _ = w.Write(compressTestBody("br"))
+	}))
+	defer testServer.Close()
+	testCrawler := newCrawler(defaultCrawlConfig(testServer.URL))
+	testResult := testCrawler.fetchPage(context.Background(), testServer.URL)
+	if testAcceptEncoding != defaultAcceptEncoding {
+		fmt.Println("fetchPage sent an invalid Accept-Encoding header " + testAcceptEncoding)
+		t.Fail()
+	} else if testResult.body != compressionTestBody || testResult.contentEncoding != "br" {
+		fmt.Println("fetchPage did not decode the response")
+		t.Fail()
+	} else if testResult.size != int64(len(compressionTestBody)) || testResult.wireSize != int64(len(compressTestBody("br"))) {
+		fmt.Println("fetchPage recorded invalid sizes for the response")
+		fmt.Println(testResult.wireSize, testResult.size)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for fetchPage compression passed")
+	}
+}
+
+func TestStoreFileCompression1(t *testing.T) {
+	testDirRoot, _ := filepath.Abs(filepath.Dir(os.Args[0]))
+	testDirRoot = testDirRoot + "/storeFileCompression1/"
+	_ = os.Mkdir(testDirRoot, 0755)
+	defer os.RemoveAll(testDirRoot)
+	testCrawler := newCrawler(defaultCrawlConfig("https://test.com"))
+	for _, compression := range []string{"gzip", "zstd", "br"} {
+		testFileName := testCrawler.storeFile(compressionTestBody, testDirRoot, ".html", compression)
+		testFile, _ := os.Open(testDirRoot + testFileName)
+		var testBody []byte
+		switch compression {
+		case "gzip":
+			testReader, _ := gzip.NewReader(testFile)
+			testBody, _ = ioutil.ReadAll(testReader)
+		case "zstd":
+			testReader, _ := zstd.NewReader(testFile)
+			testBody, _ = ioutil.ReadAll(testReader)
+			testReader.Close()
+		case "br":
+			testBody, _ = ioutil.ReadAll(brotli.NewReader(testFile))
+		}
+		testFile.Close()
+		if !strings.HasSuffix(testFileName, ".html"+compressionSuffixes[compression]) || string(testBody) != compressionTestBody {
+			fmt.Println("storeFile did not compress the file with " + compression)
+			t.Fail()
+			return
+		}
+	}
+	fmt.Println("Test 1 for storeFile compression passed")
+}
+
+func TestGetCompressionConfig1(t *testing.T) {
+	_ = os.Setenv("ACCEPT_ENCODING", "GZIP, identity")
+	_ = os.Setenv("STORE_COMPRESSION", "zstd")
+	testConfig := getCompressionConfig()
+	if testConfig.acceptEncoding != "gzip, identity" || testConfig.store != "zstd" {
+		fmt.Println("getCompressionConfig returned an invalid value")
+		fmt.Println(testConfig)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for getCompressionConfig passed")
+	}
+	_ = os.Setenv("ACCEPT_ENCODING", "")
+	_ = os.Setenv("STORE_COMPRESSION", "")
+}
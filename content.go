@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/* contentConfig holds the options that decide which responses are downloaded and how much of them is read
+	headFirst: Set from HEAD_FIRST, a HEAD request is sent before the GET to skip unwanted content types
+	maxBodySize: Set from MAX_BODY_SIZE, the maximum number of bytes read from a response body
+	downloadTypes: Set from DOWNLOAD_TYPES, the MIME types that are downloaded and stored on disk
+ */
+
+type contentConfig struct {
+	headFirst     bool
+	maxBodySize   int64
+	downloadTypes []string
+}
+
+/* storedPage is a record in the manifest.jsonl file written next to the responses saved on disk
+	Text responses are saved in UTF-8 and Charset holds the encoding they were served in. The responses that
+	were not downloaded because of their content type are recorded as Skipped without a File. Redirects holds
+	the redirect responses that led to FinalURI in the order they were followed
+ */
+
+type storedPage struct {
+	File            string           `json:"file"`
+	URI             string           `json:"uri"`
+	FinalURI        string           `json:"final_uri"`
+	StatusCode      int              `json:"status_code"`
+	ContentType     string           `json:"content_type"`
+	Charset         string           `json:"charset,omitempty"`
+	Truncated       bool             `json:"truncated"`
+	ContentEncoding string           `json:"content_encoding,omitempty"`
+	WireSize        int64            `json:"wire_size"`
+	Size            int64            `json:"size"`
+	Compression     string           `json:"compression,omitempty"`
+	Skipped         bool             `json:"skipped,omitempty"`
+	Redirects       []storedRedirect `json:"redirects,omitempty"`
+}
+
+/* storedRedirect is a redirect response of a storedPage
+ */
+
+type storedRedirect struct {
+	URI        string `json:"uri"`
+	StatusCode int    `json:"status_code"`
+}
+
+const defaultMaxBodySize = 10 << 20
+
+var defaultDownloadTypes = []string{"text/html", "application/xhtml+xml"}
+
+/*  The function reads the HEAD_FIRST, MAX_BODY_SIZE and DOWNLOAD_TYPES env variables
+	If the value of MAX_BODY_SIZE is invalid an error message is generated and the program exits.
+	Defaults to 10 MiB and text/html,application/xhtml+xml
+	Returns:
+		A contentConfig with the options set by the user
+ */
+
+func getContentConfig() contentConfig {
+	config := contentConfig{maxBodySize: defaultMaxBodySize, downloadTypes: defaultDownloadTypes}
+	if os.Getenv("HEAD_FIRST") != "" {
+		headFirst, err := strconv.ParseBool(os.Getenv("HEAD_FIRST"))
+		if err != nil {
+			logger.Warn("invalid value specified for HEAD_FIRST env variable, no HEAD requests will be sent",
+				"value", os.Getenv("HEAD_FIRST"))
+		}
+		config.headFirst = headFirst
+	}
+	if os.Getenv("MAX_BODY_SIZE") != "" {
+		maxBodySize, err := strconv.ParseInt(os.Getenv("MAX_BODY_SIZE"), 10, 64)
+		if err != nil || maxBodySize <= 0 {
+			logger.Error("invalid value for MAX_BODY_SIZE env variable", "value", os.Getenv("MAX_BODY_SIZE"))
+			os.Exit(1)
+		}
+		config.maxBodySize = maxBodySize
+	}
+	if os.Getenv("DOWNLOAD_TYPES") != "" {
+		config.downloadTypes = parseDownloadTypes(strings.Split(os.Getenv("DOWNLOAD_TYPES"), ","))
+	}
+	return config
+}
+
+/*  The function normalizes a list of MIME types dropping the empty entries
+	Arguments:
+		types: A slice of MIME types
+	Returns:
+		A slice of lower cased MIME types
+ */
+
+func parseDownloadTypes(types []string) []string {
+	var downloadTypes []string
+	for _, mediaType := range types {
+		if mediaType = strings.ToLower(strings.TrimSpace(mediaType)); mediaType != "" {
+			downloadTypes = append(downloadTypes, mediaType)
+		}
+	}
+	return downloadTypes
+}
+
+/*  The function returns the media type of a Content-Type header without its parameters
+	Arguments:
+		header: A string with the value of the Content-Type header
+	Returns:
+		The lower cased media type or an empty string if the header is missing or invalid
+ */
+
+func mediaType(header string) string {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+/*  The function checks if a media type matches one of the configured MIME types. A type can end with
+	a wildcard subtype to match all types of that kind. A response without a Content-Type is always allowed
+	Arguments:
+		contentType: The media type of the response
+		types: A slice of MIME types
+	Returns:
+		A true value if the media type matches
+ */
+
+func typeAllowed(contentType string, types []string) bool {
+	if contentType == "" {
+		return true
+	}
+	for _, allowed := range types {
+		if allowed == "*/*" || allowed == contentType ||
+			(strings.HasSuffix(allowed, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(allowed, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+/*  The function checks if a response should be parsed for links. Only HTML and XHTML responses are parsed
+	Arguments:
+		contentType: The media type of the response
+	Returns:
+		A true value for HTML and XHTML responses and responses without a Content-Type
+ */
+
+func isHTML(contentType string) bool {
+	return contentType == "" || contentType == "text/html" || contentType == "application/xhtml+xml"
+}
+
+/*  The function reads at most maxBodySize bytes from the response body
+	Arguments:
+		httpBody: An io.Reader with the response body
+		maxBodySize: The maximum number of bytes to read
+	Returns:
+		A string with the response body
+		A true value if the body was longer than maxBodySize and was truncated
+ */
+
+func readBody(httpBody io.Reader, maxBodySize int64) (string, bool) {
+	body, err := ioutil.ReadAll(io.LimitReader(httpBody, maxBodySize+1))
+	if err != nil {
+		logger.Warn("error reading the response body", "error", err)
+	}
+	if int64(len(body)) > maxBodySize {
+		return string(body[:maxBodySize]), true
+	}
+	return string(body), false
+}
+
+/*  The function returns the file extension used to save a response of the given media type
+	Arguments:
+		contentType: The media type of the response
+	Returns:
+		A string with the extension including the leading dot
+ */
+
+func extensionForType(contentType string) string {
+	if isHTML(contentType) {
+		return ".html"
+	}
+	extensions, err := mime.ExtensionsByType(contentType)
+	if err != nil || len(extensions) == 0 {
+		return ".bin"
+	}
+	return extensions[0]
+}
+
+/*  The function saves a downloaded response in the rootPath and appends a record for it to the manifest.jsonl file
+	Responses that failed or were skipped before a request was sent are not saved and the responses that were
+	not downloaded because of their content type are only recorded in the manifest
+	Arguments:
+		result: The fetchResult of the uri
+		body: A string with the body to save
+		rootPath: A string containing the path of the root directory
+ */
+
+func (c *Crawler) storePage(result fetchResult, body string, rootPath string) {
+	if result.err != nil || (result.skipped && result.statusCode == 0) {
+		return
+	}
+	record := storedPage{
+		URI:             result.uri,
+		FinalURI:        result.finalURI,
+		StatusCode:      result.statusCode,
+		ContentType:     result.contentType,
+		Charset:         result.charset,
+		Truncated:       result.truncated,
+		ContentEncoding: result.contentEncoding,
+		WireSize:        result.wireSize,
+		Size:            result.size,
+		Skipped:         result.skipped,
+	}
+	for _, hop := range result.redirects {
+		record.Redirects = append(record.Redirects, storedRedirect{URI: hop.uri, StatusCode: hop.statusCode})
+	}
+	if !result.skipped {
+		record.File = c.storeFile(body, rootPath, extensionForType(result.contentType), c.config.compression.store)
+		record.Compression = c.config.compression.store
+	}
+	line, _ := json.Marshal(record)
+	c.manifestLock.Lock()
+	defer c.manifestLock.Unlock()
+	manifest, err := os.OpenFile(rootPath+"manifest.jsonl", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logger.Error("error opening the manifest file", "error", err)
+		return
+	}
+	defer manifest.Close()
+	_, _ = manifest.Write(append(line, '\n'))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newContentTestServer(headRequests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			*headRequests++
+		}
+		if strings.HasSuffix(r.URL.Path, ".png") {
+			w.Header().Set("Content-Type", "image/png")
+		} else {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+}
+
+func TestTypeAllowed1(t *testing.T) {
+	testTypes := []string{"text/html", "image/*"}
+	if !typeAllowed("text/html", testTypes) || !typeAllowed("image/png", testTypes) || !typeAllowed("", testTypes) {
+		fmt.Println("typeAllowed rejected an allowed media type")
+		t.Fail()
+	} else if typeAllowed("application/pdf", testTypes) || typeAllowed("imagex/png", testTypes) {
+		fmt.Println("typeAllowed allowed a media type that is not configured")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for typeAllowed passed")
+	}
+}
+
+func TestReadBody1(t *testing.T) {
+	testBody, testTruncated := readBody(strings.NewReader("0123456789"), 4)
+	if testBody != "0123" || !testTruncated {
+		fmt.Println("readBody did not truncate the body " + testBody)
+		t.Fail()
+	}
+	testBody, testTruncated = readBody(strings.NewReader("0123456789"), 10)
+	if testBody != "0123456789" || testTruncated {
+		fmt.Println("readBody truncated a body within the limit " + testBody)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for readBody passed")
+	}
+}
+
+func TestFetchPageContentType1(t *testing.T) {
+	headRequests := 0
+	testServer := newContentTestServer(&headRequests)
+	defer testServer.Close()
+	testCrawler := newCrawler(defaultCrawlConfig(testServer.URL))
+	testCrawler.config.content = contentConfig{maxBodySize: 5, downloadTypes: defaultDownloadTypes}
+	testImage := testCrawler.fetchPage(context.Background(), testServer.URL+"/image.png")
+	testPage := testCrawler.fetchPage(context.Background(), testServer.URL+"/page")
+	if !testImage.skipped || testImage.body != "" || testImage.contentType != "image/png" {
+		fmt.Println("fetchPage downloaded a content type that is not in DOWNLOAD_TYPES")
+		t.Fail()
+	} else if testPage.skipped || testPage.body != "01234" || !testPage.truncated || testPage.contentType != "text/html" {
+		fmt.Println("fetchPage returned an invalid result for an html page")
+		fmt.Println(testPage)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for fetchPage content types passed")
+	}
+}
+
+func TestFetchPageContentType2(t *testing.T) {
+	headRequests := 0
+	testServer := newContentTestServer(&headRequests)
+	defer testServer.Close()
+	testCrawler := newCrawler(defaultCrawlConfig(testServer.URL))
+	testCrawler.config.content = contentConfig{headFirst: true, maxBodySize: defaultMaxBodySize, downloadTypes: defaultDownloadTypes}
+	testImage := testCrawler.fetchPage(context.Background(), testServer.URL+"/image.png")
+	if headRequests != 1 || !testImage.skipped || testImage.statusCode != 200 {
+		fmt.Println("fetchPage did not skip the response using a HEAD request")
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for fetchPage content types passed")
+	}
+}
+
+func TestStorePage1(t *testing.T) {
+	testDirRoot, _ := filepath.Abs(filepath.Dir(os.Args[0]))
+	testDirRoot = testDirRoot + "/storePage1/"
+	_ = os.Mkdir(testDirRoot, 0755)
+	defer os.RemoveAll(testDirRoot)
+	testResult := fetchResult{uri: "https://test.com/doc", finalURI: "https://test.com/doc", statusCode: 200,
+		contentType: "application/pdf", truncated: true}
+	testCrawler := newCrawler(defaultCrawlConfig("https://test.com"))
+	testCrawler.storePage(testResult, "%PDF", testDirRoot)
+	testCrawler.storePage(fetchResult{uri: "https://test.com/skipped", skipped: true}, "", testDirRoot)
+	manifest, err := os.Open(testDirRoot + "manifest.jsonl")
+	if err != nil {
+		fmt.Println("storePage did not write the manifest file")
+		t.FailNow()
+	}
+	defer manifest.Close()
+	var records []storedPage
+	scanner := bufio.NewScanner(manifest)
+	for scanner.Scan() {
+		var record storedPage
+		_ = json.Unmarshal(scanner.Bytes(), &record)
+		records = append(records, record)
+	}
+	if len(records) != 1 || !records[0].Truncated || !strings.HasSuffix(records[0].File, ".pdf") {
+		fmt.Println("storePage wrote an invalid manifest")
+		fmt.Println(records)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for storePage passed")
+	}
+}
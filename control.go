@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/* rateLimiter spaces the requests of all the threads of a Crawler evenly
+	interval: The time between two requests, 0 for no limit
+	next: The earliest time the next request can be sent
+ */
+
+type rateLimiter struct {
+	lock     sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+/*  The function checks the value of the env variable RATE_LIMIT which is the maximum number of requests
+	sent per second. If the value specified in the env variable is invalid an error message is generated
+	and the program exits.
+	Defaults to 0 which does not limit the requests
+	Returns:
+		A float64 with the number of requests per second
+ */
+
+func getRateLimit() float64 {
+	if os.Getenv("RATE_LIMIT") == "" {
+		return 0
+	}
+	rateLimit, err := strconv.ParseFloat(os.Getenv("RATE_LIMIT"), 64)
+	if err != nil || rateLimit < 0 {
+		logger.Error("invalid value for RATE_LIMIT env variable", "value", os.Getenv("RATE_LIMIT"))
+		os.Exit(1)
+	}
+	return rateLimit
+}
+
+/*  The function changes the number of requests sent per second
+	Arguments:
+		requestsPerSecond: The maximum number of requests per second, 0 for no limit
+ */
+
+func (limiter *rateLimiter) setRate(requestsPerSecond float64) {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+	limiter.interval = 0
+	if requestsPerSecond > 0 {
+		limiter.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+}
+
+/*  The function returns the number of requests sent per second
+	Returns:
+		A float64 with the number of requests per second, 0 if the requests are not limited
+ */
+
+func (limiter *rateLimiter) rate() float64 {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+	if limiter.interval == 0 {
+		return 0
+	}
+	return float64(time.Second) / float64(limiter.interval)
+}
+
+/*  The function blocks until the next request can be sent
+	Arguments:
+		ctx: The context of the crawl, the function returns when it is cancelled
+ */
+
+func (limiter *rateLimiter) wait(ctx context.Context) {
+	limiter.lock.Lock()
+	if limiter.interval == 0 {
+		limiter.lock.Unlock()
+		return
+	}
+	now := time.Now()
+	if limiter.next.Before(now) {
+		limiter.next = now
+	}
+	delay := limiter.next.Sub(now)
+	limiter.next = limiter.next.Add(limiter.interval)
+	limiter.lock.Unlock()
+	if delay <= 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+/*  The function changes the number of requests the Crawler sends per second while it is running
+	Arguments:
+		requestsPerSecond: The maximum number of requests per second, 0 for no limit
+ */
+
+func (c *Crawler) SetRateLimit(requestsPerSecond float64) {
+	c.limiter.setRate(requestsPerSecond)
+}
+
+/*  The function changes the maximum number of URIs crawled while the Crawler is running. URIs that were
+	already inserted into the queue are still crawled if the limit is lowered below their number
+	Arguments:
+		maxPages: The maximum number of URIs crawled, 0 for no limit
+ */
+
+func (c *Crawler) SetMaxPages(maxPages int64) {
+	atomic.StoreInt64(&c.config.maxPages, maxPages)
+}
+
+/*  The function pauses a running crawl or resumes a paused one. Once the requests in flight have finished
+	the state of the paused crawl is logged
+	Arguments:
+		ctx: The context of the crawl
+ */
+
+func (c *Crawler) togglePause(ctx context.Context) {
+	if c.Paused() {
+		c.Resume()
+		logger.Info("crawl resumed")
+		return
+	}
+	c.Pause()
+	logger.Info("crawl pausing, waiting for the requests in flight")
+	for atomic.LoadInt64(&c.activeCounter) > 0 && ctx.Err() == nil {
+		time.Sleep(50 * time.Millisecond)
+	}
+	snapshot := c.snapshot()
+	logger.Info("crawl paused", "visited", snapshot.Visited, "queued", snapshot.Queued, "errors", snapshot.Errors,
+		"threads", snapshot.Threads, "rate_limit", snapshot.RateLimit, "max_pages", snapshot.MaxPages,
+		"downloaded", formatBytes(snapshot.WireBytes))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter1(t *testing.T) {
+	var testLimiter rateLimiter
+	testLimiter.setRate(20)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		testLimiter.wait(context.Background())
+	}
+	testElapsed := time.Since(start)
+	if testElapsed < 100*time.Millisecond || testLimiter.rate() != 20 {
+		fmt.Println("rateLimiter did not space the requests")
+		fmt.Println(testElapsed)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for rateLimiter passed")
+	}
+}
+
+func TestRateLimiter2(t *testing.T) {
+	var testLimiter rateLimiter
+	testLimiter.setRate(1)
+	testLimiter.setRate(0)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		testLimiter.wait(context.Background())
+	}
+	if time.Since(start) > 100*time.Millisecond || testLimiter.rate() != 0 {
+		fmt.Println("rateLimiter limited the requests without a rate")
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for rateLimiter passed")
+	}
+}
+
+func TestGetRateLimit1(t *testing.T) {
+	_ = os.Setenv("RATE_LIMIT", "2.5")
+	testRateLimit := getRateLimit()
+	_ = os.Setenv("RATE_LIMIT", "")
+	if testRateLimit != 2.5 || getRateLimit() != 0 {
+		fmt.Println("getRateLimit returned an invalid value")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for getRateLimit passed")
+	}
+}
+
+func TestTogglePause1(t *testing.T) {
+	testCrawler := newCrawler(defaultCrawlConfig("https://test.com"))
+	testCrawler.togglePause(context.Background())
+	testPaused := testCrawler.Paused()
+	testCrawler.SetMaxPages(1)
+	testCrawler.SetRateLimit(4)
+	testSnapshot := testCrawler.snapshot()
+	testCrawler.togglePause(context.Background())
+	if !testPaused || testCrawler.Paused() {
+		fmt.Println("togglePause did not pause and resume the crawl")
+		t.Fail()
+	} else if testSnapshot.MaxPages != 1 || testSnapshot.RateLimit != 4 || !testCrawler.reserveInsert() ||
+		testCrawler.reserveInsert() {
+		fmt.Println("The settings of the crawl were not changed")
+		fmt.Println(testSnapshot)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for togglePause passed")
+	}
+}
@@ -2,47 +2,69 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
-	"sync"
 	"sync/atomic"
 	"time"
 )
 
-var insertCounter int64 //A counter to keep a track of the number of URIs inserted into the channel
-var visitedCounter int64 //A counter to keep a track of the number of URIs visited
-var inserted sync.Map //A syncMap to keep a track of the URIs parsed by the HTML
-
 func main() {
-	crawlURI := getCrawlURI()
-	crawlURI = checkValidBaseURL(crawlURI)
-	_,crawlErr := strconv.ParseBool(crawlURI)
-	if crawlErr==nil{
-		fmt.Println(crawlURI)
-	}
-	hostBaseURL := getBaseHostname(crawlURI)
-	writeOnDisk := checkWriteOnDisk()
-	queue := make(chan string)
-	done := make(chan bool)
-	insertInitialURI(crawlURI,queue)
-	rootPath := getRootPath(&writeOnDisk)
-	uriOutput := checkDisplay()
-	threads := getThreadCount()
-	createConcurrentThreads(done,queue,hostBaseURL, writeOnDisk, rootPath, uriOutput, threads, crawlURI)
-	close(done)
+	logger = getLogger()
+	startMetricsServer()
+	if serveAddr := os.Getenv("SERVE_ADDR"); serveAddr != "" {
+		serveAPI(serveAddr)
+		return
+	}
+	seed := getCrawlURI()
+	crawlURI, siteRoot, local := getLocalSeed(seed)
+	if !local {
+		crawlURI = checkValidBaseURL(seed)
+		_,crawlErr := strconv.ParseBool(crawlURI)
+		if crawlErr==nil{
+			os.Exit(1)
+		}
+	}
+	config := getCrawlConfig(crawlURI)
+	config.siteRoot = siteRoot
+	crawler := newCrawler(config)
+	stopProgress := crawler.startProgress(getProgressInterval())
+	stopPauseSignal := crawler.handlePauseSignal(context.Background())
+	crawler.Run(context.Background())
+	stopPauseSignal()
+	stopProgress()
+	crawler.printSummary()
+	if crawler.config.graph.enabled {
+		crawler.exportGraph()
+		crawler.printGraphSummary()
+	}
+	if crawler.config.report.enabled {
+		crawler.writeReport()
+	}
+	if crawler.config.seoAudit {
+		crawler.printAuditSummary()
+	}
+	if crawler.config.structured.enabled {
+		crawler.printStructuredDataSummary()
+	}
+	if crawler.config.linkCheck.enabled && crawler.printBrokenLinks() > 0 {
+		os.Exit(1)
+	}
 }
 
 //Specifies the usage instructions for the source code to be run
 
 func usage() {
-	_, _ = fmt.Fprintf(os.Stderr, "Please Use the module as : go run <filename>.go <URI>\n")
+	_, _ = fmt.Fprintf(os.Stderr, "Please Use the module as : go run . <URI>\n")
 }
 
 /* The function prompts the user with a message on how to run the source files in case
@@ -60,7 +82,7 @@ func getCrawlURI() string{
 		args = flag.Args()
 		if len(args) < 1 {
 			usage()
-			fmt.Println("Please specify start page")
+			logger.Error("please specify start page")
 			os.Exit(1)
 		}} else {
 		args = append(args,os.Getenv("CRAWL_URL"))
@@ -80,7 +102,7 @@ func checkValidBaseURL (URL string) string {
 		URL = "https://"+URL
 	}
 	if !strings.Contains(URL,".") {
-		fmt.Println("Invalid URI provided. No top level domain provided in the URI")
+		logger.Error("invalid URI provided, no top level domain provided in the URI", "uri", URL)
 		return "false"
 	}
 	return URL
@@ -96,8 +118,7 @@ func checkValidBaseURL (URL string) string {
 func getBaseHostname(crawlURI string) string{
 	hostURL, err := url.Parse(crawlURI)
 	if err!=nil{
-		fmt.Println("Invalid URL provided. Please find the error below:")
-		fmt.Println(err)
+		logger.Error("invalid URL provided", "uri", crawlURI, "error", err)
 		os.Exit(1)
 	}
 	return hostURL.Hostname()
@@ -110,22 +131,23 @@ func getBaseHostname(crawlURI string) string{
 func checkWriteOnDisk() bool {
 	writeOnDisk, diskError := strconv.ParseBool(os.Getenv("STORE_ON_DISK"))
 	if diskError != nil {
-		fmt.Println("Warning: Invalid Value supplied for STORE_ON_DISK env variable")
-		fmt.Println("No response will be saved to the disk")
+		logger.Warn("invalid value supplied for STORE_ON_DISK env variable, no response will be saved to the disk",
+			"value", os.Getenv("STORE_ON_DISK"))
 	}
 	return writeOnDisk
 }
 
-/* Inserts initial crawlURI into the queue to start processing
+/* Inserts initial crawlURI into the frontier to start processing
    Arguments:
 		crawlURI: A string that specifies the initial URI
  */
 
-func insertInitialURI(crawlURI string, queue chan string){
-	inserted.Store(crawlURI,true)
-	inserted.Store(crawlURI+"/",true)
-	go func() { queue <- crawlURI}()
-	atomic.AddInt64(&insertCounter,1)
+func (c *Crawler) insertInitialURI(crawlURI string){
+	c.inserted.Store(crawlURI,true)
+	c.inserted.Store(crawlURI+"/",true)
+	c.frontier.push(crawlURI,0,0)
+	atomic.AddInt64(&c.insertCounter,1)
+	frontierSize.Inc()
 }
 
 /*  The function returns the value of the rootPath if the value specified in the ROOT_PATH env variable is valid
@@ -141,7 +163,7 @@ func getRootPath(writeOnDisk *bool) string{
 	if *writeOnDisk{
 		rootPath = os.Getenv("ROOT_PATH")
 		if !checkValidDiskPath(rootPath){
-			fmt.Println("Not saving any files to disk")
+			logger.Warn("not saving any files to disk")
 			*writeOnDisk = false
 			rootPath = ""
 		}
@@ -157,8 +179,7 @@ func checkDisplay() bool{
 	uriOutputFlag := os.Getenv("DISPLAY_URI")
 	uriOutput, err:= strconv.ParseBool(uriOutputFlag)
 	if err != nil{
-		fmt.Println("Invalid value specified for display URI")
-		fmt.Println("No URI will be printed")
+		logger.Warn("invalid value specified for DISPLAY_URI env variable, no URI will be printed", "value", uriOutputFlag)
 		return false
 	}
 	return uriOutput
@@ -177,86 +198,43 @@ func getThreadCount() int64{
 		var err error
 		threads, err = strconv.ParseInt(os.Getenv("THREAD_COUNT"),10,64)
 		if err != nil{
-			fmt.Println("Invalid value for THREAD_COUNT env variable")
+			logger.Error("invalid value for THREAD_COUNT env variable", "value", os.Getenv("THREAD_COUNT"))
 			os.Exit(1)
 		}
 	} else {
-		threads = 5
+		threads = defaultThreadCount
 	}
 	return threads
 }
 
 
-/* The function takes the following arguments and starts execution with a default value of 5
-Arguments:
-  	Done : Bool channel to synchronize thread execution
-  	Queue: String Channel to put URIs in
-  	hostBaseURL: String with the base url of the URI with which program was run
-  	writeOnDisk: bool variable to check if a value was supplied to STORE_ON_DISK env variable
-  	rootPath: String with the root path on the disk to which response is to be saved
-	uriOutput: Bool fetched from DISPLAY_URI to determine if the visited URI should be printed
-	threads: An int64 with the number of threads fetched from THREAD_COUNT defaults to 5
-	crawlURI: A string variable with the initial URI provided that must be crawled
- */
-
-func createConcurrentThreads(done chan bool, queue chan string, hostBaseURL string, writeOnDisk bool, rootPath string, uriOutput bool, threads int64, crawlURI string) {
-	for i := 0; i < int(threads); i++ {
-		go func() {
-			for uri := range queue {
-				httpBody := fetchURI(uri)
-				httpBodyReader := uriOutputStore(httpBody,uriOutput,uri,writeOnDisk,rootPath)
-				links := getAllLinksHTML(httpBodyReader)
-				filterAndEnqueue(links, queue, hostBaseURL, crawlURI)
-				checkCounters(queue,done)
-			}
-			done <- true
-		}()
-	}
-	<-done
-}
-
-/*  The function checks the values of insertCounter and visitedCounter and checks for equality
-	On equality the function closes the queue channel and throws the flow back to the original function
-	Arguments:
-		queue: A string channel that is used to maintain a list of the URIs
-		done: A channel to synchronize the execution of multiple threads
-	Return:
-		If equal prints the number of URIs visited and closes queue channel and the flow is thrown back
- */
-
-func checkCounters(queue chan string, done chan bool)  {
-	if insertCounter == visitedCounter {
-		fmt.Println("Total Visited URIs: "+strconv.FormatInt(visitedCounter,10))
-		close(queue)
-		<- done
-	}
-}
-
-/* The function stores the string in the reader object and stores at the rootPath 
+/* The function saves the string in a new file at the rootPath. Files are numbered in the order they are saved
 	Arguments:
-		httpBody: A io reader containing the response body
+		s: A string with the response body
 		rootPath: A string containing the path of the root directory
+		extension: A string with the extension of the file including the leading dot
+		compression: A string with the algorithm to compress the file with or an empty string
 	Returns:
-		A string with the response body
+		A string with the name of the file
  */
 
-func storeOnDisk(httpBody io.Reader, rootPath string) string {
-	buf := new(bytes.Buffer)
-	_, bufReadFromErr := buf.ReadFrom(httpBody)
-	if bufReadFromErr!= nil{
-		fmt.Println("Error reading from buffer")
-		fmt.Println(bufReadFromErr)
+func (c *Crawler) storeFile(s string, rootPath string, extension string, compression string) string {
+	fileName := strconv.FormatInt(atomic.AddInt64(&c.storedCounter,1),10)+extension+compressionSuffixes[compression]
+	out, outCreateErr := os.Create(rootPath+fileName)
+	if outCreateErr!= nil{
+		logger.Error("error creating file", "file", fileName, "error", outCreateErr)
+		return ""
 	}
-	s := buf.String()
-	strCounter := strconv.FormatInt(visitedCounter,10)
-	out, _ := os.Create(rootPath+strCounter+".html")
 	defer out.Close()
-	_, outWriteStringErr := out.WriteString(s)
+	writer := compressWriter(out, compression)
+	_, outWriteStringErr := io.WriteString(writer, s)
 	if outWriteStringErr!= nil{
-		fmt.Println("Error writing string to file")
-		fmt.Println(outWriteStringErr)
+		logger.Error("error writing string to file", "file", fileName, "error", outWriteStringErr)
 	}
-	return s
+	if closeErr := writer.Close(); closeErr!= nil{
+		logger.Error("error writing string to file", "file", fileName, "error", closeErr)
+	}
+	return fileName
 }
 
 /*  The function checks the value of the rootPath variable and returns a boolean
@@ -265,13 +243,12 @@ func storeOnDisk(httpBody io.Reader, rootPath string) string {
 
 func checkValidDiskPath(rootPath string) bool{
 	if rootPath == ""{
-		fmt.Println("STORE_ON_DISK was set to True but no valid root path to create files was provided")
+		logger.Warn("STORE_ON_DISK was set to true but no valid root path to create files was provided")
 		return false
 	} else {
 		_, err := os.Open(rootPath)
 		if err!=nil {
-			fmt.Println("Invalid root directory")
-			fmt.Println(err)
+			logger.Warn("invalid root directory", "path", rootPath, "error", err)
 			return false
 		}
 	}
@@ -279,86 +256,176 @@ func checkValidDiskPath(rootPath string) bool{
 }
 
 /*  The function takes an array of strings containing all the links in the HTML response, converts the relative URIs
-	to absolute URIs, filters them based on the hostname and then inserts them into the queue channel to be processed
+	to absolute URIs, filters them based on the hostname of the crawlURI and then inserts them into the frontier to be processed
 	Arguments:
 		links: An array containing all the relative and absolute URIs
-		queue: A string channel to insert the URIs in
-		hostBaseURL: The hostname of the crawlURI (the initial URI provided by user)
-		crawlURI: The initial URI provided by the user to resolve references using
+		pageURI: The URI of the page the links were found on to resolve references using
+		depth: The depth of the URIs, one more than the depth of the page
  */
 
-func filterAndEnqueue(links []string, queue chan string, hostBaseURL string, crawlURI string) {
+func (c *Crawler) filterAndEnqueue(links []string, pageURI string, depth int) {
 	for _, link := range links {
-		absolute := absoluteURL(link, crawlURI)
+		absolute := absoluteURL(link, pageURI)
 		absoluteURL, er := url.Parse(absolute)
 		if er!=nil{
 			return
 		}
-		//Will only insert it into the channel if the hostname is same as the hostName of the URL supplied in args
-		if absoluteURL.Hostname() == hostBaseURL{
-			_, _ = inserted.LoadOrStore(absolute+"/",true)
-			_, er := inserted.LoadOrStore(absolute,true)
-			if er!=true{
-				atomic.AddInt64(&insertCounter,1)
-				go func() {
-					queue <- absolute }()
+		//Will only insert it into the frontier if the hostname is same as the hostName of the URL supplied in args
+		if absoluteURL.Hostname() == c.config.hostBaseURL && (c.config.siteRoot == "" || absoluteURL.Scheme == "file") &&
+			c.hooks.allowLink(absolute, pageURI, depth){
+			_, _ = c.inserted.LoadOrStore(absolute+"/",true)
+			_, er := c.inserted.LoadOrStore(absolute,true)
+			if er!=true && c.reserveInsert(){
+				c.frontier.push(absolute,depth,1)
+			} else if er {
+				c.frontier.link(absolute)
 			}
 		}
 	}
 }
 
-/*  The function creates an httpClient with a request timeout of 30 seconds.
-	It closes the response body io.ReadCloser object and returns a new io.Reader object with the response body
+/* fetchResult holds everything the crawler learned while fetching a single uri
+	uri: The uri that was requested
+	finalURI: The uri the response was served from after following redirects
+	statusCode: The status code of the response, 0 if the request failed
+	redirects: The redirect hops followed to reach finalURI
+	contentType: The media type of the response without its parameters
+	charset: The character encoding the body was served in before it was converted to UTF-8
+	contentEncoding: The Content-Encoding the body was transferred with
+	wireSize: The number of bytes of the body received over the network
+	size: The number of bytes of the body after it was decoded
+	duration: The time taken to fetch the uri and read its body
+	body: The response body as a string
+	truncated: Set when the body was longer than MAX_BODY_SIZE and was cut off
+	skipped: Set when the body was not downloaded because its content type is not in DOWNLOAD_TYPES
+	rendered: Set when the body is the html rendered by a browser instead of the body of the response
+	err: The error returned while fetching the uri if any
+ */
+
+type fetchResult struct {
+	uri             string
+	finalURI        string
+	statusCode      int
+	redirects       []redirectHop
+	contentType     string
+	charset         string
+	contentEncoding string
+	wireSize        int64
+	size            int64
+	duration        time.Duration
+	body            string
+	truncated       bool
+	skipped         bool
+	rendered        bool
+	err             error
+}
+
+/*  The function fetches the uri and returns a fetchResult with the status code and the complete response body
 	Arguments:
+		ctx: The context of the crawl, used to stop the rendering of the page
 		uri: A string with the value of the uri from which the response is to be fetched
 	Returns:
-		An io.Reader with the response body
+		A fetchResult for the uri
  */
 
-func fetchURI(uri string) io.Reader{
-	var httpClient = &http.Client{
-		Timeout: 30*time.Second,
-	}
+func (c *Crawler) fetchPage(ctx context.Context, uri string) (result fetchResult){
+	result = fetchResult{uri: uri, finalURI: uri}
+	start := time.Now()
+	defer func() { result.duration = time.Since(start) }()
 
-	resp, reqErr := httpClient.Get(uri)
+	atomic.AddInt64(&c.visitedCounter,1)
+	req, reqErr := http.NewRequest(http.MethodGet, uri, nil)
 	if reqErr!=nil{
-		fmt.Println("Error while fetching response")
-		fmt.Println(reqErr)
+		logger.Error("error while fetching response", "uri", uri, "error", reqErr)
+		result.err = reqErr
+		return result
+	}
+	req.Header.Set("Accept-Encoding", c.config.compression.acceptEncoding)
+	if c.config.content.headFirst {
+		headReq := req.Clone(req.Context())
+		headReq.Method = http.MethodHead
+		headReq.Header.Del("Accept-Encoding")
+		headResp, headErr := c.client.Do(headReq)
+		if headErr == nil {
+			headResp.Body.Close()
+			contentType := mediaType(headResp.Header.Get("Content-Type"))
+			if headResp.StatusCode < 400 && !typeAllowed(contentType, c.config.content.downloadTypes) {
+				result.finalURI = headResp.Request.URL.String()
+				result.statusCode = headResp.StatusCode
+				result.redirects = redirectChain(headResp)
+				result.contentType = contentType
+				result.skipped = true
+				return result
+			}
+		}
+	}
+	resp, reqErr := c.client.Do(req)
+	if resp != nil {
+		result.finalURI = resp.Request.URL.String()
+		result.statusCode = resp.StatusCode
+		result.redirects = redirectChain(resp)
+	}
+	if errors.Is(reqErr, errSkipURI) {
+		logger.Info("uri skipped by a request hook", "uri", uri)
+		result.skipped = true
+		return result
+	}
+	if errors.Is(reqErr, errNotStored) {
+		logger.Info("uri not in the stored crawl", "uri", uri)
+		atomic.AddInt64(&c.notStoredCounter, 1)
+		result.skipped = true
+		return result
+	}
+	if reqErr!=nil{
+		logger.Error("error while fetching response", "uri", uri, "error", reqErr)
+		result.err = reqErr
+		return result
 	}
-
-	atomic.AddInt64(&visitedCounter,1)
-	response := getStringFromReader(resp.Body)
 	defer resp.Body.Close()
-	if resp.StatusCode >= 400 && resp.StatusCode <= 499 {
-		response = "URI returned a 4xx status code"
-	} else if resp.StatusCode >= 500 && resp.StatusCode <= 599{
-		response = "URI returned a 5xx status code"
+	result.contentType = mediaType(resp.Header.Get("Content-Type"))
+	if !typeAllowed(result.contentType, c.config.content.downloadTypes) {
+		result.skipped = true
+		return result
 	}
-	return strings.NewReader(response)
+	wire := &countingReader{reader: resp.Body}
+	result.contentEncoding = resp.Header.Get("Content-Encoding")
+	decoded, decodeErr := decodeContent(wire, result.contentEncoding)
+	if decodeErr != nil {
+		logger.Error("error while decoding response", "uri", uri, "content_encoding", result.contentEncoding, "error", decodeErr)
+		result.err = decodeErr
+		return result
+	}
+	if closer, ok := decoded.(io.Closer); ok {
+		defer closer.Close()
+	}
+	result.body, result.truncated = readBody(decoded, c.config.content.maxBodySize)
+	result.wireSize = wire.count
+	result.size = int64(len(result.body))
+	if result.truncated {
+		logger.Warn("response truncated", "uri", uri, "max_body_size", c.config.content.maxBodySize)
+	}
+	if isText(result.contentType) {
+		result.body, result.charset = transcodeBody(result.body, resp.Header.Get("Content-Type"))
+	}
+	c.renderPage(ctx, &result)
+	return result
 }
 
-/*  The function prints on the terminal window if DISPLAY_URI=true and stores on disk if STORE_ON_DISK=true
-	this also returns an io.Reader object with the httpResponse
+/*  The function returns the body that is passed on for display, storage and parsing.
+	4xx and 5xx responses are replaced with a message containing the status code class
 	Arguments:
-		httBody: An io.Reader object with the response body
-		uriOutput: A bool argument with the value of DISPLAY_URI if the value set is valid bool
-		uri: The uri that is being visited and should be printed
-		writeOnDisk: A bool argument with the value of STORE_ON_DISK if the value set is valid bool
-		rootPath: A string argument with the root path of the disk (if the value set in ROOT_PATH is a valid path)
+		result: The fetchResult for the uri
 	Returns:
-		An io reader object with the response body
+		A string with the response body
  */
 
-func uriOutputStore(httpBody io.Reader, uriOutput bool, uri string, writeOnDisk bool, rootPath string) io.Reader{
-	if uriOutput{
-		fmt.Println(uri)
-	}
-	if writeOnDisk {
-		s  := storeOnDisk(httpBody, rootPath)
-		return strings.NewReader(s)
-	} else {
-		return httpBody
+func responseBody(result fetchResult) string{
+	if result.statusCode >= 400 && result.statusCode <= 499 {
+		return "URI returned a 4xx status code"
+	} else if result.statusCode >= 500 && result.statusCode <= 599{
+		return "URI returned a 5xx status code"
 	}
+	return result.body
 }
 
 /* The function returns a string from a io.Reader object
@@ -372,8 +439,7 @@ func getStringFromReader(httpBody io.Reader) string{
 	buf := new(bytes.Buffer)
 	_, bufReadFromErr := buf.ReadFrom(httpBody)
 	if bufReadFromErr!= nil{
-		fmt.Println("Error reading from buffer")
-		fmt.Println(bufReadFromErr)
+		logger.Warn("error reading from buffer", "error", bufReadFromErr)
 	}
 	s := buf.String()
 	return s
@@ -400,6 +466,18 @@ func absoluteURL(href, base string) string {
 	return uri.String()
 }
 
+/* anchor holds a link found in an anchor element of the html response
+	href: The value of the href attribute with the fragment removed
+	text: The text inside the anchor element with the whitespace collapsed
+	rel: The link types of the rel attribute in lower case
+ */
+
+type anchor struct {
+	href string
+	text string
+	rel  string
+}
+
 /*This function takes a reader object and returns a array of string slices for all the anchor links
 in the html response
 	Arguments:
@@ -409,25 +487,78 @@ in the html response
 */
 
 func getAllLinksHTML(httpBody io.Reader) []string {
-	var links []string
-	var hrefLinks []string
-	page := html.NewTokenizer(httpBody)
-	for {
-		tokenType := page.Next()
-		if tokenType == html.ErrorToken {
-			return links
-		}
-		token := page.Token()
-		if tokenType == html.StartTagToken && token.DataAtom.String() == "a" {
-			for _, attr := range token.Attr {
-				if attr.Key == "href" {
-					poundRemovedURI := removePound(attr.Val)
-					hrefLinks = append(hrefLinks, poundRemovedURI)
-					appendHrefURL(&links, hrefLinks)
-				}
+	return anchorLinks(getAllAnchorsHTML(httpBody))
+}
+
+/*This function takes a reader object and returns all the anchor elements with an href in the html response
+	Arguments:
+		httpBody: Response body is passed as a reader object
+	Returns :
+		An array of anchors in the order they appear in the html response
+*/
+
+func getAllAnchorsHTML(httpBody io.Reader) []anchor {
+	return documentAnchors(parseDocument(httpBody))
+}
+
+/*This function parses an html response into a document tree. The content of noscript elements is parsed
+as html so that the links inside them are found
+	Arguments:
+		httpBody: Response body is passed as a reader object
+	Returns :
+		The root node of the document, an empty document if the response can not be read
+*/
+
+func parseDocument(httpBody io.Reader) *html.Node {
+	doc, err := html.ParseWithOptions(httpBody, html.ParseOptionEnableScripting(false))
+	if err != nil {
+		return &html.Node{Type: html.DocumentNode}
+	}
+	return doc
+}
+
+/*This function returns all the anchor elements with an href in a document
+	Arguments:
+		doc: The parsed html response
+	Returns :
+		An array of anchors in the order they appear in the html response
+*/
+
+func documentAnchors(doc *html.Node) []anchor {
+	var anchors []anchor
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			if href, ok := nodeAttr(n, "href"); ok {
+				rel, _ := nodeAttr(n, "rel")
+				anchors = append(anchors, anchor{
+					href: removePound(href),
+					text: nodeText(n),
+					rel:  strings.Join(strings.Fields(strings.ToLower(rel)), " "),
+				})
 			}
 		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	return anchors
+}
+
+/*This function returns the unique hrefs of the anchors in the order they were found
+	Arguments:
+		anchors: An array of anchors
+	Returns :
+		An array of uris as string slices
+*/
+
+func anchorLinks(anchors []anchor) []string {
+	var links []string
+	for _, a := range anchors {
+		appendHrefURL(&links, []string{a.href})
 	}
+	return links
 }
 
 /* This function checks if the string contains a # and returns a slice of the string trimmed till the index of #
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"gopkg.in/h2non/gock.v1"
 	"io/ioutil"
@@ -176,44 +178,46 @@ func TestRemovePound2(t *testing.T){
 	}
 }
 
-func TestStoreOnDisk1(t *testing.T){
-	testReader := strings.NewReader("Test text")
+func TestStorePage2(t *testing.T){
 	testDirRoot, _ := filepath.Abs(filepath.Dir(os.Args[0]))
 	testDirRoot = testDirRoot+"/testDir/"
 	mkDirErr := os.Mkdir(testDirRoot,0755)
 	if mkDirErr!=nil{
-		fmt.Println("Creation of directory failed in Store On Disk test 2 with the error message")
+		fmt.Println("Creation of directory failed in Store Page test 2 with the error message")
 		fmt.Print(mkDirErr)
 		t.Fail()
 	}
-	testStr := storeOnDisk(testReader,testDirRoot)
-	if testStr != "Test text" {
-		fmt.Println("Store On Disk function returned invalid value with the output"+testStr)
+	testCrawler := newCrawler(defaultCrawlConfig("https://test.com"))
+	testCrawler.storePage(fetchResult{uri: "https://test.com", finalURI: "https://test.com", statusCode: 200, contentType: "text/html"}, "Test text", testDirRoot)
+	testBytes, _ := ioutil.ReadFile(testDirRoot+"1.html")
+	if string(testBytes) != "Test text" {
+		fmt.Println("Store Page function stored an invalid value with the output"+string(testBytes))
 		t.Fail()
 	} else {
-		fmt.Println("Test 1 for store on disk passed.")
+		fmt.Println("Test 2 for store page passed.")
 	}
 	_ = os.RemoveAll(testDirRoot)
 }
 
-func TestStoreOnDisk2(t *testing.T){
-	testReader := strings.NewReader("Test text")
+func TestStorePage3(t *testing.T){
 	testDirRoot, _ := filepath.Abs(filepath.Dir(os.Args[0]))
 	testDirRoot = testDirRoot+"/testDir/"
 	mkDirEerr := os.Mkdir(testDirRoot,0755)
 	if mkDirEerr!=nil{
-		fmt.Println("Creation of directory failed in Store On Disk test 2 with the error message")
+		fmt.Println("Creation of directory failed in Store Page test 3 with the error message")
 		fmt.Print(mkDirEerr)
 		t.Fail()
 	}
-	storeOnDisk(testReader,testDirRoot)
+	testCrawler := newCrawler(defaultCrawlConfig("https://test.com"))
+	testCrawler.storePage(fetchResult{uri: "https://test.com", finalURI: "https://test.com", statusCode: 200, contentType: "text/html"}, "Test text", testDirRoot)
+	testCrawler.storePage(fetchResult{uri: "https://test.com/down", err: errors.New("connection refused")}, "", testDirRoot)
 	testFiles, _ := ioutil.ReadDir(testDirRoot)
-	if len(testFiles) != 1 {
-		fmt.Println("Store On Disk test returned wrong number of files")
+	if len(testFiles) != 2 {
+		fmt.Println("Store Page test returned wrong number of files")
 		fmt.Println("It returned "+strconv.FormatInt(int64(len(testFiles)),10)+" file/files")
 		t.Fail()
 	} else {
-		fmt.Println("Test 2 for store on disk passed.")
+		fmt.Println("Test 3 for store page passed.")
 	}
 	_ = os.RemoveAll(testDirRoot)
 }
@@ -330,27 +334,19 @@ func TestCheck2 (t *testing.T) {
 }
 
 func TestCheckCounters1(t *testing.T) {
-	testQueue := make(chan string)
-	testDone := make(chan bool)
-	atomic.AddInt64(&insertCounter,1)
+	testCrawler := newCrawler(defaultCrawlConfig("https://test.com"))
+	atomic.AddInt64(&testCrawler.insertCounter,1)
 	testInsertValue := "testValue"
-	go func() {
-		testQueue <- testInsertValue
-	}()
-	checkCounters(testQueue,testDone)
-	go func() {
-		for {
-			testValue, signal := <- testQueue
-			if !signal{
-				fmt.Println("Channel was closed by checkCounters. Test 1 for checkCounters failed")
-				fmt.Println("The value that should be read from testQueue is"+testValue)
-				t.Fail()
-			} else {
-				fmt.Println("Test 1 for checkCounters passed!")
-			}
-		}
-	}()
-	atomic.SwapInt64(&insertCounter,0)
+	testCrawler.frontier.push(testInsertValue,0,0)
+	testCrawler.checkCounters()
+	testValue, signal := testCrawler.frontier.pop()
+	if !signal{
+		fmt.Println("Frontier was closed by checkCounters. Test 1 for checkCounters failed")
+		fmt.Println("The value that should be read from the frontier is"+testInsertValue)
+		t.Fail()
+	} else if testValue.uri == testInsertValue {
+		fmt.Println("Test 1 for checkCounters passed!")
+	}
 }
 
 func TestCheckDisplay1(t *testing.T){
@@ -598,51 +594,7 @@ func TestGetThreadCount2(t *testing.T){
 	}
 }
 
-func TestUriOutputStore1(t *testing.T){
-	testHttpBodyReader := strings.NewReader("Test Text")
-	testUri := "test URI"
-	testDirRoot, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	testResultReader := uriOutputStore(testHttpBodyReader, false,testUri, false,testDirRoot)
-	testStringResult := getStringFromReader(testResultReader)
-	if testStringResult!="Test Text"{
-		fmt.Println("uriOutputStore returned an invalid value"+testStringResult)
-		t.Fail()
-	} else if testStringResult == "Test Text" && reflect.TypeOf(testResultReader) != reflect.TypeOf(strings.NewReader("string")){
-		fmt.Println("uriOutputStore returned correct value but incorrect type")
-		fmt.Print(reflect.TypeOf(testResultReader))
-		t.Fail()
-	} else if testStringResult == "Test Text" && reflect.TypeOf(testResultReader) == reflect.TypeOf(strings.NewReader("string")){
-		fmt.Println("Test 1 for uriOutputStore passed!")
-	}
-}
-
-func TestUriOutputStore2(t *testing.T){
-	testHttpBodyReader := strings.NewReader("Test Text")
-	testUri := "test URI"
-	testDirRoot, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	testDirRoot = testDirRoot +"/uriOutputStore2/"
-	_ = os.Mkdir(testDirRoot, 0755)
-	testResultReader := uriOutputStore(testHttpBodyReader, false,testUri, true,testDirRoot)
-	testStringResult := getStringFromReader(testResultReader)
-	testFiles, _ := ioutil.ReadDir(testDirRoot)
-	if testStringResult!="Test Text"{
-		fmt.Println("uriOutputStore returned an invalid value"+testStringResult)
-		t.Fail()
-	} else if testStringResult == "Test Text" && reflect.TypeOf(testResultReader) != reflect.TypeOf(strings.NewReader("string")){
-		fmt.Println("uriOutputStore returned correct value but incorrect type")
-		fmt.Print(reflect.TypeOf(testResultReader))
-		t.Fail()
-	} else if testStringResult == "Test Text" && reflect.TypeOf(testResultReader) == reflect.TypeOf(strings.NewReader("string")) && len(testFiles)!=1 {
-		fmt.Println("uriOutputStore returned correct value and a correct type but didn't store the output on disk")
-		fmt.Println("Number of files on disk are")
-		fmt.Print(len(testFiles))
-		t.Fail()
-	} else if testStringResult == "Test Text" && reflect.TypeOf(testResultReader) == reflect.TypeOf(strings.NewReader("string")) && len(testFiles)==1{
-		fmt.Println("Test 2 for uriOutputStore passed!")
-	}
-}
-
-func TestFetchURI(t *testing.T){
+func TestFetchPage1(t *testing.T){
 	defer gock.Off()
 	testReader := strings.NewReader(
 		` <p>
@@ -652,22 +604,23 @@ func TestFetchURI(t *testing.T){
 			</p>`)
 	initialTestString := getStringFromReader(testReader)
 	gock.New("http://testfetchuri.com").Get("/test").Reply(200).BodyString(initialTestString)
-	testResultReader := fetchURI("http://testfetchuri.com/test")
-	testResultString := getStringFromReader(testResultReader)
+	testCrawler := newCrawler(defaultCrawlConfig("http://testfetchuri.com/test"))
+	testResult := testCrawler.fetchPage(context.Background(), "http://testfetchuri.com/test")
+	testResultString := responseBody(testResult)
 	if initialTestString != testResultString {
-		fmt.Println("Invalid value returned by fetchURI")
+		fmt.Println("Invalid value returned by fetchPage")
 		fmt.Println(testResultString)
 		t.Fail()
-	} else if initialTestString == testResultString && reflect.TypeOf(testResultReader) != reflect.TypeOf(testReader){
-		fmt.Println("Correct Value returned but incorrect type returned by fetchURI")
-		fmt.Println(reflect.TypeOf(testResultReader))
+	} else if testResult.statusCode != 200 {
+		fmt.Println("Correct Value returned but incorrect status code returned by fetchPage")
+		fmt.Println(testResult.statusCode)
 		t.Fail()
-	} else if initialTestString == testResultString && reflect.TypeOf(testResultReader) == reflect.TypeOf(testReader){
-		fmt.Println("Test 1 for Fetch URI passed")
+	} else {
+		fmt.Println("Test 1 for Fetch Page passed")
 	}
 }
 
-func TestFetchURI2(t *testing.T){
+func TestFetchPage2(t *testing.T){
 	defer gock.Off()
 	testReader := strings.NewReader(
 		` <p>
@@ -677,23 +630,24 @@ func TestFetchURI2(t *testing.T){
 			</p>`)
 	initialTestString := getStringFromReader(testReader)
 	gock.New("http://testfetchuri.com").Get("/test").Reply(500).BodyString(initialTestString)
-	testResultReader := fetchURI("http://testfetchuri.com/test")
+	testCrawler := newCrawler(defaultCrawlConfig("http://testfetchuri.com/test"))
+	testResult := testCrawler.fetchPage(context.Background(), "http://testfetchuri.com/test")
 	test5XXString := "URI returned a 5xx status code"
-	testResultString := getStringFromReader(testResultReader)
+	testResultString := responseBody(testResult)
 	if testResultString != test5XXString {
-		fmt.Println("Invalid value returned by fetchURI")
+		fmt.Println("Invalid value returned by fetchPage")
 		fmt.Println(testResultString)
 		t.Fail()
-	} else if test5XXString == testResultString && reflect.TypeOf(testResultReader) != reflect.TypeOf(testReader){
-		fmt.Println("Correct Value returned but incorrect type returned by fetchURI")
-		fmt.Println(reflect.TypeOf(testResultReader))
+	} else if testResult.statusCode != 500 {
+		fmt.Println("Correct Value returned but incorrect status code returned by fetchPage")
+		fmt.Println(testResult.statusCode)
 		t.Fail()
-	} else if test5XXString == testResultString && reflect.TypeOf(testResultReader) == reflect.TypeOf(testReader){
-		fmt.Println("Test 2 for Fetch URI passed")
+	} else {
+		fmt.Println("Test 2 for Fetch Page passed")
 	}
 }
 
-func TestFetchURI3(t *testing.T){
+func TestFetchPage3(t *testing.T){
 	defer gock.Off()
 	testReader := strings.NewReader(
 		` <p>
@@ -703,54 +657,58 @@ func TestFetchURI3(t *testing.T){
 			</p>`)
 	initialTestString := getStringFromReader(testReader)
 	gock.New("http://testfetchuri.com").Get("/test").Reply(450).BodyString(initialTestString)
-	testResultReader := fetchURI("http://testfetchuri.com/test")
+	testCrawler := newCrawler(defaultCrawlConfig("http://testfetchuri.com/test"))
+	testResult := testCrawler.fetchPage(context.Background(), "http://testfetchuri.com/test")
 	test4XXString := "URI returned a 4xx status code"
-	testResultString := getStringFromReader(testResultReader)
+	testResultString := responseBody(testResult)
 	if testResultString != test4XXString {
-		fmt.Println("Invalid value returned by fetchURI")
+		fmt.Println("Invalid value returned by fetchPage")
 		fmt.Println(testResultString)
 		t.Fail()
-	} else if test4XXString == testResultString && reflect.TypeOf(testResultReader) != reflect.TypeOf(testReader){
-		fmt.Println("Correct Value returned but incorrect type returned by fetchURI")
-		fmt.Println(reflect.TypeOf(testResultReader))
+	} else if testResult.statusCode != 450 {
+		fmt.Println("Correct Value returned but incorrect status code returned by fetchPage")
+		fmt.Println(testResult.statusCode)
 		t.Fail()
-	} else if test4XXString == testResultString && reflect.TypeOf(testResultReader) == reflect.TypeOf(testReader){
-		fmt.Println("Test 3 for Fetch URI passed")
+	} else {
+		fmt.Println("Test 3 for Fetch Page passed")
 	}
 }
 
 func TestInsertInitialURI(t *testing.T){
-	testQueue := make(chan string)
 	uri := "http://test.com"
-	insertInitialURI(uri,testQueue)
-	test := <- testQueue
-	_,testBool1 := inserted.Load(uri)
-	_,testBool := inserted.Load(uri+"/")
-	if test != uri{
-		fmt.Println("Invalid value inserted in testQueue"+test)
+	testCrawler := newCrawler(defaultCrawlConfig(uri))
+	testCrawler.insertInitialURI(uri)
+	testItem, _ := testCrawler.frontier.pop()
+	test := testItem.uri
+	_,testBool1 := testCrawler.inserted.Load(uri)
+	_,testBool := testCrawler.inserted.Load(uri+"/")
+	if test != uri || testItem.depth != 0 {
+		fmt.Println("Invalid value inserted in the frontier"+test)
 		t.Fail()
 	} else if test == uri && !testBool1 && !testBool {
-		fmt.Println("The channel returned the correct value but failed to store the uri in the sync Map")
+		fmt.Println("The frontier returned the correct value but failed to store the uri in the sync Map")
 		t.Fail()
 	} else if test == uri && testBool1 && testBool{
 		fmt.Println("Test 1 for initalURI passed")
 	}
-	atomic.SwapInt64(&insertCounter,0)
-	atomic.SwapInt64(&visitedCounter,0)
-	close(testQueue)
 }
 
+//The crawler shared by the filterAndEnqueue tests that crawl test.com
+var filterTestCrawler = newCrawler(defaultCrawlConfig("https://test.com"))
+
 func TestFilterAndEnqueue1(t *testing.T){
 	testLinks := []string{"/test1","https://test.com/test2","/test1"}
-	testQueue := make(chan string,3)
 	testDone := make(chan bool)
 	testHostBaseURL := "test.com"
 	testCrawlURI := "https://test.com"
 	testCounter := 0
-	filterAndEnqueue(testLinks,testQueue,testHostBaseURL,testCrawlURI)
+	testCrawler := filterTestCrawler
+	testCrawler.config.hostBaseURL = testHostBaseURL
+	testCrawler.filterAndEnqueue(testLinks,testCrawlURI,1)
 	go func() {
 		for {
-			testValue,signal := <-testQueue
+			testItem,signal := testCrawler.frontier.pop()
+			testValue := testItem.uri
 			//fmt.Println(signal)
 			//fmt.Println(testValue)
 			if signal{
@@ -769,15 +727,16 @@ func TestFilterAndEnqueue1(t *testing.T){
 
 func TestFilterAndEnqueue2(t *testing.T){
 	testLinks := []string{"/test1","https://test.com/test2","/test1"}
-	testQueue := make(chan string,3)
 	testDone := make(chan bool)
 	testHostBaseURL := "testing.com"
 	testCrawlURI := "https://test.com"
 	testCounter := 0
-	filterAndEnqueue(testLinks,testQueue,testHostBaseURL,testCrawlURI)
+	testCrawler := newCrawler(defaultCrawlConfig("https://"+testHostBaseURL))
+	testCrawler.filterAndEnqueue(testLinks,testCrawlURI,1)
 	go func() {
 		for {
-			testValue,signal := <-testQueue
+			testItem,signal := testCrawler.frontier.pop()
+			testValue := testItem.uri
 			//fmt.Println(signal)
 			//fmt.Println(testValue)
 			if signal{
@@ -798,15 +757,17 @@ func TestFilterAndEnqueue2(t *testing.T){
 
 func TestFilterAndEnqueue3(t *testing.T){
 	testLinks := []string{"/test1","https://test.com/test2","/test1"}
-	testQueue := make(chan string,3)
 	testDone := make(chan bool)
 	testHostBaseURL := "test.com"
 	testCrawlURI := "https://test1.com"
 	testCounter := 0
-	filterAndEnqueue(testLinks,testQueue,testHostBaseURL,testCrawlURI)
+	testCrawler := filterTestCrawler
+	testCrawler.config.hostBaseURL = testHostBaseURL
+	testCrawler.filterAndEnqueue(testLinks,testCrawlURI,1)
 	go func() {
 		for {
-			testValue,signal := <-testQueue
+			testItem,signal := testCrawler.frontier.pop()
+			testValue := testItem.uri
 			if signal{
 				testCounter+=1
 			} else {
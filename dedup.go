@@ -0,0 +1,237 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+/* duplicateConfig holds the options for content based duplicate detection
+	detect: Set from DETECT_DUPLICATES, enables fingerprinting of every fetched body
+	nearDistance: Set from NEAR_DUPLICATE_DISTANCE, the maximum SimHash hamming distance for two pages to be
+		reported as near duplicates. A negative value disables near duplicate detection
+	skipLinks: Set from SKIP_DUPLICATE_LINKS, links are not extracted from pages detected as duplicates
+ */
+
+type duplicateConfig struct {
+	detect       bool
+	nearDistance int
+	skipLinks    bool
+}
+
+/* contentFingerprint holds the fingerprints computed for a single page body
+	hash: A hex encoded SHA-256 of the body
+	simHash: A 64 bit SimHash of the visible text of the body
+ */
+
+type contentFingerprint struct {
+	hash    string
+	simHash uint64
+}
+
+/* duplicateState holds the fingerprints of the pages fetched by a Crawler
+	duplicateCounter: A counter to keep a track of the number of duplicate pages found
+	contentHashes: A syncMap from the content hash to the first URI that served it
+	simHashes: The SimHashes of all the unique pages seen so far
+ */
+
+type duplicateState struct {
+	duplicateCounter int64
+	contentHashes    sync.Map
+	simHashes        []simHashEntry
+	simHashesLock    sync.Mutex
+}
+
+type simHashEntry struct {
+	simHash uint64
+	uri     string
+}
+
+/*  The function reads the DETECT_DUPLICATES, NEAR_DUPLICATE_DISTANCE and SKIP_DUPLICATE_LINKS env variables
+	Invalid values are reported and the corresponding option is left disabled
+	Returns:
+		A duplicateConfig with the options set by the user
+ */
+
+func getDuplicateConfig() duplicateConfig {
+	config := duplicateConfig{nearDistance: -1}
+	if os.Getenv("DETECT_DUPLICATES") == "" {
+		return config
+	}
+	detect, err := strconv.ParseBool(os.Getenv("DETECT_DUPLICATES"))
+	if err != nil {
+		logger.Warn("invalid value specified for DETECT_DUPLICATES env variable, duplicate pages will not be detected",
+			"value", os.Getenv("DETECT_DUPLICATES"))
+		return config
+	}
+	config.detect = detect
+	if os.Getenv("NEAR_DUPLICATE_DISTANCE") != "" {
+		distance, err := strconv.Atoi(os.Getenv("NEAR_DUPLICATE_DISTANCE"))
+		if err != nil || distance > 64 {
+			logger.Warn("invalid value specified for NEAR_DUPLICATE_DISTANCE env variable, only exact duplicates will be detected",
+				"value", os.Getenv("NEAR_DUPLICATE_DISTANCE"))
+		} else {
+			config.nearDistance = distance
+		}
+	}
+	if os.Getenv("SKIP_DUPLICATE_LINKS") != "" {
+		skipLinks, err := strconv.ParseBool(os.Getenv("SKIP_DUPLICATE_LINKS"))
+		if err != nil {
+			logger.Warn("invalid value specified for SKIP_DUPLICATE_LINKS env variable, links will be extracted from duplicate pages",
+				"value", os.Getenv("SKIP_DUPLICATE_LINKS"))
+		}
+		config.skipLinks = skipLinks
+	}
+	return config
+}
+
+/*  The function computes the exact hash and the SimHash of a page body
+	Arguments:
+		body: A string with the response body
+	Returns:
+		A contentFingerprint for the body
+ */
+
+func fingerprintContent(body string) contentFingerprint {
+	sum := sha256.Sum256([]byte(body))
+	return contentFingerprint{
+		hash:    hex.EncodeToString(sum[:]),
+		simHash: simHash(visibleWords(strings.NewReader(body))),
+	}
+}
+
+/*  The function records the fingerprint of the page and checks if the same or a similar body was already seen
+	Arguments:
+		uri: The uri that served the body
+		fingerprint: The contentFingerprint of the body
+		nearDistance: The maximum hamming distance for near duplicates, a negative value disables the check
+	Returns:
+		The uri of the page this one duplicates or an empty string if the content is new
+		The hamming distance between the two SimHashes, 0 for exact duplicates
+ */
+
+func (state *duplicateState) checkDuplicate(uri string, fingerprint contentFingerprint, nearDistance int) (string, int) {
+	original, loaded := state.contentHashes.LoadOrStore(fingerprint.hash, uri)
+	if loaded {
+		return original.(string), 0
+	}
+	if nearDistance < 0 {
+		return "", 0
+	}
+	state.simHashesLock.Lock()
+	defer state.simHashesLock.Unlock()
+	for _, entry := range state.simHashes {
+		distance := bits.OnesCount64(entry.simHash ^ fingerprint.simHash)
+		if distance <= nearDistance {
+			return entry.uri, distance
+		}
+	}
+	state.simHashes = append(state.simHashes, simHashEntry{simHash: fingerprint.simHash, uri: uri})
+	return "", 0
+}
+
+/*  The function fingerprints the body of a fetched page and reports it if it duplicates an earlier page
+	Arguments:
+		result: The fetchResult of the page
+	Returns:
+		A true value if the page is a duplicate and its links should not be followed
+ */
+
+func (c *Crawler) reportDuplicate(result fetchResult) bool {
+	if !c.config.dedup.detect || result.err != nil || result.statusCode >= 400 || result.body == "" {
+		return false
+	}
+	original, distance := c.checkDuplicate(result.uri, fingerprintContent(result.body), c.config.dedup.nearDistance)
+	if original == "" {
+		return false
+	}
+	atomic.AddInt64(&c.duplicateCounter, 1)
+	if distance == 0 {
+		_, _ = fmt.Fprintln(c.output, "Duplicate content: "+result.uri+" has the same content as "+original)
+	} else {
+		_, _ = fmt.Fprintln(c.output, "Near duplicate content: "+result.uri+" is similar to "+original+
+			" (distance "+strconv.Itoa(distance)+")")
+	}
+	return c.config.dedup.skipLinks
+}
+
+/*  The function returns the lower cased words of the text in an html document skipping scripts and styles
+	Arguments:
+		httpBody: An io.Reader with the html document
+	Returns:
+		A slice of words in the order they appear in the document
+ */
+
+func visibleWords(httpBody io.Reader) []string {
+	var words []string
+	skip := 0
+	page := html.NewTokenizer(httpBody)
+	for {
+		tokenType := page.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			return words
+		case html.StartTagToken, html.EndTagToken:
+			name, _ := page.TagName()
+			if string(name) == "script" || string(name) == "style" {
+				if tokenType == html.StartTagToken {
+					skip++
+				} else if skip > 0 {
+					skip--
+				}
+			}
+		case html.TextToken:
+			if skip == 0 {
+				words = append(words, strings.FieldsFunc(strings.ToLower(string(page.Text())), func(r rune) bool {
+					return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+				})...)
+			}
+		}
+	}
+}
+
+/*  The function computes a 64 bit SimHash from three word shingles so that pages that differ only
+	in small parts of their text have hashes with a small hamming distance
+	Arguments:
+		words: A slice of words of the document
+	Returns:
+		The SimHash of the words
+ */
+
+func simHash(words []string) uint64 {
+	var weights [64]int
+	shingleSize := 3
+	if len(words) < shingleSize {
+		shingleSize = len(words)
+	}
+	for i := 0; i+shingleSize <= len(words) && shingleSize > 0; i++ {
+		hasher := fnv.New64a()
+		_, _ = hasher.Write([]byte(strings.Join(words[i:i+shingleSize], " ")))
+		shingleHash := hasher.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if shingleHash&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var hash uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			hash |= 1 << uint(bit)
+		}
+	}
+	return hash
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFingerprintContent1(t *testing.T) {
+	testFingerprint1 := fingerprintContent("<p>Same body</p>")
+	testFingerprint2 := fingerprintContent("<p>Same body</p>")
+	testFingerprint3 := fingerprintContent("<p>Other body</p>")
+	if testFingerprint1 != testFingerprint2 {
+		fmt.Println("fingerprintContent returned different fingerprints for the same body")
+		t.Fail()
+	} else if testFingerprint1.hash == testFingerprint3.hash {
+		fmt.Println("fingerprintContent returned the same hash for different bodies")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for fingerprintContent passed")
+	}
+}
+
+func TestVisibleWords1(t *testing.T) {
+	testReader := strings.NewReader(`<html><head><style>p {color: red}</style></head>
+		<body><script>var a = 1;</script><p>Hello, World!</p></body></html>`)
+	testWords := visibleWords(testReader)
+	if strings.Join(testWords, " ") != "hello world" {
+		fmt.Println("visibleWords returned an invalid value")
+		fmt.Println(testWords)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for visibleWords passed")
+	}
+}
+
+func TestCheckDuplicate1(t *testing.T) {
+	var testState duplicateState
+	testFingerprint := fingerprintContent("<p>check duplicate test one</p>")
+	testOriginal, _ := testState.checkDuplicate("http://test.com/dedup1", testFingerprint, -1)
+	if testOriginal != "" {
+		fmt.Println("checkDuplicate reported a new page as a duplicate of " + testOriginal)
+		t.Fail()
+	}
+	testOriginal, testDistance := testState.checkDuplicate("http://test.com/dedup1?session=1", testFingerprint, -1)
+	if testOriginal != "http://test.com/dedup1" || testDistance != 0 {
+		fmt.Println("checkDuplicate did not report an exact duplicate")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for checkDuplicate passed")
+	}
+}
+
+func TestCheckDuplicate2(t *testing.T) {
+	testText := "the quick brown fox jumps over the lazy dog while the farmer watches from the porch " +
+		"and the cat sleeps in the sun next to the old red barn on the hill"
+	testFingerprint1 := fingerprintContent("<p>" + testText + " today</p>")
+	testFingerprint2 := fingerprintContent("<p>" + testText + " tomorrow</p>")
+	var testState duplicateState
+	_, _ = testState.checkDuplicate("http://test.com/dedup2", testFingerprint1, 10)
+	testOriginal, testDistance := testState.checkDuplicate("http://test.com/dedup2/print", testFingerprint2, 10)
+	if testOriginal != "http://test.com/dedup2" {
+		fmt.Println("checkDuplicate did not report a near duplicate")
+		fmt.Println(testDistance)
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for checkDuplicate passed")
+	}
+}
+
+func TestGetDuplicateConfig1(t *testing.T) {
+	_ = os.Setenv("DETECT_DUPLICATES", "true")
+	_ = os.Setenv("NEAR_DUPLICATE_DISTANCE", "3")
+	_ = os.Setenv("SKIP_DUPLICATE_LINKS", "true")
+	testConfig := getDuplicateConfig()
+	if !testConfig.detect || testConfig.nearDistance != 3 || !testConfig.skipLinks {
+		fmt.Println("getDuplicateConfig returned an invalid value")
+		fmt.Println(testConfig)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for getDuplicateConfig passed")
+	}
+	_ = os.Setenv("DETECT_DUPLICATES", "")
+	_ = os.Setenv("NEAR_DUPLICATE_DISTANCE", "")
+	_ = os.Setenv("SKIP_DUPLICATE_LINKS", "")
+}
+
+func TestGetDuplicateConfig2(t *testing.T) {
+	_ = os.Setenv("DETECT_DUPLICATES", "invalid_value")
+	testConfig := getDuplicateConfig()
+	if testConfig.detect || testConfig.nearDistance != -1 {
+		fmt.Println("getDuplicateConfig enabled detection for an invalid value")
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for getDuplicateConfig passed")
+	}
+	_ = os.Setenv("DETECT_DUPLICATES", "")
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/* crawlConfig holds every option of a single crawl
+	crawlURI: The initial URI of the crawl
+	hostBaseURL: The hostname of the crawlURI, only URIs on this host are crawled
+	siteRoot: The directory of a local site whose file://localhost/ URIs are read from disk, empty for a crawl
+		over http
+	threads: The number of threads fetching URIs, the maximum number of threads in adaptive mode
+	uriOutput: Set from DISPLAY_URI, prints every visited uri
+	writeOnDisk: Set from STORE_ON_DISK, saves the responses in rootPath
+	rootPath: Set from ROOT_PATH, the directory the responses are saved in
+	maxPages: Set from MAX_PAGES, the maximum number of URIs crawled, 0 for no limit
+	maxRedirects: Set from MAX_REDIRECTS, the maximum number of redirects followed for a single uri
+	rateLimit: Set from RATE_LIMIT, the maximum number of requests sent per second, 0 for no limit
+	adaptive: Set from ADAPTIVE_CONCURRENCY, adjusts the number of threads to the responses of the host
+	seoAudit: Set from SEO_AUDIT, extracts the SEO metadata of every html page and summarizes its issues
+	resultsOutput: Set from RESULTS_OUTPUT, the JSON lines file the pageResult of every uri is appended to
+	hooks: Read from the HOOKS_CONFIG file, the headers, skipped URIs, excluded links, body replacements and
+		result sink added to the hooks of the Crawler
+	auth: Read from the AUTH_CONFIG file, the credentials, cookies and login form of the crawl
+	session: Read from the SESSION_CONFIG file, the static headers of every host and the cookies of the crawl
+	render: Set from RENDER_PATTERNS, the pages rendered in a browser and the renderer of every pattern
+	replay: Set from REPLAY_PATH, the stored crawl the responses are read from instead of the network
+	content, compression, dedup, linkCheck, priority, graph, report, structured, extract, text: The options of
+		the content types, compression, duplicate detection, broken link checker, crawl order, link graph, site
+		report, structured data, content extraction and text extraction features
+ */
+
+type crawlConfig struct {
+	crawlURI      string
+	hostBaseURL   string
+	siteRoot      string
+	threads       int64
+	uriOutput     bool
+	writeOnDisk   bool
+	rootPath      string
+	maxPages      int64
+	maxRedirects  int
+	rateLimit     float64
+	adaptive      bool
+	seoAudit      bool
+	resultsOutput string
+	hooks         hooksConfig
+	auth          authConfig
+	session       sessionConfig
+	render        renderConfig
+	replay        replayConfig
+	content       contentConfig
+	compression   compressionConfig
+	dedup         duplicateConfig
+	linkCheck     linkCheckConfig
+	priority      priorityConfig
+	graph         linkGraphConfig
+	report        reportConfig
+	structured    structuredDataConfig
+	extract       extractConfig
+	text          textConfig
+}
+
+/* Crawler crawls a single host starting from the crawlURI of its crawlConfig. Every Crawler has its own
+	frontier, counters and statistics so that several crawls can run in the same process
+	output: The io.Writer the visited URIs, duplicates, broken links and the summary are printed on
+	onPage: Called with the pageResult of every fetched uri if set
+	hooks: The request, response, link filter and result sink hooks of the crawl
+	concurrency: Adjusts the number of threads in adaptive mode, nil otherwise
+	sitemapPriority: The priority of the URIs listed in the sitemap of the host, read for the best-first
+		strategy and to find the orphan pages of the link graph
+ */
+
+type Crawler struct {
+	insertCounter    int64 //A counter to keep a track of the number of URIs inserted into the channel
+	visitedCounter   int64 //A counter to keep a track of the number of URIs visited
+	processedCounter int64 //A counter to keep a track of the number of URIs whose links were enqueued
+	storedCounter    int64 //A counter to keep a track of the number of responses saved on disk
+	activeCounter    int64 //A counter to keep a track of the number of requests in flight
+	config           crawlConfig
+	client           *http.Client
+	output           io.Writer
+	onPage           func(pageResult)
+	hooks            pipelineHooks
+	frontier         *frontier
+	inserted         sync.Map //A syncMap to keep a track of the URIs parsed by the HTML
+	manifestLock     sync.Mutex
+	stats            *crawlStatistics
+	pauseLock        sync.Mutex
+	resume           chan struct{} //Closed when a paused crawl is resumed, nil while the crawl is running
+	limiter          rateLimiter
+	pool             workerPool
+	concurrency      *concurrencyController
+	sitemapPriority  map[string]float64
+	duplicateState
+	redirectState
+	linkCheckState
+	linkGraphState
+	seoState
+	structuredDataState
+	extractState
+	textState
+	renderState
+	replayState
+}
+
+/* pageResult is the record of a single fetched uri that is passed to the onPage function of a Crawler
+ */
+
+type pageResult struct {
+	URI            string            `json:"uri"`
+	FinalURI       string            `json:"final_uri"`
+	StatusCode     int               `json:"status_code"`
+	ContentType    string            `json:"content_type,omitempty"`
+	Charset        string            `json:"charset,omitempty"`
+	Redirects      int               `json:"redirects,omitempty"`
+	WireSize       int64             `json:"wire_size"`
+	Size           int64             `json:"size"`
+	Duration       float64           `json:"duration_seconds"`
+	Depth          int               `json:"depth"`
+	Truncated      bool              `json:"truncated,omitempty"`
+	Skipped        bool              `json:"skipped,omitempty"`
+	Rendered       bool              `json:"rendered,omitempty"`
+	Links          int               `json:"links"`
+	SEO            *pageAudit        `json:"seo,omitempty"`
+	StructuredData *structuredData   `json:"structured_data,omitempty"`
+	Records        []extractedRecord `json:"records,omitempty"`
+	Text           *pageText         `json:"text,omitempty"`
+	Error          string            `json:"error,omitempty"`
+}
+
+const defaultThreadCount = 5
+
+/*  The function reads the options of a crawl from the env variables
+	Arguments:
+		crawlURI: A string with the initial URI of the crawl
+	Returns:
+		A crawlConfig with the options set by the user
+ */
+
+func getCrawlConfig(crawlURI string) crawlConfig {
+	writeOnDisk := checkWriteOnDisk()
+	rootPath := getRootPath(&writeOnDisk)
+	config := crawlConfig{
+		crawlURI:      crawlURI,
+		hostBaseURL:   getBaseHostname(crawlURI),
+		threads:       getThreadCount(),
+		uriOutput:     checkDisplay(),
+		writeOnDisk:   writeOnDisk,
+		rootPath:      rootPath,
+		maxPages:      getMaxPages(),
+		maxRedirects:  getMaxRedirects(),
+		rateLimit:     getRateLimit(),
+		adaptive:      checkAdaptiveConcurrency(),
+		seoAudit:      checkSEOAudit(),
+		resultsOutput: getResultsOutput(),
+		hooks:         getHooksConfig(),
+		auth:          getAuthConfig(),
+		session:       getSessionConfig(),
+		render:        getRenderConfig(),
+		replay:        getReplayConfig(),
+		content:       getContentConfig(),
+		compression:   getCompressionConfig(),
+		dedup:         getDuplicateConfig(),
+		linkCheck:     getLinkCheckConfig(),
+		priority:      getPriorityConfig(),
+		graph:         getLinkGraphConfig(),
+		report:        getReportConfig(),
+		structured:    getStructuredDataConfig(),
+		extract:       getExtractConfig(),
+		text:          getTextConfig(),
+	}
+	config.graph.enabled = config.graph.enabled || config.report.enabled
+	if config.writeOnDisk && config.replay.path != "" && sameDirectory(config.rootPath, config.replay.path) {
+		logger.Warn("ROOT_PATH is the stored crawl of REPLAY_PATH, not saving any files to disk")
+		config.writeOnDisk = false
+	}
+	return config
+}
+
+/*  The function returns the options of a crawl with every option set to its default value
+	Arguments:
+		crawlURI: A string with the initial URI of the crawl
+	Returns:
+		A crawlConfig with the default options
+ */
+
+func defaultCrawlConfig(crawlURI string) crawlConfig {
+	var hostBaseURL string
+	if crawlURL, err := url.Parse(crawlURI); err == nil {
+		hostBaseURL = crawlURL.Hostname()
+	}
+	return crawlConfig{
+		crawlURI:     crawlURI,
+		hostBaseURL:  hostBaseURL,
+		threads:      defaultThreadCount,
+		maxRedirects: defaultMaxRedirects,
+		content:      contentConfig{maxBodySize: defaultMaxBodySize, downloadTypes: defaultDownloadTypes},
+		compression:  compressionConfig{acceptEncoding: defaultAcceptEncoding},
+		dedup:        duplicateConfig{nearDistance: -1},
+		priority:     priorityConfig{strategy: strategyBreadthFirst},
+		report:       reportConfig{maxDepth: defaultReportMaxDepth},
+	}
+}
+
+/*  The function creates a Crawler that prints its output on stdout
+	Arguments:
+		config: The crawlConfig with the options of the crawl
+	Returns:
+		A pointer to a Crawler that is started with Run
+ */
+
+func newCrawler(config crawlConfig) *Crawler {
+	c := &Crawler{
+		config: config,
+		client: newHTTPClient(config.maxRedirects),
+		output: os.Stdout,
+		stats:  newCrawlStatistics(),
+	}
+	if config.resultsOutput != "" {
+		c.addSink(fileSink(config.resultsOutput))
+	}
+	c.addConfigHooks(config.hooks)
+	c.client.Jar = newCookieJar(append(append([]seedCookie(nil), config.session.cookies...), config.auth.cookies...))
+	transport := http.DefaultTransport
+	if config.siteRoot != "" {
+		transport = newSiteTransport(config.siteRoot)
+	}
+	if config.replay.path != "" {
+		transport = &replayTransport{responses: config.replay.responses}
+	}
+	if len(config.auth.Hosts) > 0 {
+		transport = &authTransport{base: transport, hosts: config.auth.Hosts}
+	}
+	if len(config.session.Headers) > 0 {
+		transport = &headerTransport{base: transport, headers: config.session.Headers}
+	}
+	c.client.Transport = &hookTransport{base: transport, hooks: &c.hooks}
+	c.frontier = newFrontier(config.priority.strategy, c.score)
+	c.limiter.setRate(config.rateLimit)
+	if config.adaptive {
+		c.concurrency = newConcurrencyController(config.threads)
+	}
+	return c
+}
+
+/*  The function starts the threads and crawls until every URI inserted into the frontier has been processed
+	When the context is cancelled the URIs left in the frontier are drained without being fetched. Nothing is
+	crawled if the login form of the auth config can not be submitted, the login is skipped when a stored crawl
+	is replayed. The browsers of the renderers are closed once the crawl is over
+	Arguments:
+		ctx: A context to cancel the crawl
+ */
+
+func (c *Crawler) Run(ctx context.Context) {
+	defer c.config.render.close()
+	if c.config.auth.Login != nil && c.config.replay.path == "" {
+		if err := c.login(ctx); err != nil {
+			logger.Error("login failed, the crawl is stopped", "uri", c.config.auth.Login.URL, "error", err)
+			return
+		}
+	}
+	priority := c.config.priority
+	if (priority.strategy == strategyBestFirst && priority.scorer == nil && priority.sitemapWeight != 0) ||
+		c.config.graph.enabled {
+		sitemapPriority := c.fetchSitemapPriorities()
+		c.graphLock.Lock()
+		c.sitemapPriority = sitemapPriority
+		c.graphLock.Unlock()
+	}
+	c.insertInitialURI(c.config.crawlURI)
+	c.startWorkers(ctx)
+	c.pool.workers.Wait()
+}
+
+/*  The function fetches a single uri, records it and enqueues the links found on it
+	Arguments:
+		ctx: The context of the crawl, the uri is skipped if it is cancelled
+		uri: A string with the uri to fetch
+		depth: The number of links followed from the crawlURI to the uri
+ */
+
+func (c *Crawler) process(ctx context.Context, uri string, depth int) {
+	c.waitIfPaused(ctx)
+	c.limiter.wait(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+	activeWorkers.Inc()
+	atomic.AddInt64(&c.activeCounter, 1)
+	defer func() {
+		activeWorkers.Dec()
+		atomic.AddInt64(&c.activeCounter, -1)
+	}()
+	result := c.fetchPage(ctx, uri)
+	c.hooks.applyResponse(&result)
+	if c.concurrency != nil {
+		if threads, reason := c.concurrency.observe(result); threads > 0 {
+			logger.Info("number of threads changed", "threads", threads, "reason", reason, "uri", uri)
+			c.resizeWorkers(threads)
+		}
+	}
+	c.stats.record(result)
+	c.recordPage(result)
+	observeFetch(result)
+	c.recordFailure(result)
+	body := responseBody(result)
+	if c.config.uriOutput {
+		_, _ = fmt.Fprintln(c.output, uri)
+	}
+	if c.config.writeOnDisk {
+		c.storePage(result, body, c.config.rootPath)
+	}
+	if isHTML(result.contentType) {
+		c.recordCanonical(result.finalURI, body)
+	}
+	var links []string
+	var audit *pageAudit
+	var data *structuredData
+	var records []extractedRecord
+	var text *pageText
+	if c.followRedirect(result) && isHTML(result.contentType) && !c.reportDuplicate(result) {
+		doc := parseDocument(strings.NewReader(body))
+		anchors := documentAnchors(doc)
+		c.recordLinks(result.finalURI, anchors)
+		c.recordGraph(result.finalURI, anchors)
+		links = anchorLinks(anchors)
+		c.filterAndEnqueue(links, result.finalURI, depth+1)
+		analyzed := result.statusCode < 400
+		if c.config.seoAudit && analyzed {
+			analysis := auditDocument(doc)
+			c.recordAudit(result.finalURI, analysis)
+			audit = &analysis
+		}
+		if c.config.structured.enabled && analyzed {
+			pageData := extractStructuredData(doc, result.finalURI)
+			c.recordStructuredData(result.finalURI, pageData)
+			data = &pageData
+		}
+		if c.config.extract.enabled && analyzed {
+			records = extractRecords(doc, result.finalURI, c.config.extract.rules)
+			c.emitRecords(records)
+		}
+		if c.config.text.enabled && result.statusCode >= 200 && result.statusCode < 300 {
+			pageText := extractText(doc)
+			pageText.URI = result.finalURI
+			c.writeText(pageText)
+			text = &pageText
+		}
+	}
+	if c.onPage != nil || len(c.hooks.sinks) > 0 {
+		page := newPageResult(result, depth, len(links))
+		page.SEO = audit
+		page.StructuredData = data
+		page.Records = records
+		page.Text = text
+		if c.onPage != nil {
+			c.onPage(page)
+		}
+		for _, sink := range c.hooks.sinks {
+			sink(page)
+		}
+	}
+}
+
+/*  The function checks the values of insertCounter and processedCounter and closes the frontier on equality
+	so that the threads stop once every inserted URI has been processed
+ */
+
+func (c *Crawler) checkCounters() {
+	if atomic.LoadInt64(&c.insertCounter) == atomic.LoadInt64(&c.processedCounter) {
+		c.stopWorkers()
+		c.frontier.close()
+	}
+}
+
+/*  The function pauses the crawl. The requests in flight are finished and the threads wait before
+	fetching the next uri until Resume is called
+ */
+
+func (c *Crawler) Pause() {
+	c.pauseLock.Lock()
+	defer c.pauseLock.Unlock()
+	if c.resume == nil {
+		c.resume = make(chan struct{})
+	}
+}
+
+/*  The function resumes a paused crawl
+ */
+
+func (c *Crawler) Resume() {
+	c.pauseLock.Lock()
+	defer c.pauseLock.Unlock()
+	if c.resume != nil {
+		close(c.resume)
+		c.resume = nil
+	}
+}
+
+/*  The function checks if the crawl is paused
+	Returns:
+		A true value if Pause was called and the crawl was not resumed
+ */
+
+func (c *Crawler) Paused() bool {
+	c.pauseLock.Lock()
+	defer c.pauseLock.Unlock()
+	return c.resume != nil
+}
+
+/*  The function blocks while the crawl is paused
+	Arguments:
+		ctx: The context of the crawl, the function returns when it is cancelled
+ */
+
+func (c *Crawler) waitIfPaused(ctx context.Context) {
+	c.pauseLock.Lock()
+	resume := c.resume
+	c.pauseLock.Unlock()
+	if resume == nil {
+		return
+	}
+	select {
+	case <-resume:
+	case <-ctx.Done():
+	}
+}
+
+/*  The function converts a fetchResult to the pageResult passed on to the onPage function
+	Arguments:
+		result: The fetchResult of the uri
+		depth: The number of links followed from the crawlURI to the uri
+		links: The number of unique links found on the page
+	Returns:
+		A pageResult for the uri
+ */
+
+func newPageResult(result fetchResult, depth int, links int) pageResult {
+	page := pageResult{
+		URI:         result.uri,
+		FinalURI:    result.finalURI,
+		StatusCode:  result.statusCode,
+		ContentType: result.contentType,
+		Charset:     result.charset,
+		Redirects:   len(result.redirects),
+		WireSize:    result.wireSize,
+		Size:        result.size,
+		Duration:    result.duration.Seconds(),
+		Depth:       depth,
+		Truncated:   result.truncated,
+		Skipped:     result.skipped,
+		Rendered:    result.rendered,
+		Links:       links,
+	}
+	if result.err != nil {
+		page.Error = result.err.Error()
+	}
+	return page
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newSiteTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<a href="/a">A</a><a href="/b">B</a>`))
+	})
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<a href="/b">B</a><a href="/missing">Missing</a>`))
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<p>b</p>`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCrawlerRun1(t *testing.T) {
+	testServer := newSiteTestServer()
+	defer testServer.Close()
+	testCrawler := newCrawler(defaultCrawlConfig(testServer.URL))
+	testCrawler.output = io.Discard
+	var testLock sync.Mutex
+	testResults := map[string]pageResult{}
+	testCrawler.onPage = func(result pageResult) {
+		testLock.Lock()
+		testResults[result.URI] = result
+		testLock.Unlock()
+	}
+	testCrawler.Run(context.Background())
+	if testCrawler.visitedCounter != 4 || len(testResults) != 4 {
+		fmt.Println("Run did not crawl every page of the site")
+		fmt.Println(testCrawler.visitedCounter, testResults)
+		t.Fail()
+	} else if testResults[testServer.URL].Links != 2 || testResults[testServer.URL+"/missing"].StatusCode != 404 {
+		fmt.Println("Run passed invalid results to onPage")
+		fmt.Println(testResults)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for Crawler Run passed")
+	}
+}
+
+func TestCrawlerPause1(t *testing.T) {
+	testServer := newSiteTestServer()
+	defer testServer.Close()
+	testCrawler := newCrawler(defaultCrawlConfig(testServer.URL))
+	testCrawler.output = io.Discard
+	testCrawler.Pause()
+	testDone := make(chan bool)
+	go func() {
+		testCrawler.Run(context.Background())
+		close(testDone)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	testPaused := testCrawler.snapshot()
+	testCrawler.Resume()
+	<-testDone
+	if !testPaused.Paused || testPaused.Visited != 0 {
+		fmt.Println("Pause did not stop the crawl")
+		fmt.Println(testPaused)
+		t.Fail()
+	} else if testCrawler.Paused() || testCrawler.snapshot().Visited != 4 {
+		fmt.Println("Resume did not finish the crawl")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for Crawler Pause passed")
+	}
+}
+
+func TestCrawlerCancel1(t *testing.T) {
+	testServer := newSiteTestServer()
+	defer testServer.Close()
+	testCrawler := newCrawler(defaultCrawlConfig(testServer.URL))
+	testCrawler.output = io.Discard
+	testCrawler.Pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	testCrawler.Run(ctx)
+	if testCrawler.visitedCounter != 0 || testCrawler.insertCounter != testCrawler.processedCounter {
+		fmt.Println("Run fetched URIs after the crawl was cancelled")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for Crawler cancel passed")
+	}
+}
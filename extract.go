@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+)
+
+//The values a field can be extracted as
+const (
+	fieldText = "text"
+	fieldAttr = "attr"
+	fieldHTML = "html"
+)
+
+/* extractConfig holds the options of the content extraction
+	enabled: Set when EXTRACT_RULES is set, extracts the fields of the rules from every html page
+	rules: Read from the EXTRACT_RULES file, the rules applied to the pages
+	output: Set from EXTRACT_OUTPUT, the JSON lines file the records are appended to, the records are printed
+		on the output of the Crawler if empty
+ */
+
+type extractConfig struct {
+	enabled bool
+	rules   []extractRule
+	output  string
+}
+
+/* extractRule maps the pages whose uri matches a pattern to the fields extracted from them
+	Name: The name of the rule, added to the records it produces
+	Pattern: A regexp matched against the uri of the page, every page matches an empty pattern
+	Fields: The fields extracted from the matching pages
+ */
+
+type extractRule struct {
+	Name    string         `json:"name"`
+	Pattern string         `json:"pattern,omitempty"`
+	Fields  []extractField `json:"fields"`
+	pattern *regexp.Regexp
+}
+
+/* extractField is a named value selected with a CSS selector or an XPath expression
+	Type: text (the default) for the text of the element, attr for the value of the Attr attribute or html
+		for the html inside the element
+	Multiple: Set to extract a list with the value of every matching element instead of the first one
+ */
+
+type extractField struct {
+	Name     string `json:"name"`
+	CSS      string `json:"css,omitempty"`
+	XPath    string `json:"xpath,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Attr     string `json:"attr,omitempty"`
+	Multiple bool   `json:"multiple,omitempty"`
+	css      cascadia.Sel
+	xpath    *xpath.Expr
+}
+
+/* extractedRecord holds the fields extracted from a page by a rule
+ */
+
+type extractedRecord struct {
+	URI    string                 `json:"uri"`
+	Rule   string                 `json:"rule"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+/* extractState holds the records extracted by a Crawler
+	recordLock: Serializes the writes of the records
+	recordCounter: A counter to keep a track of the number of records extracted
+ */
+
+type extractState struct {
+	recordLock    sync.Mutex
+	recordCounter int64
+}
+
+/*  The function reads the rules file of the EXTRACT_RULES env variable and the EXTRACT_OUTPUT env variable
+	If the rules file can not be read or holds an invalid rule an error message is generated and the
+	program exits.
+	Returns:
+		An extractConfig with the options set by the user
+ */
+
+func getExtractConfig() extractConfig {
+	var config extractConfig
+	if os.Getenv("EXTRACT_RULES") == "" {
+		return config
+	}
+	data, err := os.ReadFile(os.Getenv("EXTRACT_RULES"))
+	var rules []extractRule
+	if err == nil {
+		err = json.Unmarshal(data, &rules)
+	}
+	if err == nil {
+		rules, err = newExtractRules(rules)
+	}
+	if err != nil {
+		logger.Error("invalid rules file for EXTRACT_RULES env variable", "path", os.Getenv("EXTRACT_RULES"), "error", err)
+		os.Exit(1)
+	}
+	return extractConfig{enabled: true, rules: rules, output: strings.TrimSpace(os.Getenv("EXTRACT_OUTPUT"))}
+}
+
+/*  The function validates the extraction rules and compiles their patterns, selectors and expressions
+	Arguments:
+		rules: The rules read from the rules file or the job request
+	Returns:
+		The rules ready to be applied
+		An error naming the first invalid rule or field
+ */
+
+func newExtractRules(rules []extractRule) ([]extractRule, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("no extraction rules")
+	}
+	compiled := make([]extractRule, len(rules))
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return nil, errors.New("rule " + strconv.Itoa(i+1) + " has no name")
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: invalid pattern: %w", rule.Name, err)
+		}
+		if len(rule.Fields) == 0 {
+			return nil, errors.New("rule " + rule.Name + " has no fields")
+		}
+		rule.pattern = pattern
+		rule.Fields = append([]extractField(nil), rule.Fields...)
+		names := map[string]bool{}
+		for j := range rule.Fields {
+			field := &rule.Fields[j]
+			if err := field.compile(); err != nil {
+				return nil, fmt.Errorf("rule %s: field %q: %w", rule.Name, field.Name, err)
+			}
+			if names[field.Name] {
+				return nil, fmt.Errorf("rule %s: field %q is defined twice", rule.Name, field.Name)
+			}
+			names[field.Name] = true
+		}
+		compiled[i] = rule
+	}
+	return compiled, nil
+}
+
+/*  The function validates a field and compiles its selector or expression
+	Returns:
+		An error if the field is invalid
+ */
+
+func (field *extractField) compile() error {
+	if field.Name == "" {
+		return errors.New("name is required")
+	}
+	if (field.CSS == "") == (field.XPath == "") {
+		return errors.New("exactly one of css and xpath is required")
+	}
+	if field.Type == "" {
+		field.Type = fieldText
+	}
+	switch field.Type {
+	case fieldText, fieldHTML:
+	case fieldAttr:
+		if field.Attr == "" {
+			return errors.New("attr is required for the attr type")
+		}
+	default:
+		return errors.New("unsupported type " + field.Type + ", supported values are text, attr and html")
+	}
+	var err error
+	if field.CSS != "" {
+		field.css, err = cascadia.Parse(field.CSS)
+	} else {
+		field.xpath, err = xpath.Compile(field.XPath)
+	}
+	return err
+}
+
+/*  The function returns the value of the field in a document
+	Arguments:
+		doc: The parsed html page
+	Returns:
+		A string with the value of the first matching element, a slice with the value of every matching
+		element for multiple fields, or nil if no element matches
+ */
+
+func (field extractField) extract(doc *html.Node) interface{} {
+	var nodes []*html.Node
+	if field.css != nil {
+		nodes = cascadia.QueryAll(doc, field.css)
+	} else {
+		nodes = htmlquery.QuerySelectorAll(doc, field.xpath)
+	}
+	var values []string
+	for _, n := range nodes {
+		value, ok := field.value(n)
+		if !ok {
+			continue
+		}
+		if !field.Multiple {
+			return value
+		}
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+/*  The function returns the value of the field for a matching element
+	Arguments:
+		n: The matching element
+	Returns:
+		A string with the text, attribute or inner html of the element
+		A false value if the element does not have the attribute of an attr field
+ */
+
+func (field extractField) value(n *html.Node) (string, bool) {
+	switch field.Type {
+	case fieldAttr:
+		return nodeAttr(n, field.Attr)
+	case fieldHTML:
+		var b strings.Builder
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			_ = html.Render(&b, child)
+		}
+		return strings.TrimSpace(b.String()), true
+	}
+	return nodeText(n), true
+}
+
+/*  The function applies the rules matching the uri of a page
+	Arguments:
+		doc: The parsed html page
+		pageURI: The uri the page was served from
+		rules: The extraction rules
+	Returns:
+		An extractedRecord for every matching rule that found at least one field
+ */
+
+func extractRecords(doc *html.Node, pageURI string, rules []extractRule) []extractedRecord {
+	var records []extractedRecord
+	for _, rule := range rules {
+		if !rule.pattern.MatchString(pageURI) {
+			continue
+		}
+		record := extractedRecord{URI: pageURI, Rule: rule.Name, Fields: map[string]interface{}{}}
+		for _, field := range rule.Fields {
+			if value := field.extract(doc); value != nil {
+				record.Fields[field.Name] = value
+			}
+		}
+		if len(record.Fields) > 0 {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+/*  The function writes the records of a page as JSON lines to the EXTRACT_OUTPUT file or the output of the
+	Crawler. The html of the fields is not escaped
+	Arguments:
+		records: The extractedRecords of the page
+ */
+
+func (c *Crawler) emitRecords(records []extractedRecord) {
+	if len(records) == 0 {
+		return
+	}
+	var lines bytes.Buffer
+	encoder := json.NewEncoder(&lines)
+	encoder.SetEscapeHTML(false)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			logger.Error("error while encoding the extracted record", "uri", record.URI, "error", err)
+		}
+	}
+	atomic.AddInt64(&c.recordCounter, int64(len(records)))
+	c.recordLock.Lock()
+	defer c.recordLock.Unlock()
+	if c.config.extract.output == "" {
+		_, _ = c.output.Write(lines.Bytes())
+		return
+	}
+	file, err := os.OpenFile(c.config.extract.output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logger.Error("error opening the extraction output file", "path", c.config.extract.output, "error", err)
+		return
+	}
+	defer file.Close()
+	_, _ = file.Write(lines.Bytes())
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNewExtractRules1(t *testing.T) {
+	testInvalid := map[string][]extractRule{
+		"no rules":      nil,
+		"no name":       {{Fields: []extractField{{Name: "title", CSS: "h1"}}}},
+		"bad pattern":   {{Name: "product", Pattern: "(", Fields: []extractField{{Name: "title", CSS: "h1"}}}},
+		"no fields":     {{Name: "product"}},
+		"two selectors": {{Name: "product", Fields: []extractField{{Name: "title", CSS: "h1", XPath: "//h1"}}}},
+		"bad css":       {{Name: "product", Fields: []extractField{{Name: "title", CSS: "h1["}}}},
+		"bad xpath":     {{Name: "product", Fields: []extractField{{Name: "title", XPath: "//h1["}}}},
+		"bad type":      {{Name: "product", Fields: []extractField{{Name: "title", CSS: "h1", Type: "json"}}}},
+		"no attr":       {{Name: "product", Fields: []extractField{{Name: "image", CSS: "img", Type: fieldAttr}}}},
+		"duplicate":     {{Name: "product", Fields: []extractField{{Name: "title", CSS: "h1"}, {Name: "title", CSS: "h2"}}}},
+	}
+	for name, rules := range testInvalid {
+		if _, err := newExtractRules(rules); err == nil {
+			fmt.Println("newExtractRules accepted invalid rules: " + name)
+			t.Fail()
+		}
+	}
+	testRules, err := newExtractRules([]extractRule{{Name: "product", Pattern: "/p/", Fields: []extractField{{Name: "title", CSS: "h1"}}}})
+	if err != nil || testRules[0].Fields[0].Type != fieldText || testRules[0].pattern == nil || testRules[0].Fields[0].css == nil {
+		fmt.Println("newExtractRules did not compile valid rules")
+		fmt.Println(err)
+		t.Fail()
+	} else if !t.Failed() {
+		fmt.Println("Test 1 for newExtractRules passed")
+	}
+}
+
+func TestExtractRecords1(t *testing.T) {
+	var testRules []extractRule
+	_ = json.Unmarshal([]byte(`[
+		{"name": "product", "pattern": "/p/[0-9]+$", "fields": [
+			{"name": "title", "css": "h1.title"},
+			{"name": "price", "xpath": "//span[@class='price']"},
+			{"name": "image", "css": "img.main", "type": "attr", "attr": "src"},
+			{"name": "thumbnails", "xpath": "//ul/li/img/@src", "multiple": true},
+			{"name": "description", "css": "#description", "type": "html"},
+			{"name": "tags", "css": ".tag", "multiple": true},
+			{"name": "missing", "css": ".missing"}
+		]},
+		{"name": "page", "fields": [{"name": "heading", "xpath": "//h1"}]},
+		{"name": "empty", "fields": [{"name": "missing", "css": "h6"}]}
+	]`), &testRules)
+	testRules, err := newExtractRules(testRules)
+	testDoc := parseDocument(strings.NewReader(`<h1 class="title"> Desk
+		lamp </h1><span class="price">20 €</span><img class="main" src="/lamp.jpg">` +
+		`<ul><li><img src="/1.jpg"></li><li><img src="/2.jpg"></li></ul>` +
+		`<div id="description"> A <b>bright</b> lamp </div><a class="tag">desk</a><a class="tag">light</a>`))
+	testRecords := extractRecords(testDoc, "https://test.com/p/12", testRules)
+	testOther := extractRecords(testDoc, "https://test.com/about", testRules)
+	var testJSON bytes.Buffer
+	testEncoder := json.NewEncoder(&testJSON)
+	testEncoder.SetEscapeHTML(false)
+	_ = testEncoder.Encode(testRecords)
+	testExpected := `[{"uri":"https://test.com/p/12","rule":"product","fields":{"description":"A <b>bright</b> lamp",` +
+		`"image":"/lamp.jpg","price":"20 €","tags":["desk","light"],"thumbnails":["/1.jpg","/2.jpg"],"title":"Desk lamp"}},` +
+		`{"uri":"https://test.com/p/12","rule":"page","fields":{"heading":"Desk lamp"}}]` + "\n"
+	if err != nil || testJSON.String() != testExpected {
+		fmt.Println("extractRecords returned invalid records")
+		fmt.Println(err, testJSON.String())
+		t.Fail()
+	} else if len(testOther) != 1 || testOther[0].Rule != "page" {
+		fmt.Println("extractRecords applied a rule to a page that does not match its pattern")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for extractRecords passed")
+	}
+}
+
+func TestEmitRecords1(t *testing.T) {
+	testServer := newSiteTestServer()
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testRules, _ := newExtractRules([]extractRule{{Name: "links", Fields: []extractField{{Name: "links", CSS: "a", Type: fieldAttr, Attr: "href", Multiple: true}}}})
+	testConfig.extract = extractConfig{enabled: true, rules: testRules}
+	testCrawler := newCrawler(testConfig)
+	var testOutput bytes.Buffer
+	testCrawler.output = &testOutput
+	testResults := 0
+	testCrawler.onPage = func(result pageResult) {
+		testResults += len(result.Records)
+	}
+	testCrawler.config.threads = 1
+	testCrawler.Run(context.Background())
+	var testRecords []extractedRecord
+	for _, line := range strings.Split(strings.TrimSpace(testOutput.String()), "\n") {
+		var record extractedRecord
+		_ = json.Unmarshal([]byte(line), &record)
+		testRecords = append(testRecords, record)
+	}
+	testOutput.Reset()
+	testCrawler.printSummary()
+	if len(testRecords) != 2 || testRecords[0].URI != testServer.URL || testResults != 2 {
+		fmt.Println("The records were not printed on the output")
+		fmt.Println(testRecords)
+		t.Fail()
+	} else if !strings.Contains(testOutput.String(), "Extracted Records: 2\n") {
+		fmt.Println("The summary did not include the number of records")
+		fmt.Println(testOutput.String())
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for emitRecords passed")
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"container/heap"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//The crawl orders supported by CRAWL_STRATEGY
+const (
+	strategyBreadthFirst = "bfs"
+	strategyDepthFirst   = "dfs"
+	strategyBestFirst    = "best"
+)
+
+//The maximum number of sitemaps read from a sitemap index
+const maxSitemaps = 50
+
+/* priorityConfig holds the options of the order in which the URIs are crawled
+	strategy: Set from CRAWL_STRATEGY, bfs crawls by depth, dfs crawls the last found uri first and best
+		crawls the uri with the highest score first
+	patterns: Set from SCORE_PATTERNS, the weight added to the score of the URIs matching a regular expression
+	sitemapWeight: Set from SITEMAP_WEIGHT, the weight of the priority of the URIs listed in the sitemap.xml
+		of the host
+	inboundWeight: Set from INBOUND_WEIGHT, the weight added to the score for every page linking to the uri
+	scorer: Replaces the built in score of the best-first strategy if set. It is called while the frontier
+		is locked and should return quickly
+ */
+
+type priorityConfig struct {
+	strategy      string
+	patterns      []patternWeight
+	sitemapWeight float64
+	inboundWeight float64
+	scorer        func(uri string, depth int, inbound int) float64
+}
+
+/* patternWeight holds a regular expression of SCORE_PATTERNS and the weight added to the score of the
+	URIs matching it
+ */
+
+type patternWeight struct {
+	pattern *regexp.Regexp
+	weight  float64
+}
+
+/* frontierItem is a uri waiting in the frontier
+	depth: The number of links followed from the crawlURI to the uri
+	inbound: The number of pages found linking to the uri so far
+	seq: The order in which the uri was inserted
+	index: The position of the item in the heap
+ */
+
+type frontierItem struct {
+	uri     string
+	depth   int
+	inbound int
+	score   float64
+	seq     int64
+	index   int
+}
+
+/* frontierHeap orders the frontierItems by the crawl strategy. It implements heap.Interface
+ */
+
+type frontierHeap struct {
+	strategy string
+	items    []*frontierItem
+}
+
+/* frontier holds the URIs that were inserted but not fetched yet. The threads take the next uri with pop
+	which blocks until a uri is inserted or the frontier is closed
+	queued: The frontierItems in the heap by uri, used to update the score of a uri when a new link to it
+		is found
+	score: Computes the score of a uri for the best-first strategy, nil for the other strategies
+ */
+
+type frontier struct {
+	lock   sync.Mutex
+	ready  *sync.Cond
+	heap   frontierHeap
+	queued map[string]*frontierItem
+	score  func(uri string, depth int, inbound int) float64
+	seq    int64
+	closed bool
+}
+
+/* sitemapDocument holds the entries of a sitemap or a sitemap index
+ */
+
+type sitemapDocument struct {
+	URLs []struct {
+		Loc      string `xml:"loc"`
+		Priority string `xml:"priority"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+/*  The function reads the CRAWL_STRATEGY, SCORE_PATTERNS, SITEMAP_WEIGHT and INBOUND_WEIGHT env variables.
+	If a value is invalid an error message is generated and the program exits.
+	Defaults to a breadth-first crawl
+	Returns:
+		A priorityConfig with the options set by the user
+ */
+
+func getPriorityConfig() priorityConfig {
+	patterns, err := parseScorePatterns(os.Getenv("SCORE_PATTERNS"))
+	var sitemapWeight, inboundWeight float64
+	if err == nil && os.Getenv("SITEMAP_WEIGHT") != "" {
+		sitemapWeight, err = strconv.ParseFloat(os.Getenv("SITEMAP_WEIGHT"), 64)
+	}
+	if err == nil && os.Getenv("INBOUND_WEIGHT") != "" {
+		inboundWeight, err = strconv.ParseFloat(os.Getenv("INBOUND_WEIGHT"), 64)
+	}
+	var config priorityConfig
+	if err == nil {
+		config, err = newPriorityConfig(os.Getenv("CRAWL_STRATEGY"), patterns, sitemapWeight, inboundWeight)
+	}
+	if err != nil {
+		logger.Error("invalid value for CRAWL_STRATEGY, SCORE_PATTERNS, SITEMAP_WEIGHT or INBOUND_WEIGHT env variable",
+			"error", err)
+		os.Exit(1)
+	}
+	return config
+}
+
+/*  The function parses a comma separated list of pattern=weight pairs
+	Arguments:
+		scorePatterns: A string like /blog/=2,/tag/=-1
+	Returns:
+		A map with the weight of every pattern
+		An error if a pair has no weight or the weight is not a number
+ */
+
+func parseScorePatterns(scorePatterns string) (map[string]float64, error) {
+	patterns := map[string]float64{}
+	if scorePatterns == "" {
+		return patterns, nil
+	}
+	for _, pair := range strings.Split(scorePatterns, ",") {
+		separator := strings.LastIndex(pair, "=")
+		if separator < 1 {
+			return nil, fmt.Errorf("invalid score pattern %q, expected pattern=weight", pair)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(pair[separator+1:]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight in score pattern %q", pair)
+		}
+		patterns[strings.TrimSpace(pair[:separator])] = weight
+	}
+	return patterns, nil
+}
+
+/*  The function validates the crawl strategy and compiles the score patterns
+	Arguments:
+		strategy: bfs, dfs, best or an empty string for bfs
+		patterns: The weight of every regular expression matched against the URIs
+		sitemapWeight: The weight of the sitemap priority of a uri
+		inboundWeight: The weight of every page linking to a uri
+	Returns:
+		A priorityConfig with the options
+		An error if the strategy is not supported or a pattern is not a valid regular expression
+ */
+
+func newPriorityConfig(strategy string, patterns map[string]float64, sitemapWeight float64,
+	inboundWeight float64) (priorityConfig, error) {
+	config := priorityConfig{strategy: strings.ToLower(strategy), sitemapWeight: sitemapWeight, inboundWeight: inboundWeight}
+	switch config.strategy {
+	case "":
+		config.strategy = strategyBreadthFirst
+	case strategyBreadthFirst, strategyDepthFirst, strategyBestFirst:
+	default:
+		return config, fmt.Errorf("unsupported strategy %q, supported values are bfs, dfs and best", strategy)
+	}
+	for pattern, weight := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return config, fmt.Errorf("invalid score pattern %q: %w", pattern, err)
+		}
+		config.patterns = append(config.patterns, patternWeight{pattern: compiled, weight: weight})
+	}
+	sort.Slice(config.patterns, func(i, j int) bool {
+		return config.patterns[i].pattern.String() < config.patterns[j].pattern.String()
+	})
+	return config, nil
+}
+
+/*  The function creates an empty frontier
+	Arguments:
+		strategy: The crawl strategy used to order the URIs
+		score: Computes the score of a uri for the best-first strategy, ignored by the other strategies
+	Returns:
+		A pointer to a frontier
+ */
+
+func newFrontier(strategy string, score func(uri string, depth int, inbound int) float64) *frontier {
+	f := &frontier{heap: frontierHeap{strategy: strategy}, queued: map[string]*frontierItem{}}
+	if strategy == strategyBestFirst {
+		f.score = score
+	}
+	f.ready = sync.NewCond(&f.lock)
+	return f
+}
+
+/*  The function inserts a uri into the frontier and wakes up a thread waiting for it
+	Arguments:
+		uri: A string with the uri
+		depth: The number of links followed from the crawlURI to the uri
+		inbound: The number of pages found linking to the uri
+ */
+
+func (f *frontier) push(uri string, depth int, inbound int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	item := &frontierItem{uri: uri, depth: depth, inbound: inbound, seq: f.seq}
+	f.seq++
+	if f.score != nil {
+		item.score = f.score(uri, depth, inbound)
+	}
+	heap.Push(&f.heap, item)
+	f.queued[uri] = item
+	f.ready.Signal()
+}
+
+/*  The function records a new link to a uri that is waiting in the frontier and updates its score
+	Arguments:
+		uri: A string with the uri
+ */
+
+func (f *frontier) link(uri string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	item, ok := f.queued[uri]
+	if !ok {
+		return
+	}
+	item.inbound++
+	if f.score != nil {
+		item.score = f.score(item.uri, item.depth, item.inbound)
+		heap.Fix(&f.heap, item.index)
+	}
+}
+
+/*  The function takes the next uri to fetch from the frontier. It blocks while the frontier is empty
+	Returns:
+		The frontierItem of the uri
+		A false value once the frontier is closed and empty
+ */
+
+func (f *frontier) pop() (frontierItem, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for len(f.heap.items) == 0 && !f.closed {
+		f.ready.Wait()
+	}
+	if len(f.heap.items) == 0 {
+		return frontierItem{}, false
+	}
+	item := heap.Pop(&f.heap).(*frontierItem)
+	delete(f.queued, item.uri)
+	return *item, true
+}
+
+/*  The function closes the frontier so that the threads waiting in pop return
+ */
+
+func (f *frontier) close() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.closed = true
+	f.ready.Broadcast()
+}
+
+/*  The function returns the number of URIs waiting in the frontier
+	Returns:
+		An int with the number of URIs
+ */
+
+func (f *frontier) len() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.heap.items)
+}
+
+func (h *frontierHeap) Len() int { return len(h.items) }
+
+func (h *frontierHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	switch h.strategy {
+	case strategyDepthFirst:
+		return a.seq > b.seq
+	case strategyBestFirst:
+		if a.score != b.score {
+			return a.score > b.score
+		}
+	}
+	if a.depth != b.depth {
+		return a.depth < b.depth
+	}
+	return a.seq < b.seq
+}
+
+func (h *frontierHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *frontierHeap) Push(x any) {
+	item := x.(*frontierItem)
+	item.index = len(h.items)
+	h.items = append(h.items, item)
+}
+
+func (h *frontierHeap) Pop() any {
+	last := len(h.items) - 1
+	item := h.items[last]
+	h.items[last] = nil
+	h.items = h.items[:last]
+	return item
+}
+
+/*  The function computes the score of a uri for the best-first strategy from the score patterns, the
+	sitemap priority and the number of pages linking to the uri
+	Arguments:
+		uri: A string with the uri
+		depth: The number of links followed from the crawlURI to the uri
+		inbound: The number of pages found linking to the uri
+	Returns:
+		A float64 with the score, higher scores are crawled first
+ */
+
+func (c *Crawler) score(uri string, depth int, inbound int) float64 {
+	if c.config.priority.scorer != nil {
+		return c.config.priority.scorer(uri, depth, inbound)
+	}
+	var score float64
+	for _, pattern := range c.config.priority.patterns {
+		if pattern.pattern.MatchString(uri) {
+			score += pattern.weight
+		}
+	}
+	score += c.config.priority.sitemapWeight * c.sitemapPriority[strings.TrimSuffix(uri, "/")]
+	score += c.config.priority.inboundWeight * float64(inbound)
+	return score
+}
+
+/*  The function reads the priority of the URIs listed in the sitemap.xml of the host. Sitemap indexes are
+	followed for sitemaps on the same host
+	Returns:
+		A map with the priority of every uri without a trailing slash, URIs without a priority get 0.5
+ */
+
+func (c *Crawler) fetchSitemapPriorities() map[string]float64 {
+	priorities := map[string]float64{}
+	crawlURL, err := url.Parse(c.config.crawlURI)
+	if err != nil {
+		return priorities
+	}
+	sitemaps := []string{crawlURL.Scheme + "://" + crawlURL.Host + "/sitemap.xml"}
+	for i := 0; i < len(sitemaps) && i < maxSitemaps; i++ {
+		document, err := c.fetchSitemap(sitemaps[i])
+		if err != nil {
+			logger.Warn("error while reading sitemap", "uri", sitemaps[i], "error", err)
+			continue
+		}
+		for _, entry := range document.URLs {
+			priority := 0.5
+			if entry.Priority != "" {
+				if priority, err = strconv.ParseFloat(strings.TrimSpace(entry.Priority), 64); err != nil {
+					priority = 0.5
+				}
+			}
+			priorities[strings.TrimSuffix(strings.TrimSpace(entry.Loc), "/")] = priority
+		}
+		for _, entry := range document.Sitemaps {
+			sitemapURL, err := url.Parse(strings.TrimSpace(entry.Loc))
+			if err == nil && sitemapURL.Hostname() == c.config.hostBaseURL {
+				sitemaps = append(sitemaps, sitemapURL.String())
+			}
+		}
+	}
+	logger.Info("sitemap priorities loaded", "uris", len(priorities))
+	return priorities
+}
+
+/*  The function fetches and parses a single sitemap
+	Arguments:
+		uri: A string with the uri of the sitemap
+	Returns:
+		A sitemapDocument with the entries of the sitemap
+		An error if the sitemap could not be fetched or parsed
+ */
+
+func (c *Crawler) fetchSitemap(uri string) (sitemapDocument, error) {
+	var document sitemapDocument
+	resp, err := c.client.Get(uri)
+	if err != nil {
+		return document, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return document, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	err = xml.NewDecoder(io.LimitReader(resp.Body, c.config.content.maxBodySize)).Decode(&document)
+	return document, err
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func popTestFrontier(testFrontier *frontier) []string {
+	testFrontier.close()
+	var testURIs []string
+	for {
+		item, ok := testFrontier.pop()
+		if !ok {
+			return testURIs
+		}
+		testURIs = append(testURIs, item.uri)
+	}
+}
+
+func TestFrontier1(t *testing.T) {
+	testFrontier := newFrontier(strategyBreadthFirst, nil)
+	testFrontier.push("a", 2, 1)
+	testFrontier.push("b", 1, 1)
+	testFrontier.push("c", 1, 1)
+	testURIs := popTestFrontier(testFrontier)
+	if fmt.Sprint(testURIs) != "[b c a]" {
+		fmt.Println("The breadth-first frontier returned an invalid order")
+		fmt.Println(testURIs)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for frontier passed")
+	}
+}
+
+func TestFrontier2(t *testing.T) {
+	testFrontier := newFrontier(strategyDepthFirst, nil)
+	testFrontier.push("a", 1, 1)
+	testFrontier.push("b", 1, 1)
+	testFrontier.push("c", 2, 1)
+	testURIs := popTestFrontier(testFrontier)
+	if fmt.Sprint(testURIs) != "[c b a]" {
+		fmt.Println("The depth-first frontier returned an invalid order")
+		fmt.Println(testURIs)
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for frontier passed")
+	}
+}
+
+func TestFrontier3(t *testing.T) {
+	testFrontier := newFrontier(strategyBestFirst, func(uri string, depth int, inbound int) float64 {
+		return float64(inbound)
+	})
+	testFrontier.push("a", 1, 1)
+	testFrontier.push("b", 1, 1)
+	testFrontier.push("c", 1, 1)
+	testFrontier.link("c")
+	testFrontier.link("c")
+	testFrontier.link("b")
+	testFrontier.link("missing")
+	testLength := testFrontier.len()
+	testURIs := popTestFrontier(testFrontier)
+	if fmt.Sprint(testURIs) != "[c b a]" || testLength != 3 {
+		fmt.Println("The best-first frontier did not order the URIs by their score")
+		fmt.Println(testURIs)
+		t.Fail()
+	} else {
+		fmt.Println("Test 3 for frontier passed")
+	}
+}
+
+func TestNewPriorityConfig1(t *testing.T) {
+	testPatterns, testErr := parseScorePatterns("/blog/=2, /tag/=-1.5")
+	testConfig, testConfigErr := newPriorityConfig("BEST", testPatterns, 1, 0)
+	_, testStrategyErr := newPriorityConfig("random", nil, 0, 0)
+	_, testPatternErr := newPriorityConfig("", map[string]float64{"(": 1}, 0, 0)
+	_, testWeightErr := parseScorePatterns("/blog/")
+	if testErr != nil || testConfigErr != nil || testConfig.strategy != strategyBestFirst || len(testConfig.patterns) != 2 ||
+		testConfig.patterns[1].weight != -1.5 {
+		fmt.Println("newPriorityConfig did not parse the score patterns")
+		fmt.Println(testConfig, testErr, testConfigErr)
+		t.Fail()
+	} else if testStrategyErr == nil || testPatternErr == nil || testWeightErr == nil {
+		fmt.Println("newPriorityConfig accepted an invalid option")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for newPriorityConfig passed")
+	}
+}
+
+func TestFetchSitemapPriorities1(t *testing.T) {
+	var testServer *httptest.Server
+	testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>` + testServer.URL + `/pages.xml</loc></sitemap>` +
+				`<sitemap><loc>https://other.com/pages.xml</loc></sitemap></sitemapindex>`))
+		case "/pages.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>` + testServer.URL + `/a/</loc><priority>0.9</priority></url>` +
+				`<url><loc>` + testServer.URL + `/b</loc></url></urlset>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.priority.sitemapWeight = 2
+	testCrawler := newCrawler(testConfig)
+	testCrawler.sitemapPriority = testCrawler.fetchSitemapPriorities()
+	if testCrawler.score(testServer.URL+"/a", 1, 0) != 1.8 || testCrawler.score(testServer.URL+"/b", 1, 0) != 1 ||
+		len(testCrawler.sitemapPriority) != 2 {
+		fmt.Println("The sitemap priorities were not read")
+		fmt.Println(testCrawler.sitemapPriority)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for fetchSitemapPriorities passed")
+	}
+}
+
+func TestCrawlerStrategy1(t *testing.T) {
+	testServer := newSiteTestServer()
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.threads = 1
+	testConfig.priority, _ = newPriorityConfig(strategyBestFirst, map[string]float64{"/b$": 1}, 0, 0)
+	testCrawler := newCrawler(testConfig)
+	testCrawler.output = io.Discard
+	var testOrder []string
+	var testDepths []int
+	testCrawler.onPage = func(result pageResult) {
+		testOrder = append(testOrder, result.URI[len(testServer.URL):])
+		testDepths = append(testDepths, result.Depth)
+	}
+	testCrawler.Run(context.Background())
+	if fmt.Sprint(testOrder) != "[ /b /a /missing]" || fmt.Sprint(testDepths) != "[0 1 1 2]" {
+		fmt.Println("The best-first crawl did not fetch the URIs in the order of their score")
+		fmt.Println(testOrder, testDepths)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for crawl strategy passed")
+	}
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//The export formats of GRAPH_OUTPUT by file extension
+var graphFormats = map[string]string{".graphml": "graphml", ".dot": "dot", ".gv": "dot", ".csv": "csv"}
+
+//The number of pages with the most inbound links printed in the summary
+const topInboundPages = 10
+
+//The damping factor, maximum number of iterations and convergence tolerance of the PageRank computation
+const (
+	pageRankDamping    = 0.85
+	pageRankIterations = 100
+	pageRankTolerance  = 1e-9
+)
+
+/* linkGraphConfig holds the options of the link graph
+	enabled: Set when GRAPH_OUTPUT is set, records every link between the pages of the host
+	outputs: Set from GRAPH_OUTPUT, the files the graph is exported to at the end of the crawl
+ */
+
+type linkGraphConfig struct {
+	enabled bool
+	outputs []string
+}
+
+/* graphEdge is a link from one page of the host to another
+	text: The anchor text of the link
+	rel: The rel attribute of the link, redirect for the hop from a uri to the uri it redirects to
+ */
+
+type graphEdge struct {
+	source string
+	target string
+	text   string
+	rel    string
+}
+
+/* linkGraphState holds the pages and links recorded by a Crawler
+	graphNodes: The status code of every uri of the graph, 0 for URIs that were not fetched
+	graphEdges: Every link in the order it was found
+	graphCanonicals: The canonical URLs declared by every page, recorded for the site report
+ */
+
+type linkGraphState struct {
+	graphLock       sync.Mutex
+	graphNodes      map[string]int
+	graphEdges      []graphEdge
+	graphCanonicals map[string][]string
+}
+
+/* graphNode is a uri of the link graph with its computed metrics
+	Inbound: The number of other pages linking to the uri
+	Outbound: The number of other pages the uri links to
+	Depth: The smallest number of clicks from the crawlURI to the uri, -1 if it can not be reached
+	Orphan: Set for URIs other than the crawlURI without inbound links, e.g. pages only listed in the sitemap
+	PageRank: The internal PageRank of the uri, the ranks of all the nodes add up to 1
+ */
+
+type graphNode struct {
+	URI        string  `json:"uri"`
+	StatusCode int     `json:"status_code"`
+	Inbound    int     `json:"inbound"`
+	Outbound   int     `json:"outbound"`
+	Depth      int     `json:"depth"`
+	Orphan     bool    `json:"orphan"`
+	PageRank   float64 `json:"pagerank"`
+}
+
+/* linkGraph is a copy of the link graph of a crawl with the metrics of every node
+	nodes: The nodes sorted by uri
+	index: The position of every uri in nodes
+ */
+
+type linkGraph struct {
+	nodes []graphNode
+	edges []graphEdge
+	index map[string]int
+}
+
+/*  The function checks the value of the GRAPH_OUTPUT env variable which is a comma separated list of files
+	the link graph is exported to. The format is chosen by the extension of the file: .graphml, .dot, .gv
+	or .csv for an edge list. If an extension is not supported an error message is generated and the
+	program exits.
+	Returns:
+		A linkGraphConfig with the options set by the user
+ */
+
+func getLinkGraphConfig() linkGraphConfig {
+	var config linkGraphConfig
+	if os.Getenv("GRAPH_OUTPUT") == "" {
+		return config
+	}
+	for _, output := range strings.Split(os.Getenv("GRAPH_OUTPUT"), ",") {
+		output = strings.TrimSpace(output)
+		if _, ok := graphFormats[strings.ToLower(filepath.Ext(output))]; !ok {
+			logger.Error("invalid value for GRAPH_OUTPUT env variable, supported extensions are .graphml, .dot, .gv and .csv",
+				"value", output)
+			os.Exit(1)
+		}
+		config.outputs = append(config.outputs, output)
+	}
+	config.enabled = true
+	return config
+}
+
+/*  The function returns the key of a uri in the link graph so that a uri with and without a trailing
+	slash is the same node
+	Arguments:
+		uri: A string with the uri
+	Returns:
+		A string with the uri without its trailing slash
+ */
+
+func graphKey(uri string) string {
+	return strings.TrimSuffix(uri, "/")
+}
+
+/*  The function records a fetched uri as a node of the link graph and the redirect from the uri to the
+	uri it was served from
+	Arguments:
+		result: The fetchResult of the uri
+ */
+
+func (c *Crawler) recordPage(result fetchResult) {
+	if !c.config.graph.enabled {
+		return
+	}
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	if c.graphNodes == nil {
+		c.graphNodes = map[string]int{}
+	}
+	uri, finalURI := graphKey(result.uri), graphKey(result.finalURI)
+	c.graphNodes[uri] = result.statusCode
+	if finalURI != uri && len(result.redirects) > 0 {
+		if finalURL, err := url.Parse(finalURI); err == nil && finalURL.Hostname() == c.config.hostBaseURL {
+			c.graphNodes[uri] = result.redirects[0].statusCode
+			c.graphNodes[finalURI] = result.statusCode
+			c.graphEdges = append(c.graphEdges, graphEdge{source: uri, target: finalURI, rel: "redirect"})
+		}
+	}
+}
+
+/*  The function records the links from a page to the other URIs of the host as edges of the link graph
+	Arguments:
+		pageURI: The uri of the page the anchors were found on
+		anchors: The anchors found on the page
+ */
+
+func (c *Crawler) recordGraph(pageURI string, anchors []anchor) {
+	if !c.config.graph.enabled {
+		return
+	}
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	if c.graphNodes == nil {
+		c.graphNodes = map[string]int{}
+	}
+	for _, a := range anchors {
+		absolute := absoluteURL(a.href, pageURI)
+		absoluteURL, err := url.Parse(absolute)
+		if err != nil || absoluteURL.Hostname() != c.config.hostBaseURL || !c.linkScheme(absoluteURL.Scheme) ||
+			(c.config.siteRoot != "" && absoluteURL.Scheme != "file") {
+			continue
+		}
+		target := graphKey(absolute)
+		if _, ok := c.graphNodes[target]; !ok {
+			c.graphNodes[target] = 0
+		}
+		c.graphEdges = append(c.graphEdges, graphEdge{source: graphKey(pageURI), target: target, text: a.text, rel: a.rel})
+	}
+}
+
+/*  The function copies the link graph recorded so far and computes the inbound and outbound links, the
+	click depth, the orphan pages and the PageRank. URIs listed in the sitemap of the host are added as nodes
+	Returns:
+		A linkGraph with the metrics of every node
+ */
+
+func (c *Crawler) linkGraph() linkGraph {
+	c.graphLock.Lock()
+	graph := linkGraph{edges: append([]graphEdge(nil), c.graphEdges...), index: map[string]int{}}
+	nodes := map[string]int{}
+	for uri, statusCode := range c.graphNodes {
+		nodes[uri] = statusCode
+	}
+	for uri := range c.sitemapPriority {
+		nodes[uri] += 0
+	}
+	c.graphLock.Unlock()
+	seed := graphKey(c.config.crawlURI)
+	nodes[seed] += 0
+	for _, edge := range graph.edges {
+		nodes[edge.source] += 0
+		nodes[edge.target] += 0
+	}
+	for uri := range nodes {
+		graph.nodes = append(graph.nodes, graphNode{URI: uri, StatusCode: nodes[uri], Depth: -1})
+	}
+	sort.Slice(graph.nodes, func(i, j int) bool { return graph.nodes[i].URI < graph.nodes[j].URI })
+	for i, node := range graph.nodes {
+		graph.index[node.URI] = i
+	}
+	linked := map[[2]string]bool{}
+	neighbours := map[string][]string{}
+	for _, edge := range graph.edges {
+		pair := [2]string{edge.source, edge.target}
+		if edge.source == edge.target || linked[pair] {
+			continue
+		}
+		linked[pair] = true
+		graph.nodes[graph.index[edge.source]].Outbound++
+		graph.nodes[graph.index[edge.target]].Inbound++
+		neighbours[edge.source] = append(neighbours[edge.source], edge.target)
+	}
+	graph.nodes[graph.index[seed]].Depth = 0
+	for queue := []string{seed}; len(queue) > 0; queue = queue[1:] {
+		depth := graph.nodes[graph.index[queue[0]]].Depth
+		for _, target := range neighbours[queue[0]] {
+			if node := &graph.nodes[graph.index[target]]; node.Depth == -1 {
+				node.Depth = depth + 1
+				queue = append(queue, target)
+			}
+		}
+	}
+	for i := range graph.nodes {
+		graph.nodes[i].Orphan = graph.nodes[i].Inbound == 0 && graph.nodes[i].URI != seed
+	}
+	graph.computePageRank()
+	return graph
+}
+
+/*  The function computes the internal PageRank of every node. Links with rel=nofollow and links from a page
+	to itself are ignored and the rank of the pages without outbound links is spread over all the pages
+ */
+
+func (graph *linkGraph) computePageRank() {
+	n := float64(len(graph.nodes))
+	outbound := make([][]int, len(graph.nodes))
+	linked := map[[2]int]bool{}
+	for _, edge := range graph.edges {
+		pair := [2]int{graph.index[edge.source], graph.index[edge.target]}
+		if pair[0] == pair[1] || linked[pair] || hasRel(edge.rel, "nofollow") {
+			continue
+		}
+		linked[pair] = true
+		outbound[pair[0]] = append(outbound[pair[0]], pair[1])
+	}
+	rank := make([]float64, len(graph.nodes))
+	for i := range rank {
+		rank[i] = 1 / n
+	}
+	for iteration := 0; iteration < pageRankIterations; iteration++ {
+		next := make([]float64, len(rank))
+		var dangling, delta float64
+		for i, targets := range outbound {
+			if len(targets) == 0 {
+				dangling += rank[i]
+			}
+			for _, target := range targets {
+				next[target] += rank[i] / float64(len(targets))
+			}
+		}
+		for i := range next {
+			next[i] = (1-pageRankDamping)/n + pageRankDamping*(next[i]+dangling/n)
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank = next
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+	for i := range graph.nodes {
+		graph.nodes[i].PageRank = rank[i]
+	}
+}
+
+/*  The function checks if a rel attribute contains a link type
+	Arguments:
+		rel: The space separated link types of the rel attribute
+		linkType: The link type to look for
+	Returns:
+		A true value if the link type is present
+ */
+
+func hasRel(rel string, linkType string) bool {
+	for _, field := range strings.Fields(rel) {
+		if field == linkType {
+			return true
+		}
+	}
+	return false
+}
+
+/*  The function writes the link graph in one of the export formats
+	Arguments:
+		w: The io.Writer the graph is written to
+		format: graphml, dot or csv
+	Returns:
+		An error if the format is not supported or the graph could not be written
+ */
+
+func (graph linkGraph) write(w io.Writer, format string) error {
+	switch format {
+	case "graphml":
+		return graph.writeGraphML(w)
+	case "dot":
+		return graph.writeDOT(w)
+	case "csv":
+		return graph.writeCSV(w)
+	}
+	return fmt.Errorf("unsupported graph format %q, supported values are graphml, dot and csv", format)
+}
+
+/*  The function writes the link graph as GraphML with the metrics of the nodes and the anchor text and rel
+	attribute of the edges as data keys
+	Arguments:
+		w: The io.Writer the graph is written to
+	Returns:
+		An error if the graph could not be written
+ */
+
+func (graph linkGraph) writeGraphML(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	b.WriteString(`  <key id="status_code" for="node" attr.name="status_code" attr.type="int"/>` + "\n")
+	b.WriteString(`  <key id="inbound" for="node" attr.name="inbound" attr.type="int"/>` + "\n")
+	b.WriteString(`  <key id="outbound" for="node" attr.name="outbound" attr.type="int"/>` + "\n")
+	b.WriteString(`  <key id="depth" for="node" attr.name="depth" attr.type="int"/>` + "\n")
+	b.WriteString(`  <key id="orphan" for="node" attr.name="orphan" attr.type="boolean"/>` + "\n")
+	b.WriteString(`  <key id="pagerank" for="node" attr.name="pagerank" attr.type="double"/>` + "\n")
+	b.WriteString(`  <key id="text" for="edge" attr.name="text" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="rel" for="edge" attr.name="rel" attr.type="string"/>` + "\n")
+	b.WriteString(`  <graph id="site" edgedefault="directed">` + "\n")
+	for _, node := range graph.nodes {
+		b.WriteString(`    <node id="` + xmlEscape(node.URI) + `">`)
+		b.WriteString(`<data key="status_code">` + strconv.Itoa(node.StatusCode) + `</data>`)
+		b.WriteString(`<data key="inbound">` + strconv.Itoa(node.Inbound) + `</data>`)
+		b.WriteString(`<data key="outbound">` + strconv.Itoa(node.Outbound) + `</data>`)
+		b.WriteString(`<data key="depth">` + strconv.Itoa(node.Depth) + `</data>`)
+		b.WriteString(`<data key="orphan">` + strconv.FormatBool(node.Orphan) + `</data>`)
+		b.WriteString(`<data key="pagerank">` + strconv.FormatFloat(node.PageRank, 'g', 6, 64) + `</data></node>` + "\n")
+	}
+	for _, edge := range graph.edges {
+		b.WriteString(`    <edge source="` + xmlEscape(edge.source) + `" target="` + xmlEscape(edge.target) + `">`)
+		b.WriteString(`<data key="text">` + xmlEscape(edge.text) + `</data>`)
+		b.WriteString(`<data key="rel">` + xmlEscape(edge.rel) + `</data></edge>` + "\n")
+	}
+	b.WriteString("  </graph>\n</graphml>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+/*  The function writes the link graph in the Graphviz DOT language
+	Arguments:
+		w: The io.Writer the graph is written to
+	Returns:
+		An error if the graph could not be written
+ */
+
+func (graph linkGraph) writeDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph site {\n")
+	for _, node := range graph.nodes {
+		fmt.Fprintf(&b, "  %s [status_code=%d, inbound=%d, outbound=%d, depth=%d, orphan=%t, pagerank=%.6g];\n",
+			dotQuote(node.URI), node.StatusCode, node.Inbound, node.Outbound, node.Depth, node.Orphan, node.PageRank)
+	}
+	for _, edge := range graph.edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s", dotQuote(edge.source), dotQuote(edge.target), dotQuote(edge.text))
+		if edge.rel != "" {
+			b.WriteString(", rel=" + dotQuote(edge.rel))
+		}
+		b.WriteString("];\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+/*  The function writes the edges of the link graph as CSV with a source, target, text and rel column
+	Arguments:
+		w: The io.Writer the graph is written to
+	Returns:
+		An error if the graph could not be written
+ */
+
+func (graph linkGraph) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"source", "target", "text", "rel"})
+	for _, edge := range graph.edges {
+		_ = writer.Write([]string{edge.source, edge.target, edge.text, edge.rel})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+/*  The function exports the link graph to every file of GRAPH_OUTPUT. Files that can not be written are
+	reported and skipped
+ */
+
+func (c *Crawler) exportGraph() {
+	graph := c.linkGraph()
+	for _, output := range c.config.graph.outputs {
+		file, err := os.Create(output)
+		if err == nil {
+			err = graph.write(file, graphFormats[strings.ToLower(filepath.Ext(output))])
+			err = errors.Join(err, file.Close())
+		}
+		if err != nil {
+			logger.Error("error while exporting the link graph", "path", output, "error", err)
+			continue
+		}
+		logger.Info("link graph exported", "path", output, "nodes", len(graph.nodes), "edges", len(graph.edges))
+	}
+}
+
+/*  The function prints the number of pages and links of the link graph, the click depth histogram, the
+	pages with the most inbound links and the orphan pages
+ */
+
+func (c *Crawler) printGraphSummary() {
+	graph := c.linkGraph()
+	_, _ = fmt.Fprintln(c.output, "Link Graph: "+strconv.Itoa(len(graph.nodes))+" pages, "+strconv.Itoa(len(graph.edges))+" links")
+	depths := map[int]int{}
+	var orphans []string
+	for _, node := range graph.nodes {
+		depths[node.Depth]++
+		if node.Orphan {
+			orphans = append(orphans, node.URI)
+		}
+	}
+	var levels []int
+	for depth := range depths {
+		levels = append(levels, depth)
+	}
+	sort.Ints(levels)
+	_, _ = fmt.Fprintln(c.output, "Click Depth:")
+	for _, depth := range levels {
+		label := strconv.Itoa(depth)
+		if depth == -1 {
+			label = "unreachable"
+		}
+		_, _ = fmt.Fprintln(c.output, "\t"+label+": "+strconv.Itoa(depths[depth]))
+	}
+	nodes := append([]graphNode(nil), graph.nodes...)
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Inbound > nodes[j].Inbound })
+	_, _ = fmt.Fprintln(c.output, "Most Linked Pages:")
+	for i := 0; i < len(nodes) && i < topInboundPages && nodes[i].Inbound > 0; i++ {
+		_, _ = fmt.Fprintln(c.output, "\t"+nodes[i].URI+": "+strconv.Itoa(nodes[i].Inbound))
+	}
+	_, _ = fmt.Fprintln(c.output, "Orphan Pages: "+strconv.Itoa(len(orphans)))
+	for _, orphan := range orphans {
+		_, _ = fmt.Fprintln(c.output, "\t"+orphan)
+	}
+}
+
+/*  The function escapes a string for an XML attribute or element
+	Arguments:
+		s: The string to escape
+	Returns:
+		A string that can be used in XML
+ */
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+/*  The function quotes a string as a DOT identifier
+	Arguments:
+		s: The string to quote
+	Returns:
+		A string in double quotes with backslashes and double quotes escaped
+ */
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestGetAllAnchorsHTMLRel1(t *testing.T) {
+	testAnchors := getAllAnchorsHTML(strings.NewReader(`<a rel="NoFollow  ugc" href="/a">A</a><a href="/b">B</a>`))
+	if len(testAnchors) != 2 || testAnchors[0].rel != "nofollow ugc" || testAnchors[1].rel != "" {
+		fmt.Println("getAllAnchorsHTML did not return the rel attribute of the anchors")
+		fmt.Println(testAnchors)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for the rel attribute of getAllAnchorsHTML passed")
+	}
+}
+
+func TestLinkGraph1(t *testing.T) {
+	testServer := newSiteTestServer()
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.graph.enabled = true
+	testCrawler := newCrawler(testConfig)
+	testCrawler.output = io.Discard
+	testCrawler.Run(context.Background())
+	testGraph := testCrawler.linkGraph()
+	testB := testGraph.nodes[testGraph.index[testServer.URL+"/b"]]
+	testMissing := testGraph.nodes[testGraph.index[testServer.URL+"/missing"]]
+	if len(testGraph.nodes) != 4 || len(testGraph.edges) != 4 {
+		fmt.Println("The link graph did not record every page and link")
+		fmt.Println(testGraph.nodes, testGraph.edges)
+		t.Fail()
+	} else if testB.Inbound != 2 || testB.Depth != 1 || testMissing.Depth != 2 || testMissing.StatusCode != 404 ||
+		testMissing.Orphan {
+		fmt.Println("The link graph computed invalid metrics")
+		fmt.Println(testB, testMissing)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for the link graph passed")
+	}
+}
+
+func TestLinkGraph2(t *testing.T) {
+	testCrawler := newCrawler(defaultCrawlConfig("https://test.com"))
+	testCrawler.config.graph.enabled = true
+	testCrawler.sitemapPriority = map[string]float64{"https://test.com/orphan": 0.5}
+	testCrawler.recordPage(fetchResult{uri: "https://test.com", finalURI: "https://test.com/", statusCode: 200})
+	testCrawler.recordGraph("https://test.com/", []anchor{{href: "/a", text: `Say "hi"`, rel: "nofollow"},
+		{href: "https://other.com/b", text: "B"}, {href: "/a/", text: "A"}})
+	testGraph := testCrawler.linkGraph()
+	var testGraphML, testDOT, testCSV bytes.Buffer
+	_ = testGraph.write(&testGraphML, "graphml")
+	_ = testGraph.write(&testDOT, "dot")
+	_ = testGraph.write(&testCSV, "csv")
+	testRecords, _ := csv.NewReader(&testCSV).ReadAll()
+	var testDocument struct {
+		Nodes []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"graph>node"`
+	}
+	testXMLErr := xml.Unmarshal(testGraphML.Bytes(), &testDocument)
+	testOrphan := testGraph.nodes[testGraph.index["https://test.com/orphan"]]
+	if len(testGraph.nodes) != 3 || !testOrphan.Orphan || testOrphan.Depth != -1 ||
+		testGraph.nodes[testGraph.index["https://test.com/a"]].Inbound != 1 {
+		fmt.Println("The link graph computed invalid metrics")
+		fmt.Println(testGraph.nodes)
+		t.Fail()
+	} else if testXMLErr != nil || len(testDocument.Nodes) != 3 || len(testRecords) != 3 ||
+		testRecords[1][2] != `Say "hi"` || testRecords[1][3] != "nofollow" {
+		fmt.Println("The link graph was not exported as GraphML and CSV")
+		fmt.Println(testXMLErr, testDocument, testRecords)
+		t.Fail()
+	} else if !strings.Contains(testDOT.String(), `"https://test.com" -> "https://test.com/a" [label="Say \"hi\"", rel="nofollow"];`) {
+		fmt.Println("The link graph was not exported as DOT")
+		fmt.Println(testDOT.String())
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for the link graph passed")
+	}
+}
+
+func TestPrintGraphSummary1(t *testing.T) {
+	testCrawler := newCrawler(defaultCrawlConfig("https://test.com"))
+	testCrawler.config.graph.enabled = true
+	var testOutput bytes.Buffer
+	testCrawler.output = &testOutput
+	testCrawler.recordGraph("https://test.com", []anchor{{href: "/a"}, {href: "/b"}})
+	testCrawler.recordGraph("https://test.com/a", []anchor{{href: "/b"}})
+	testCrawler.printGraphSummary()
+	if !strings.Contains(testOutput.String(), "Link Graph: 3 pages, 3 links") ||
+		!strings.Contains(testOutput.String(), "\thttps://test.com/b: 2\n") ||
+		!strings.Contains(testOutput.String(), "Orphan Pages: 0") {
+		fmt.Println("printGraphSummary printed an invalid summary")
+		fmt.Println(testOutput.String())
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for printGraphSummary passed")
+	}
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+/* requestHook is called with the request of every uri before it is sent. It can change the headers of the
+	request or return errSkipURI to skip the uri without fetching it, any other error fails the uri
+ */
+
+type requestHook func(req *http.Request) error
+
+/* responseHook is called with the fetchResult of every uri before its body is stored and parsed. It can
+	inspect the response or transform its body, an error fails the uri and drops its body
+ */
+
+type responseHook func(result *fetchResult) error
+
+/* linkFilterHook is called with every link in the host of the crawl before it is inserted into the frontier
+	The link is dropped if a hook returns false
+ */
+
+type linkFilterHook func(link string, pageURI string, depth int) bool
+
+/* resultSink is called with the pageResult of every uri once it has been processed
+ */
+
+type resultSink func(page pageResult)
+
+/* pipelineHooks holds the functions plugged into the processing of every uri by a Crawler. The hooks of a
+	kind are called in the order they were added
+ */
+
+type pipelineHooks struct {
+	request    []requestHook
+	response   []responseHook
+	linkFilter []linkFilterHook
+	sinks      []resultSink
+}
+
+/* hookTransport calls the requestHooks of a Crawler with every request it sends, including the redirects and
+	the requests of the sitemap, the login form and the broken link checker
+ */
+
+type hookTransport struct {
+	base  http.RoundTripper
+	hooks *pipelineHooks
+}
+
+/* hooksConfig holds the hooks of the crawl read from the HOOKS_CONFIG file, so that the requests, responses,
+	links and results of a crawl can be changed without changing the crawler
+	Headers: The headers set on every request, replacing the values set by the crawler
+	Skip: The regexps of the URIs skipped without being fetched
+	ExcludeLinks: The regexps of the links dropped before they are inserted into the frontier
+	Replace: The replacements applied in order to the body of every response before it is stored and parsed
+	SinkURL: The url the pageResult of every uri is posted to as JSON
+ */
+
+type hooksConfig struct {
+	Headers      map[string]string `json:"headers,omitempty"`
+	Skip         []string          `json:"skip,omitempty"`
+	ExcludeLinks []string          `json:"exclude_links,omitempty"`
+	Replace      []bodyReplacement `json:"replace,omitempty"`
+	SinkURL      string            `json:"sink_url,omitempty"`
+	skip         []*regexp.Regexp
+	excludeLinks []*regexp.Regexp
+}
+
+/* bodyReplacement replaces the matches of a regexp in the body of a response, the replacement can refer to
+	the groups of the regexp with $1 or ${name}
+ */
+
+type bodyReplacement struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+	pattern     *regexp.Regexp
+}
+
+//The error returned by a requestHook to skip a uri
+var errSkipURI = errors.New("uri skipped by a request hook")
+
+//The maximum time taken to post a pageResult to the SinkURL of the hooks config
+const sinkTimeout = 10 * time.Second
+
+/*  The function reads the file of the HOOKS_CONFIG env variable. If the file can not be read or is invalid an
+	error message is generated and the program exits.
+	Returns:
+		A hooksConfig with the hooks set by the user
+ */
+
+func getHooksConfig() hooksConfig {
+	var config hooksConfig
+	if os.Getenv("HOOKS_CONFIG") == "" {
+		return config
+	}
+	data, err := os.ReadFile(os.Getenv("HOOKS_CONFIG"))
+	if err == nil {
+		err = json.Unmarshal(data, &config)
+	}
+	if err == nil {
+		config, err = newHooksConfig(config)
+	}
+	if err != nil {
+		logger.Error("invalid hooks file for HOOKS_CONFIG env variable", "path", os.Getenv("HOOKS_CONFIG"), "error", err)
+		os.Exit(1)
+	}
+	return config
+}
+
+/*  The function validates the headers and the sink url of a hooksConfig and compiles its patterns
+	Arguments:
+		config: The hooksConfig read from the hooks file
+	Returns:
+		The hooksConfig ready to be added to a Crawler
+		An error naming the first invalid option
+ */
+
+func newHooksConfig(config hooksConfig) (hooksConfig, error) {
+	for name, value := range config.Headers {
+		if !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			return config, errors.New("invalid header " + name)
+		}
+	}
+	var err error
+	if config.skip, err = compilePatterns(config.Skip); err != nil {
+		return config, errors.New("skip: " + err.Error())
+	}
+	if config.excludeLinks, err = compilePatterns(config.ExcludeLinks); err != nil {
+		return config, errors.New("exclude_links: " + err.Error())
+	}
+	for i, replacement := range config.Replace {
+		if config.Replace[i].pattern, err = regexp.Compile(replacement.Pattern); err != nil {
+			return config, errors.New("replace: " + err.Error())
+		}
+	}
+	if config.SinkURL != "" {
+		sinkURL, err := url.Parse(config.SinkURL)
+		if err != nil || (sinkURL.Scheme != "http" && sinkURL.Scheme != "https") || sinkURL.Host == "" {
+			return config, errors.New("invalid sink_url " + config.SinkURL)
+		}
+	}
+	return config, nil
+}
+
+/*  The function compiles a list of regexps
+	Arguments:
+		patterns: The regexps
+	Returns:
+		The compiled regexps
+		An error if a regexp is invalid
+ */
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		expression, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, expression)
+	}
+	return compiled, nil
+}
+
+/*  The function adds the hooks of a hooksConfig to the Crawler
+	Arguments:
+		config: The hooksConfig
+ */
+
+func (c *Crawler) addConfigHooks(config hooksConfig) {
+	if len(config.Headers) > 0 {
+		header := http.Header{}
+		for name, value := range config.Headers {
+			header.Set(name, value)
+		}
+		c.addRequestHook(setHeaders(header))
+	}
+	for _, pattern := range config.skip {
+		c.addRequestHook(skipMatching(pattern))
+	}
+	for _, pattern := range config.excludeLinks {
+		c.addLinkFilter(excludeMatching(pattern))
+	}
+	for _, replacement := range config.Replace {
+		c.addResponseHook(replaceBody(replacement))
+	}
+	if config.SinkURL != "" {
+		c.addSink(postSink(config.SinkURL))
+	}
+}
+
+/*  The function adds a requestHook to the Crawler. It must be called before Run
+	Arguments:
+		hook: The requestHook
+ */
+
+func (c *Crawler) addRequestHook(hook requestHook) {
+	c.hooks.request = append(c.hooks.request, hook)
+}
+
+/*  The function adds a responseHook to the Crawler. It must be called before Run
+	Arguments:
+		hook: The responseHook
+ */
+
+func (c *Crawler) addResponseHook(hook responseHook) {
+	c.hooks.response = append(c.hooks.response, hook)
+}
+
+/*  The function adds a linkFilterHook to the Crawler. It must be called before Run
+	Arguments:
+		hook: The linkFilterHook
+ */
+
+func (c *Crawler) addLinkFilter(hook linkFilterHook) {
+	c.hooks.linkFilter = append(c.hooks.linkFilter, hook)
+}
+
+/*  The function adds a resultSink to the Crawler. It must be called before Run
+	Arguments:
+		sink: The resultSink
+ */
+
+func (c *Crawler) addSink(sink resultSink) {
+	c.hooks.sinks = append(c.hooks.sinks, sink)
+}
+
+/*  The function calls the requestHooks with a request until one of them returns an error
+	Arguments:
+		req: The request about to be sent
+	Returns:
+		The error of the first failing hook, errSkipURI if the uri is skipped
+ */
+
+func (hooks pipelineHooks) applyRequest(req *http.Request) error {
+	for _, hook := range hooks.request {
+		if err := hook(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*  The function calls the responseHooks with the fetchResult of a uri. A failing hook sets the error of the
+	result, drops its body and stops the remaining hooks
+	Arguments:
+		result: The fetchResult of the uri
+ */
+
+func (hooks pipelineHooks) applyResponse(result *fetchResult) {
+	if result.err != nil || result.skipped {
+		return
+	}
+	for _, hook := range hooks.response {
+		if err := hook(result); err != nil {
+			logger.Error("response hook failed", "uri", result.uri, "error", err)
+			result.err = err
+			result.body = ""
+			return
+		}
+	}
+}
+
+/*  The function checks a link with the linkFilterHooks
+	Arguments:
+		link: The absolute link
+		pageURI: The uri of the page the link was found on
+		depth: The depth of the link
+	Returns:
+		A false value if a hook drops the link
+ */
+
+func (hooks pipelineHooks) allowLink(link string, pageURI string, depth int) bool {
+	for _, hook := range hooks.linkFilter {
+		if !hook(link, pageURI, depth) {
+			return false
+		}
+	}
+	return true
+}
+
+/*  The function returns a requestHook setting headers on every request
+	Arguments:
+		header: The headers to set, replacing the values set by the crawler
+	Returns:
+		A requestHook
+ */
+
+func setHeaders(header http.Header) requestHook {
+	return func(req *http.Request) error {
+		for name, values := range header {
+			req.Header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+		}
+		return nil
+	}
+}
+
+/*  The function returns a requestHook skipping the URIs matching a pattern
+	Arguments:
+		pattern: The regexp matched against the uri of the request
+	Returns:
+		A requestHook
+ */
+
+func skipMatching(pattern *regexp.Regexp) requestHook {
+	return func(req *http.Request) error {
+		if pattern.MatchString(req.URL.String()) {
+			return errSkipURI
+		}
+		return nil
+	}
+}
+
+/*  The function returns a linkFilterHook dropping the links matching a pattern
+	Arguments:
+		pattern: The regexp matched against the absolute link
+	Returns:
+		A linkFilterHook
+ */
+
+func excludeMatching(pattern *regexp.Regexp) linkFilterHook {
+	return func(link string, pageURI string, depth int) bool {
+		return !pattern.MatchString(link)
+	}
+}
+
+/*  The function returns a responseHook replacing the matches of a regexp in the body of every response
+	Arguments:
+		replacement: The bodyReplacement
+	Returns:
+		A responseHook
+ */
+
+func replaceBody(replacement bodyReplacement) responseHook {
+	return func(result *fetchResult) error {
+		result.body = replacement.pattern.ReplaceAllString(result.body, replacement.Replacement)
+		return nil
+	}
+}
+
+/*  The function calls the requestHooks with a copy of every request before it is sent by the base
+	http.RoundTripper
+	Arguments:
+		req: The request
+	Returns:
+		The response of the base http.RoundTripper
+		The error of the first failing hook, errSkipURI if the uri is skipped
+ */
+
+func (t *hookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.hooks.request) == 0 {
+		return t.base.RoundTrip(req)
+	}
+	hooked := req.Clone(req.Context())
+	if err := t.hooks.applyRequest(hooked); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.base.RoundTrip(hooked)
+}
+
+/*  The function checks the value of the RESULTS_OUTPUT env variable which is the JSON lines file the
+	pageResult of every uri is written to
+	Returns:
+		A string with the path of the file, empty if the results are not written
+ */
+
+func getResultsOutput() string {
+	return strings.TrimSpace(os.Getenv("RESULTS_OUTPUT"))
+}
+
+/*  The function returns a resultSink appending every pageResult to a JSON lines file
+	Arguments:
+		path: The path of the file
+	Returns:
+		A resultSink
+ */
+
+func fileSink(path string) resultSink {
+	var lock sync.Mutex
+	return func(page pageResult) {
+		line, err := json.Marshal(page)
+		if err != nil {
+			logger.Error("error while encoding the result", "uri", page.URI, "error", err)
+			return
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			logger.Error("error opening the results output file", "path", path, "error", err)
+			return
+		}
+		defer file.Close()
+		_, _ = file.Write(append(line, '\n'))
+	}
+}
+
+/*  The function returns a resultSink posting every pageResult as JSON to a url. A failed request is logged and
+	the crawl goes on
+	Arguments:
+		uri: The url of the sink
+	Returns:
+		A resultSink
+ */
+
+func postSink(uri string) resultSink {
+	client := &http.Client{Timeout: sinkTimeout}
+	return func(page pageResult) {
+		body, err := json.Marshal(page)
+		if err != nil {
+			logger.Error("error while encoding the result", "uri", page.URI, "error", err)
+			return
+		}
+		resp, err := client.Post(uri, "application/json", bytes.NewReader(body))
+		if err != nil {
+			logger.Error("error while posting the result", "uri", page.URI, "sink", uri, "error", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			logger.Error("the sink rejected the result", "uri", page.URI, "sink", uri, "status_code", resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPipelineHooks1(t *testing.T) {
+	testServer := newSiteTestServer()
+	defer testServer.Close()
+	testCrawler := newCrawler(defaultCrawlConfig(testServer.URL))
+	testCrawler.output = io.Discard
+	testCrawler.addRequestHook(skipMatching(regexp.MustCompile(`/b$`)))
+	testCrawler.addLinkFilter(func(link string, pageURI string, depth int) bool {
+		return !strings.HasSuffix(link, "/missing")
+	})
+	var testLock sync.Mutex
+	testResults := map[string]pageResult{}
+	testCrawler.addSink(func(page pageResult) {
+		testLock.Lock()
+		defer testLock.Unlock()
+		testResults[strings.TrimPrefix(page.URI, testServer.URL)] = page
+	})
+	testCrawler.Run(context.Background())
+	if len(testResults) != 3 || testResults["/a"].StatusCode != 200 || !testResults["/b"].Skipped ||
+		testResults["/b"].StatusCode != 0 {
+		fmt.Println("The request and link filter hooks were not applied")
+		fmt.Println(testResults)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for pipelineHooks passed")
+	}
+}
+
+func TestPipelineHooks2(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" && r.Header.Get("X-Token") == "secret" {
+			_, _ = w.Write([]byte(`<a href="/header">Header</a>`))
+		}
+	}))
+	defer testServer.Close()
+	testCrawler := newCrawler(defaultCrawlConfig(testServer.URL))
+	testCrawler.output = io.Discard
+	testCrawler.addRequestHook(setHeaders(http.Header{"x-token": {"secret"}}))
+	testCrawler.addResponseHook(func(result *fetchResult) error {
+		if result.finalURI == testServer.URL {
+			result.body += `<a href="/body">Body</a>`
+		}
+		return nil
+	})
+	var testLock sync.Mutex
+	var testURIs []string
+	testCrawler.onPage = func(page pageResult) {
+		testLock.Lock()
+		defer testLock.Unlock()
+		testURIs = append(testURIs, strings.TrimPrefix(page.URI, testServer.URL))
+	}
+	testCrawler.Run(context.Background())
+	sort.Strings(testURIs)
+	if strings.Join(testURIs, " ") != " /body /header" {
+		fmt.Println("The header and response hooks were not applied")
+		fmt.Println(testURIs)
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for pipelineHooks passed")
+	}
+}
+
+func TestFileSink1(t *testing.T) {
+	testServer := newSiteTestServer()
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.resultsOutput = filepath.Join(t.TempDir(), "results.jsonl")
+	testCrawler := newCrawler(testConfig)
+	testCrawler.output = io.Discard
+	testCrawler.Run(context.Background())
+	testData, err := os.ReadFile(testConfig.resultsOutput)
+	if err != nil || strings.Count(string(testData), "\n") != 4 || !strings.Contains(string(testData), `"status_code":404`) {
+		fmt.Println("The results were not written to the file")
+		fmt.Println(string(testData))
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for fileSink passed")
+	}
+}
+
+func TestHooksConfig1(t *testing.T) {
+	testCases := map[string]hooksConfig{
+		"invalid header X Team":                              {Headers: map[string]string{"X Team": "search"}},
+		"skip: error parsing regexp: missing closing ): `(`": {Skip: []string{"("}},
+		"invalid sink_url /results":                          {SinkURL: "/results"},
+	}
+	for testExpected, testConfig := range testCases {
+		if _, err := newHooksConfig(testConfig); err == nil || err.Error() != testExpected {
+			fmt.Println("newHooksConfig did not return " + testExpected)
+			fmt.Println(err)
+			t.Fail()
+			return
+		}
+	}
+	var testLock sync.Mutex
+	testHeaders := map[string]string{}
+	testExternal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testLock.Lock()
+		defer testLock.Unlock()
+		testHeaders["external"] = r.Header.Get("X-Team")
+	}))
+	defer testExternal.Close()
+	testExternalURI := strings.Replace(testExternal.URL, "127.0.0.1", "localhost", 1) + "/"
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testLock.Lock()
+		testHeaders[r.URL.Path] = r.Header.Get("X-Team")
+		testLock.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			_, _ = w.Write([]byte(`<a href="/a">A</a><a href="/logout">Out</a><a href="/b?sort=1">B</a><a href="` + testExternalURI + `">E</a>`))
+		} else {
+			_, _ = w.Write([]byte(`<p>old</p>`))
+		}
+	}))
+	defer testServer.Close()
+	var testSunk []string
+	testSink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var page pageResult
+		_ = json.NewDecoder(r.Body).Decode(&page)
+		testLock.Lock()
+		defer testLock.Unlock()
+		testSunk = append(testSunk, strings.TrimPrefix(page.URI, testServer.URL)+" "+fmt.Sprint(page.Skipped))
+	}))
+	defer testSink.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.linkCheck = linkCheckConfig{enabled: true, checkExternal: true}
+	testConfig.hooks, _ = newHooksConfig(hooksConfig{
+		Headers:      map[string]string{"X-Team": "search"},
+		Skip:         []string{"/logout$"},
+		ExcludeLinks: []string{`\?sort=`},
+		Replace:      []bodyReplacement{{Pattern: "<p>old</p>", Replacement: `<a href="/new">New</a>`}},
+		SinkURL:      testSink.URL,
+	})
+	testCrawler := newCrawler(testConfig)
+	testCrawler.output = io.Discard
+	testCrawler.config.threads = 1
+	testCrawler.Run(context.Background())
+	sort.Strings(testSunk)
+	if strings.Join(testSunk, ",") != " false,/a false,/logout true,/new false" {
+		fmt.Println("The hooks of the config were not applied")
+		fmt.Println(testSunk)
+		t.Fail()
+	} else if testHeaders["/"] != "search" || testHeaders["/new"] != "search" || testHeaders["external"] != "search" ||
+		testHeaders["/logout"] != "" {
+		fmt.Println("The request hooks were not applied to every request")
+		fmt.Println(testHeaders)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for hooksConfig passed")
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+/* linkCheckConfig holds the options for the broken link checker mode
+	enabled: Set from LINK_CHECK, records every failing URI with the pages that link to it
+	checkExternal: Set from CHECK_EXTERNAL, links to other hosts are checked as well but never crawled
+ */
+
+type linkCheckConfig struct {
+	enabled       bool
+	checkExternal bool
+}
+
+/* linkSource is a page that links to a uri
+	page: The uri of the page the link was found on
+	text: The anchor text of the link
+ */
+
+type linkSource struct {
+	page string
+	text string
+}
+
+/* linkReferrers holds all the sources that link to a single uri
+ */
+
+type linkReferrers struct {
+	lock    sync.Mutex
+	sources []linkSource
+}
+
+/* brokenLink holds the failure for a uri that could not be fetched
+	statusCode: The status code returned by the uri, 0 if the request failed
+	err: The error returned while fetching the uri if any
+ */
+
+type brokenLink struct {
+	statusCode int
+	err        string
+}
+
+/* linkCheckState holds the links and failures recorded by a Crawler in the broken link checker mode
+	linkSources: A syncMap from an absolute uri to the linkReferrers for the uri
+	brokenLinks: A syncMap from an absolute uri to the brokenLink failure for the uri
+	checkedExternal: A syncMap to keep a track of the external URIs that were already checked
+ */
+
+type linkCheckState struct {
+	linkSources     sync.Map
+	brokenLinks     sync.Map
+	checkedExternal sync.Map
+}
+
+/*  The function reads the LINK_CHECK and CHECK_EXTERNAL env variables
+	Invalid values are reported and the corresponding option is left disabled
+	Returns:
+		A linkCheckConfig with the options set by the user
+ */
+
+func getLinkCheckConfig() linkCheckConfig {
+	var config linkCheckConfig
+	if os.Getenv("LINK_CHECK") == "" {
+		return config
+	}
+	enabled, err := strconv.ParseBool(os.Getenv("LINK_CHECK"))
+	if err != nil {
+		logger.Warn("invalid value specified for LINK_CHECK env variable, broken links will not be reported",
+			"value", os.Getenv("LINK_CHECK"))
+		return config
+	}
+	config.enabled = enabled
+	if os.Getenv("CHECK_EXTERNAL") != "" {
+		checkExternal, err := strconv.ParseBool(os.Getenv("CHECK_EXTERNAL"))
+		if err != nil {
+			logger.Warn("invalid value specified for CHECK_EXTERNAL env variable, external links will not be checked",
+				"value", os.Getenv("CHECK_EXTERNAL"))
+		}
+		config.checkExternal = checkExternal
+	}
+	return config
+}
+
+/*  The function records the fetchResult of a uri as a broken link if the uri returned a 4xx or 5xx status
+	code or could not be fetched
+	Arguments:
+		result: The fetchResult of the uri
+ */
+
+func (c *Crawler) recordFailure(result fetchResult) {
+	if !c.config.linkCheck.enabled {
+		return
+	}
+	if result.err != nil {
+		c.brokenLinks.Store(result.uri, brokenLink{statusCode: result.statusCode, err: result.err.Error()})
+	} else if result.statusCode >= 400 {
+		c.brokenLinks.Store(result.uri, brokenLink{statusCode: result.statusCode})
+	}
+}
+
+/*  The function records the page as a source for every link found on it and checks the links to
+	other hosts if CHECK_EXTERNAL is set
+	Arguments:
+		pageURI: The uri of the page the anchors were found on
+		anchors: The anchors found on the page
+ */
+
+func (c *Crawler) recordLinks(pageURI string, anchors []anchor) {
+	if !c.config.linkCheck.enabled {
+		return
+	}
+	for _, a := range anchors {
+		absolute := absoluteURL(a.href, pageURI)
+		absoluteURL, err := url.Parse(absolute)
+		if err != nil || !c.linkScheme(absoluteURL.Scheme) {
+			continue
+		}
+		external := absoluteURL.Hostname() != c.config.hostBaseURL
+		if external && !c.config.linkCheck.checkExternal {
+			continue
+		}
+		referrers, _ := c.linkSources.LoadOrStore(absolute, &linkReferrers{})
+		referrers.(*linkReferrers).add(linkSource{page: pageURI, text: a.text})
+		if external {
+			if _, checked := c.checkedExternal.LoadOrStore(absolute, true); !checked {
+				c.checkExternalLink(absolute)
+			}
+		}
+	}
+}
+
+func (referrers *linkReferrers) add(source linkSource) {
+	referrers.lock.Lock()
+	defer referrers.lock.Unlock()
+	referrers.sources = append(referrers.sources, source)
+}
+
+/*  The function checks a link to another host with a HEAD request and falls back to a GET request
+	as some servers do not support HEAD. The link is recorded as broken if both requests fail. In a replayed
+	crawl the links that are not in the stored crawl are not checked
+	Arguments:
+		uri: The absolute uri of the external link
+ */
+
+func (c *Crawler) checkExternalLink(uri string) {
+	resp, err := c.client.Head(uri)
+	if errors.Is(err, errNotStored) {
+		logger.Info("external link not in the stored crawl, it is not checked", "uri", uri)
+		return
+	}
+	if errors.Is(err, errSkipURI) {
+		logger.Info("external link skipped by a request hook, it is not checked", "uri", uri)
+		return
+	}
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode < 400 {
+			return
+		}
+	}
+	resp, err = c.client.Get(uri)
+	if err != nil {
+		statusCode := 0
+		if resp != nil {
+			statusCode = resp.StatusCode
+		}
+		c.brokenLinks.Store(uri, brokenLink{statusCode: statusCode, err: err.Error()})
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		c.brokenLinks.Store(uri, brokenLink{statusCode: resp.StatusCode})
+	}
+}
+
+/*  The function prints every broken link with the pages and anchor texts that link to it
+	Returns:
+		An int with the number of broken links found
+ */
+
+func (c *Crawler) printBrokenLinks() int {
+	var uris []string
+	c.brokenLinks.Range(func(key, value interface{}) bool {
+		uris = append(uris, key.(string))
+		return true
+	})
+	sort.Strings(uris)
+	for _, uri := range uris {
+		value, _ := c.brokenLinks.Load(uri)
+		failure := value.(brokenLink)
+		reason := strconv.Itoa(failure.statusCode)
+		if failure.statusCode != 0 {
+			reason += " " + http.StatusText(failure.statusCode)
+		}
+		if failure.err != "" {
+			reason = failure.err
+		}
+		_, _ = fmt.Fprintln(c.output, "Broken link: "+uri+" ("+reason+")")
+		if referrers, ok := c.linkSources.Load(uri); ok {
+			for _, source := range referrers.(*linkReferrers).sources {
+				_, _ = fmt.Fprintln(c.output, "\tlinked from "+source.page+" with text \""+source.text+"\"")
+			}
+		}
+	}
+	_, _ = fmt.Fprintln(c.output, "Broken Links: "+strconv.Itoa(len(uris)))
+	return len(uris)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGetAllAnchorsHTML1(t *testing.T) {
+	testReader := strings.NewReader(`<p><a href="/one#top">First <b>link</b></a>
+		<a name="no-href">skip</a><a href="/two">
+			Second
+		</a></p>`)
+	testAnchors := getAllAnchorsHTML(testReader)
+	if len(testAnchors) != 2 {
+		fmt.Println("getAllAnchorsHTML returned the wrong number of anchors")
+		fmt.Println(testAnchors)
+		t.Fail()
+	} else if testAnchors[0].href != "/one" || testAnchors[0].text != "First link" || testAnchors[1].text != "Second" {
+		fmt.Println("getAllAnchorsHTML returned an invalid anchor")
+		fmt.Println(testAnchors)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for getAllAnchorsHTML passed")
+	}
+}
+
+func TestRecordLinks1(t *testing.T) {
+	testCrawler := newCrawler(defaultCrawlConfig("https://linkcheck.com"))
+	testCrawler.config.linkCheck = linkCheckConfig{enabled: true}
+	testAnchors := []anchor{{href: "/missing", text: "Missing page"}, {href: "https://other.com/", text: "Other"}}
+	testCrawler.recordLinks("https://linkcheck.com/page", testAnchors)
+	testCrawler.recordFailure(fetchResult{uri: "https://linkcheck.com/missing", statusCode: 404})
+	testCrawler.recordFailure(fetchResult{uri: "https://linkcheck.com/page", statusCode: 200})
+	testReferrers, ok := testCrawler.linkSources.Load("https://linkcheck.com/missing")
+	_, testExternal := testCrawler.linkSources.Load("https://other.com/")
+	_, testBroken := testCrawler.brokenLinks.Load("https://linkcheck.com/missing")
+	_, testNotBroken := testCrawler.brokenLinks.Load("https://linkcheck.com/page")
+	if !ok || testReferrers.(*linkReferrers).sources[0] != (linkSource{page: "https://linkcheck.com/page", text: "Missing page"}) {
+		fmt.Println("recordLinks did not record the source of the link")
+		t.Fail()
+	} else if testExternal {
+		fmt.Println("recordLinks recorded an external link without CHECK_EXTERNAL")
+		t.Fail()
+	} else if !testBroken || testNotBroken {
+		fmt.Println("recordFailure recorded an invalid failure")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for recordLinks passed")
+	}
+}
+
+func TestCheckExternalLink1(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		} else if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer testServer.Close()
+	testCrawler := newCrawler(defaultCrawlConfig("https://linkcheck.com"))
+	testCrawler.checkExternalLink(testServer.URL + "/ok")
+	testCrawler.checkExternalLink(testServer.URL + "/gone")
+	_, testOk := testCrawler.brokenLinks.Load(testServer.URL + "/ok")
+	testGone, testBroken := testCrawler.brokenLinks.Load(testServer.URL + "/gone")
+	if testOk {
+		fmt.Println("checkExternalLink did not fall back to a GET request")
+		t.Fail()
+	} else if !testBroken || testGone.(brokenLink).statusCode != http.StatusGone {
+		fmt.Println("checkExternalLink did not record the broken link")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for checkExternalLink passed")
+	}
+}
+
+func TestGetLinkCheckConfig1(t *testing.T) {
+	_ = os.Setenv("LINK_CHECK", "true")
+	_ = os.Setenv("CHECK_EXTERNAL", "true")
+	testConfig := getLinkCheckConfig()
+	if !testConfig.enabled || !testConfig.checkExternal {
+		fmt.Println("getLinkCheckConfig returned an invalid value")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for getLinkCheckConfig passed")
+	}
+	_ = os.Setenv("LINK_CHECK", "")
+	_ = os.Setenv("CHECK_EXTERNAL", "")
+}
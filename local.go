@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+//The host of the file URIs of a local site, file://localhost/ is the root directory of the site
+const localSiteHost = "localhost"
+
+/* siteDir serves the files of a local site like a static web server. A directory without an index.html
+	file is not found instead of being listed
+ */
+
+type siteDir struct {
+	http.Dir
+}
+
+/*  The function checks if the seed of the crawl is a file URI or the path of a local directory or file
+	The directory of the seed becomes the root of the site so that the links relative to the root of the site
+	are resolved in it. If a file URI does not exist an error message is generated and the program exits.
+	Arguments:
+		seed: The uri or path provided by the user
+	Returns:
+		A string with the file://localhost/ uri of the seed in the site
+		A string with the absolute path of the root directory of the site
+		A false value if the seed is not local and is crawled over http
+ */
+
+func getLocalSeed(seed string) (string, string, bool) {
+	seedPath := seed
+	fileURI := strings.HasPrefix(seed, "file://")
+	if fileURI {
+		seedURL, err := url.Parse(seed)
+		if err != nil || (seedURL.Host != "" && seedURL.Host != localSiteHost) {
+			logger.Error("invalid file URI provided, use file:///<absolute path>", "uri", seed)
+			os.Exit(1)
+		}
+		seedPath = filepath.FromSlash(seedURL.Path)
+	}
+	info, err := os.Stat(seedPath)
+	if err != nil {
+		if fileURI {
+			logger.Error("the file of the URI does not exist", "uri", seed, "error", err)
+			os.Exit(1)
+		}
+		return "", "", false
+	}
+	root, err := filepath.Abs(seedPath)
+	if err != nil {
+		logger.Error("invalid path provided", "path", seedPath, "error", err)
+		os.Exit(1)
+	}
+	page := "/"
+	if !info.IsDir() {
+		page += filepath.Base(root)
+		root = filepath.Dir(root)
+	}
+	return (&url.URL{Scheme: "file", Host: localSiteHost, Path: page}).String(), root, true
+}
+
+/*  The function returns a transport serving the file URIs from the root directory of a local site with the
+	status codes, redirects and content types of a static web server, the other URIs are sent over the network
+	Arguments:
+		siteRoot: The root directory of the site
+	Returns:
+		A pointer to an http.Transport
+ */
+
+func newSiteTransport(siteRoot string) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.RegisterProtocol("file", http.NewFileTransport(siteDir{http.Dir(siteRoot)}))
+	return transport
+}
+
+/*  The function opens a file of the site
+	Arguments:
+		name: The path of the file in the site
+	Returns:
+		The http.File
+		An error if the file does not exist or is a directory without an index.html file
+ */
+
+func (dir siteDir) Open(name string) (http.File, error) {
+	file, err := dir.Dir.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := file.Stat(); err == nil && info.IsDir() {
+		index, err := dir.Dir.Open(path.Join(name, "index.html"))
+		if err != nil {
+			file.Close()
+			return nil, os.ErrNotExist
+		}
+		index.Close()
+	}
+	return file, nil
+}
+
+/*  The function checks if the links with a scheme are recorded by the link check and the link graph. The file
+	URIs are only recorded in the crawl of a local site
+	Arguments:
+		scheme: The scheme of the link
+	Returns:
+		A bool, true for http, https and the file scheme of a local site
+ */
+
+func (c *Crawler) linkScheme(scheme string) bool {
+	return scheme == "http" || scheme == "https" || (scheme == "file" && c.config.siteRoot != "")
+}
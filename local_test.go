@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func newLocalTestSite(t *testing.T) string {
+	testRoot := t.TempDir()
+	testFiles := map[string]string{
+		"index.html":      `<a href="/blog/">Blog</a><a href="about.html">About</a><a href="/drafts/">Drafts</a><a href="mailto:a@test.com">Mail</a><a href="https://test.com/">Site</a>`,
+		"about.html":      `<p>About</p>`,
+		"blog/index.html": `<a href="../index.html">Home</a><a href="/blog/post.html">Post</a>`,
+		"blog/post.html":  `<a href="/missing.html">Missing</a>`,
+		"drafts/note.txt": `draft`,
+	}
+	for name, content := range testFiles {
+		_ = os.MkdirAll(filepath.Join(testRoot, filepath.Dir(name)), 0755)
+		_ = os.WriteFile(filepath.Join(testRoot, name), []byte(content), 0644)
+	}
+	return testRoot
+}
+
+func TestGetLocalSeed1(t *testing.T) {
+	testRoot := newLocalTestSite(t)
+	testDirURI, testDirRoot, testDirLocal := getLocalSeed(testRoot)
+	testFileURI, testFileRoot, testFileLocal := getLocalSeed("file://" + filepath.ToSlash(filepath.Join(testRoot, "blog", "post.html")))
+	_, _, testRemote := getLocalSeed("test.com")
+	if !testDirLocal || testDirURI != "file://localhost/" || testDirRoot != testRoot {
+		fmt.Println("getLocalSeed returned an invalid seed for a directory")
+		fmt.Println(testDirURI, testDirRoot)
+		t.Fail()
+	} else if !testFileLocal || testFileURI != "file://localhost/post.html" || testFileRoot != filepath.Join(testRoot, "blog") {
+		fmt.Println("getLocalSeed returned an invalid seed for a file URI")
+		fmt.Println(testFileURI, testFileRoot)
+		t.Fail()
+	} else if testRemote {
+		fmt.Println("getLocalSeed returned a local seed for a host")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for getLocalSeed passed")
+	}
+}
+
+func TestLocalSite1(t *testing.T) {
+	testRoot := newLocalTestSite(t)
+	testConfig := defaultCrawlConfig("file://localhost/")
+	testConfig.siteRoot = testRoot
+	testConfig.linkCheck = linkCheckConfig{enabled: true}
+	testConfig.graph = linkGraphConfig{enabled: true}
+	testCrawler := newCrawler(testConfig)
+	var testOutput bytes.Buffer
+	testCrawler.output = &testOutput
+	var testVisited []string
+	testCrawler.onPage = func(page pageResult) {
+		testVisited = append(testVisited, strings.TrimPrefix(page.URI, "file://localhost")+" "+fmt.Sprint(page.StatusCode))
+	}
+	testCrawler.config.threads = 1
+	testCrawler.Run(context.Background())
+	sort.Strings(testVisited)
+	testBroken := testCrawler.printBrokenLinks()
+	testGraph := testCrawler.linkGraph()
+	testExpected := "/ 200,/about.html 200,/blog/ 200,/blog/post.html 200,/drafts/ 404,/index.html 200,/missing.html 404"
+	if strings.Join(testVisited, ",") != testExpected {
+		fmt.Println("The local site was not crawled from the disk")
+		fmt.Println(testVisited)
+		t.Fail()
+	} else if testBroken != 2 || !strings.Contains(testOutput.String(), "file://localhost/missing.html") ||
+		!strings.Contains(testOutput.String(), "\tlinked from file://localhost/blog/post.html with text \"Missing\"\n") ||
+		!strings.Contains(testOutput.String(), "\tlinked from file://localhost/ with text \"Drafts\"\n") {
+		fmt.Println("The broken links of the local site were not reported")
+		fmt.Println(testOutput.String())
+		t.Fail()
+	} else if len(testGraph.edges) == 0 || testGraph.nodes[testGraph.index["file://localhost/blog/post.html"]].Inbound != 1 {
+		fmt.Println("The link graph of the local site was not recorded")
+		fmt.Println(testGraph.nodes, testGraph.edges)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for the local site passed")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+//The logger used for all the diagnostics. Results are printed on stdout and diagnostics are logged on stderr
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+/*  The function creates a leveled logger that writes text or JSON records
+	Arguments:
+		w: The io.Writer the records are written to
+		format: A string with the format of the records, text or json
+		level: A string with the minimum level logged, debug, info, warn or error
+	Returns:
+		A pointer to an slog.Logger
+		An error if the format or the level is invalid
+ */
+
+func newLogger(w io.Writer, format string, level string) (*slog.Logger, error) {
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	options := &slog.HandlerOptions{Level: logLevel}
+	switch strings.ToLower(format) {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q", format)
+}
+
+/*  The function reads the LOG_LEVEL and LOG_FORMAT env variables and returns a logger writing to stderr
+	If a value is invalid an error message is generated and the program exits.
+	Defaults to info and text
+	Returns:
+		A pointer to an slog.Logger
+ */
+
+func getLogger() *slog.Logger {
+	level := os.Getenv("LOG_LEVEL")
+	if level == "" {
+		level = "info"
+	}
+	format := os.Getenv("LOG_FORMAT")
+	if format == "" {
+		format = "text"
+	}
+	configured, err := newLogger(os.Stderr, format, level)
+	if err != nil {
+		logger.Error("invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+	return configured
+}
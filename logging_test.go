@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger1(t *testing.T) {
+	var testOutput bytes.Buffer
+	testLogger, err := newLogger(&testOutput, "json", "warn")
+	if err != nil {
+		fmt.Println("newLogger returned an error for a valid configuration")
+		fmt.Println(err)
+		t.Fail()
+		return
+	}
+	testLogger.Info("not logged")
+	testLogger.Warn("error while fetching response", "uri", "https://example.com/")
+	var testRecord map[string]interface{}
+	if err := json.Unmarshal(testOutput.Bytes(), &testRecord); err != nil || testRecord["level"] != "WARN" ||
+		testRecord["uri"] != "https://example.com/" {
+		fmt.Println("newLogger did not log a valid JSON record")
+		fmt.Println(testOutput.String())
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for newLogger passed")
+	}
+}
+
+func TestNewLogger2(t *testing.T) {
+	var testOutput bytes.Buffer
+	testLogger, err := newLogger(&testOutput, "text", "debug")
+	if err != nil {
+		t.Fail()
+		return
+	}
+	testLogger.Debug("redirect out of scope", "uri", "https://example.com/")
+	if !strings.Contains(testOutput.String(), "level=DEBUG") || !strings.Contains(testOutput.String(), "uri=https://example.com/") {
+		fmt.Println("newLogger did not log a valid text record")
+		fmt.Println(testOutput.String())
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for newLogger passed")
+	}
+}
+
+func TestNewLogger3(t *testing.T) {
+	_, levelErr := newLogger(&bytes.Buffer{}, "text", "verbose")
+	_, formatErr := newLogger(&bytes.Buffer{}, "xml", "info")
+	if levelErr == nil || formatErr == nil {
+		fmt.Println("newLogger accepted an invalid configuration")
+		t.Fail()
+	} else {
+		fmt.Println("Test 3 for newLogger passed")
+	}
+}
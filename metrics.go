@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	fetchesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "crawler_fetches_total",
+		Help: "Number of URIs fetched by status class and host.",
+	}, []string{"status_class", "host"})
+	fetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "crawler_fetch_duration_seconds",
+		Help:    "Time taken to fetch a URI and read its body.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"host"})
+	bytesDownloaded = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "crawler_bytes_downloaded_total",
+		Help: "Number of response bytes received over the network.",
+	})
+	activeWorkers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "crawler_active_workers",
+		Help: "Number of threads currently processing a URI.",
+	})
+	workerCount = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "crawler_workers",
+		Help: "Number of threads started by the crawler.",
+	})
+	frontierSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "crawler_frontier_size",
+		Help: "Number of URIs inserted into the queue that have not been processed yet.",
+	})
+)
+
+/*  The function starts an http listener that exposes the Prometheus metrics on /metrics if the
+	METRICS_ADDR env variable is set to an address like :9090
+	If the listener fails an error message is generated and the program exits.
+ */
+
+func startMetricsServer() {
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		if err := http.ListenAndServe(metricsAddr, mux); err != nil {
+			logger.Error("error while serving metrics", "address", metricsAddr, "error", err)
+			os.Exit(1)
+		}
+	}()
+}
+
+/*  The function adds the fetchResult of a uri to the Prometheus metrics
+	Arguments:
+		result: The fetchResult of the uri
+ */
+
+func observeFetch(result fetchResult) {
+	host := ""
+	if fetchedURL, err := url.Parse(result.uri); err == nil {
+		host = fetchedURL.Hostname()
+	}
+	fetchesTotal.WithLabelValues(statusClass(result), host).Inc()
+	fetchDuration.WithLabelValues(host).Observe(result.duration.Seconds())
+	bytesDownloaded.Add(float64(result.wireSize))
+}
+
+/*  The function returns the status class of a fetchResult used as a metric label
+	Arguments:
+		result: The fetchResult of the uri
+	Returns:
+		A string like 2xx or error if the uri could not be fetched
+ */
+
+func statusClass(result fetchResult) string {
+	if result.err != nil || result.statusCode == 0 {
+		return "error"
+	}
+	return strconv.Itoa(result.statusCode/100) + "xx"
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestStatusClass1(t *testing.T) {
+	if statusClass(fetchResult{statusCode: 204}) != "2xx" || statusClass(fetchResult{statusCode: 503}) != "5xx" ||
+		statusClass(fetchResult{err: errors.New("timeout")}) != "error" {
+		fmt.Println("statusClass returned an invalid value")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for statusClass passed")
+	}
+}
+
+func TestObserveFetch1(t *testing.T) {
+	presentBytes := testutil.ToFloat64(bytesDownloaded)
+	observeFetch(fetchResult{uri: "https://metrics.com/a", statusCode: 404, wireSize: 100, duration: time.Second})
+	observeFetch(fetchResult{uri: "https://metrics.com/b", statusCode: 404, wireSize: 50, duration: time.Second})
+	if testutil.ToFloat64(fetchesTotal.WithLabelValues("4xx", "metrics.com")) != 2 {
+		fmt.Println("observeFetch did not count the fetches by status class and host")
+		t.Fail()
+	} else if testutil.ToFloat64(bytesDownloaded)-presentBytes != 150 {
+		fmt.Println("observeFetch did not count the bytes downloaded")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for observeFetch passed")
+	}
+}
+
+func TestMetricsHandler1(t *testing.T) {
+	testServer := httptest.NewServer(promhttp.Handler())
+	defer testServer.Close()
+	resp, err := http.Get(testServer.URL)
+	if err != nil {
+		fmt.Println("Error while fetching the metrics")
+		t.FailNow()
+	}
+	defer resp.Body.Close()
+	testBody, _ := ioutil.ReadAll(resp.Body)
+	for _, name := range []string{"crawler_frontier_size", "crawler_active_workers", "crawler_bytes_downloaded_total"} {
+		if !strings.Contains(string(testBody), name) {
+			fmt.Println("The metrics endpoint did not expose " + name)
+			t.Fail()
+		}
+	}
+	if !t.Failed() {
+		fmt.Println("Test 1 for the metrics handler passed")
+	}
+}
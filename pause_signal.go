@@ -0,0 +1,38 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+/*  The function pauses or resumes the crawl every time the process receives SIGUSR1 until the returned
+	function is called
+	Arguments:
+		ctx: The context of the crawl
+	Returns:
+		A function that stops handling the signal
+ */
+
+func (c *Crawler) handlePauseSignal(ctx context.Context) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	stop := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-signals:
+				c.togglePause(ctx)
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(stop)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandlePauseSignal1(t *testing.T) {
+	testCrawler := newCrawler(defaultCrawlConfig("https://test.com"))
+	stop := testCrawler.handlePauseSignal(context.Background())
+	defer stop()
+	_ = syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	for i := 0; i < 100 && !testCrawler.Paused(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !testCrawler.Paused() {
+		fmt.Println("SIGUSR1 did not pause the crawl")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for handlePauseSignal passed")
+	}
+}
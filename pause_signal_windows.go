@@ -0,0 +1,14 @@
+package main
+
+import "context"
+
+/*  The function does nothing as SIGUSR1 is not available on windows
+	Arguments:
+		ctx: The context of the crawl
+	Returns:
+		A function that does nothing
+ */
+
+func (c *Crawler) handlePauseSignal(ctx context.Context) func() {
+	return func() {}
+}
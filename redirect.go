@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/* redirectHop is a single redirect response that was followed while fetching a uri
+	uri: The uri that returned the redirect
+	statusCode: The 3xx status code returned by the uri
+ */
+
+type redirectHop struct {
+	uri        string
+	statusCode int
+}
+
+const defaultMaxRedirects = 10
+
+/* redirectState holds the redirects followed by a Crawler
+	redirectCounter: A counter to keep a track of the number of URIs that were redirected
+	redirectChains: A syncMap from a uri to the redirect hops followed while fetching it, the last hop is the
+		final uri with the status code it returned
+ */
+
+type redirectState struct {
+	redirectCounter int64
+	redirectChains  sync.Map
+}
+
+var errRedirectLoop = errors.New("redirect loop detected")
+
+/*  The function checks the value of the env variable MAX_REDIRECTS if the value specified in the env variable
+	is invalid an error message is generated and the program exits.
+	Defaults to 10
+	Returns:
+		An int with the maximum number of redirects to follow for a single uri
+ */
+
+func getMaxRedirects() int {
+	if os.Getenv("MAX_REDIRECTS") == "" {
+		return defaultMaxRedirects
+	}
+	maxRedirects, err := strconv.Atoi(os.Getenv("MAX_REDIRECTS"))
+	if err != nil || maxRedirects < 0 {
+		logger.Error("invalid value for MAX_REDIRECTS env variable", "value", os.Getenv("MAX_REDIRECTS"))
+		os.Exit(1)
+	}
+	return maxRedirects
+}
+
+/*  The function creates the httpClient used to fetch all the URIs with a request timeout of 30 seconds.
+	The client stops with an error when more than maxRedirects redirects are followed for a uri or
+	when a redirect points back to a uri that was already visited in the same chain
+	Arguments:
+		maxRedirects: An int with the maximum number of redirects to follow
+	Returns:
+		A pointer to an http.Client
+ */
+
+func newHTTPClient(maxRedirects int) *http.Client {
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			for _, previous := range via {
+				if previous.URL.String() == req.URL.String() {
+					return errRedirectLoop
+				}
+			}
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		},
+	}
+}
+
+/*  The function walks back from the final response through the redirect responses that led to it
+	Arguments:
+		resp: The final http.Response returned by the client
+	Returns:
+		A slice of redirectHops in the order they were followed
+ */
+
+func redirectChain(resp *http.Response) []redirectHop {
+	var hops []redirectHop
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		hops = append([]redirectHop{{uri: req.Response.Request.URL.String(), statusCode: req.Response.StatusCode}}, hops...)
+	}
+	return hops
+}
+
+/*  The function records the redirect chain of a fetched uri and checks if the uri it was redirected to
+	should be parsed. A redirect to another host is out of scope and a redirect to a uri that was already
+	inserted is skipped as that uri is crawled on its own
+	Arguments:
+		result: The fetchResult of the uri
+	Returns:
+		A true value if the links on the final page should be extracted
+ */
+
+func (c *Crawler) followRedirect(result fetchResult) bool {
+	if len(result.redirects) == 0 {
+		return true
+	}
+	c.redirectChains.Store(result.uri, append(append([]redirectHop(nil), result.redirects...),
+		redirectHop{uri: result.finalURI, statusCode: result.statusCode}))
+	atomic.AddInt64(&c.redirectCounter, 1)
+	if c.config.uriOutput {
+		chain := ""
+		for _, hop := range result.redirects {
+			chain += hop.uri + " (" + strconv.Itoa(hop.statusCode) + ") -> "
+		}
+		_, _ = fmt.Fprintln(c.output, "Redirected: "+chain+result.finalURI)
+	}
+	if result.err != nil {
+		return false
+	}
+	finalURL, err := url.Parse(result.finalURI)
+	if err != nil || finalURL.Hostname() != c.config.hostBaseURL {
+		logger.Info("redirect out of scope", "uri", result.uri, "final_uri", result.finalURI)
+		return false
+	}
+	_, _ = c.inserted.LoadOrStore(result.finalURI+"/", true)
+	_, loaded := c.inserted.LoadOrStore(result.finalURI, true)
+	return !loaded
+}
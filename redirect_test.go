@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func newRedirectTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/middle", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/middle", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/end", http.StatusFound)
+	})
+	mux.HandleFunc("/end", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<a href="next">next</a>`))
+	})
+	mux.HandleFunc("/loop1", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop2", http.StatusFound)
+	})
+	mux.HandleFunc("/loop2", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop1", http.StatusFound)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestFetchPageRedirects1(t *testing.T) {
+	testServer := newRedirectTestServer()
+	defer testServer.Close()
+	testCrawler := newCrawler(defaultCrawlConfig(testServer.URL))
+	testResult := testCrawler.fetchPage(context.Background(), testServer.URL+"/start")
+	if testResult.finalURI != testServer.URL+"/end" || testResult.statusCode != 200 {
+		fmt.Println("fetchPage did not follow the redirects to the final uri " + testResult.finalURI)
+		t.Fail()
+	} else if len(testResult.redirects) != 2 || testResult.redirects[0].statusCode != 301 ||
+		testResult.redirects[1].uri != testServer.URL+"/middle" {
+		fmt.Println("fetchPage recorded an invalid redirect chain")
+		fmt.Println(testResult.redirects)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for fetchPage redirects passed")
+	}
+}
+
+func TestFetchPageRedirects2(t *testing.T) {
+	testServer := newRedirectTestServer()
+	defer testServer.Close()
+	testCrawler := newCrawler(defaultCrawlConfig(testServer.URL))
+	testResult := testCrawler.fetchPage(context.Background(), testServer.URL+"/loop1")
+	if testResult.err == nil {
+		fmt.Println("fetchPage did not detect the redirect loop")
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for fetchPage redirects passed")
+	}
+}
+
+func TestNewHTTPClient1(t *testing.T) {
+	testServer := newRedirectTestServer()
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.maxRedirects = 1
+	testResult := newCrawler(testConfig).fetchPage(context.Background(), testServer.URL+"/start")
+	if testResult.err == nil || testResult.statusCode != http.StatusFound {
+		fmt.Println("The client followed more redirects than allowed")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for newHTTPClient passed")
+	}
+}
+
+func TestFollowRedirect1(t *testing.T) {
+	testResult := fetchResult{
+		uri:        "https://test.com/old",
+		finalURI:   "https://other.com/new",
+		statusCode: 200,
+		redirects:  []redirectHop{{uri: "https://test.com/old", statusCode: 301}},
+	}
+	if newCrawler(defaultCrawlConfig("https://test.com")).followRedirect(testResult) {
+		fmt.Println("followRedirect allowed a redirect to another host")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for followRedirect passed")
+	}
+}
+
+func TestFollowRedirect2(t *testing.T) {
+	testResult := fetchResult{
+		uri:        "https://test.com/redirect2",
+		finalURI:   "https://test.com/redirect2/final",
+		statusCode: 200,
+		redirects:  []redirectHop{{uri: "https://test.com/redirect2", statusCode: 302}},
+	}
+	testCrawler := newCrawler(defaultCrawlConfig("https://test.com"))
+	if !testCrawler.followRedirect(testResult) {
+		fmt.Println("followRedirect skipped a redirect that was not crawled before")
+		t.Fail()
+	} else if testCrawler.followRedirect(testResult) {
+		fmt.Println("followRedirect allowed a final uri that was already crawled")
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for followRedirect passed")
+	}
+}
+
+func TestGetMaxRedirects1(t *testing.T) {
+	_ = os.Setenv("MAX_REDIRECTS", "3")
+	testResult := getMaxRedirects()
+	_ = os.Setenv("MAX_REDIRECTS", "")
+	if testResult != 3 || getMaxRedirects() != defaultMaxRedirects {
+		fmt.Println("getMaxRedirects returned an invalid value")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for getMaxRedirects passed")
+	}
+}
@@ -0,0 +1,757 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+/* renderer renders a page in a browser so that the links and the content added by its scripts are seen by
+	the crawler
+	render: Returns the html of the document once the page has loaded
+	close: Releases the browser of the renderer at the end of the crawl
+ */
+
+type renderer interface {
+	render(ctx context.Context, request renderRequest) (string, error)
+	close()
+}
+
+/* renderRequest is a page to render with the headers and cookies the crawl sends to its host
+	uri: The uri of the page
+	headers: The auth and session headers of the host of the page
+	cookies: The cookies of the cookie jar of the crawl for the uri
+ */
+
+type renderRequest struct {
+	uri     string
+	headers map[string]string
+	cookies []browserCookie
+}
+
+/* renderRule maps the URIs matching a pattern to the renderer they are rendered with
+ */
+
+type renderRule struct {
+	pattern  *regexp.Regexp
+	renderer renderer
+}
+
+/* renderConfig holds the options of the rendering of the pages
+	enabled: Set when RENDER_PATTERNS is set and a browser is available, renders the matching html pages
+	rules: The renderer of the URIs matching every pattern, the first matching rule is used
+ */
+
+type renderConfig struct {
+	enabled bool
+	rules   []renderRule
+}
+
+/* renderState holds the counter of the pages rendered by a Crawler
+ */
+
+type renderState struct {
+	renderedCounter int64
+}
+
+/* cdpRenderer renders the pages in a headless Chrome or Chromium driven over the Chrome DevTools Protocol
+	Every page is loaded in a new tab so that the threads of the crawl render their pages at the same time
+	endpoint: The DevTools HTTP endpoint of the browser, e.g. http://127.0.0.1:9222
+	browser: The executable started on the first render when endpoint is not set
+	wait: The time waited after the load event for the requests of the scripts to finish
+	noSandbox: Set when RENDER_NO_SANDBOX is true, starts the browser without its sandbox
+ */
+
+type cdpRenderer struct {
+	endpoint  string
+	browser   string
+	wait      time.Duration
+	noSandbox bool
+	start     sync.Once
+	startErr  error
+	cmd       *exec.Cmd
+	dataDir   string
+}
+
+/* fakeRenderer renders the pages from a map of uri to html without a browser, used in the tests of the
+	rendering
+	requests: The renderRequests received
+ */
+
+type fakeRenderer struct {
+	pages    map[string]string
+	lock     sync.Mutex
+	requests []renderRequest
+}
+
+/* headerRecorder records the headers of a request instead of sending it
+ */
+
+type headerRecorder struct {
+	header http.Header
+}
+
+/* cdpSession is the websocket connection to a tab of the browser
+	events: The events received since the last reset
+	messages: The messages read from the connection, closed with err when the connection fails
+	page: The url of the rendered page, the headers are only added to the requests of its scheme and host
+	headers: The headers added to the requests of the page, the Fetch domain is enabled when set
+ */
+
+type cdpSession struct {
+	ctx      context.Context
+	conn     *websocket.Conn
+	nextID   int
+	events   map[string]bool
+	messages chan cdpMessage
+	done     chan struct{}
+	err      error
+	page     *url.URL
+	headers  map[string]string
+}
+
+/* cdpPausedRequest holds the parameters of the Fetch.requestPaused event
+ */
+
+type cdpPausedRequest struct {
+	RequestID string `json:"requestId"`
+	Request   struct {
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+	} `json:"request"`
+}
+
+/* cdpMessage is a command sent to the browser, the response to a command or an event
+ */
+
+type cdpMessage struct {
+	ID     int             `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+//The error returned when RENDER_PATTERNS is set but no browser can be found or reached
+var errNoBrowser = errors.New("no browser found, set RENDER_BROWSER or RENDER_CDP_URL")
+
+//The names of the Chrome and Chromium executables looked up in the PATH
+var browserNames = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome", "headless-shell"}
+
+//The origin of the DevTools websocket connections, the browser started by a renderer only accepts this origin
+//so that the scripts of the crawled pages can not connect to its debugging port
+const devtoolsOrigin = "http://127.0.0.1"
+
+//The maximum time taken to start the browser or to render a single page
+const renderTimeout = 30 * time.Second
+
+//The default time waited after the load event of a page
+const defaultRenderWait = 500 * time.Millisecond
+
+/*  The function checks the values of the RENDER_PATTERNS and RENDER_WAIT env variables. RENDER_PATTERNS is a
+	comma separated list of regexps of the URIs rendered in a browser. The pages are not rendered if no browser
+	is found, if a pattern is invalid an error message is generated and the program exits.
+	Returns:
+		A renderConfig with the options set by the user
+ */
+
+func getRenderConfig() renderConfig {
+	if os.Getenv("RENDER_PATTERNS") == "" {
+		return renderConfig{}
+	}
+	wait := defaultRenderWait
+	if value := os.Getenv("RENDER_WAIT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			logger.Warn("invalid value specified for RENDER_WAIT env variable, the default value is used",
+				"value", value, "default", defaultRenderWait)
+		} else {
+			wait = parsed
+		}
+	}
+	config, err := newRenderConfig(strings.Split(os.Getenv("RENDER_PATTERNS"), ","), wait)
+	if errors.Is(err, errNoBrowser) {
+		logger.Warn("the pages are not rendered", "error", err)
+		return renderConfig{}
+	}
+	if err != nil {
+		logger.Error("invalid value for RENDER_PATTERNS env variable", "value", os.Getenv("RENDER_PATTERNS"), "error", err)
+		os.Exit(1)
+	}
+	return config
+}
+
+/*  The function compiles the patterns of the rendered URIs and finds the browser they are rendered with. The
+	browser running at the RENDER_CDP_URL endpoint is used if set, otherwise the RENDER_BROWSER executable or
+	the first Chrome or Chromium executable in the PATH is started on the first render. The browser is only
+	started without its sandbox if RENDER_NO_SANDBOX is true
+	Arguments:
+		patterns: The regexps of the URIs to render
+		wait: The time waited after the load event of every page
+	Returns:
+		A renderConfig rendering the matching URIs with the browser
+		An error if a pattern is invalid or errNoBrowser if no browser is found
+ */
+
+func newRenderConfig(patterns []string, wait time.Duration) (renderConfig, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		expression, err := regexp.Compile(pattern)
+		if err != nil {
+			return renderConfig{}, err
+		}
+		compiled = append(compiled, expression)
+	}
+	if len(compiled) == 0 {
+		return renderConfig{}, errors.New("no render patterns")
+	}
+	browser := &cdpRenderer{endpoint: strings.TrimRight(strings.TrimSpace(os.Getenv("RENDER_CDP_URL")), "/"), wait: wait}
+	if browser.endpoint == "" {
+		browser.browser = findBrowser(os.Getenv("RENDER_BROWSER"))
+		if browser.browser == "" {
+			return renderConfig{}, errNoBrowser
+		}
+	}
+	if value := os.Getenv("RENDER_NO_SANDBOX"); value != "" {
+		noSandbox, err := strconv.ParseBool(value)
+		if err != nil {
+			logger.Warn("invalid value specified for RENDER_NO_SANDBOX env variable, the browser is started with its sandbox",
+				"value", value)
+		}
+		browser.noSandbox = noSandbox
+	}
+	config := renderConfig{enabled: true}
+	for _, pattern := range compiled {
+		config.rules = append(config.rules, renderRule{pattern: pattern, renderer: browser})
+	}
+	return config, nil
+}
+
+/*  The function finds the executable of the browser
+	Arguments:
+		path: The executable set by the user, the PATH is searched if empty
+	Returns:
+		A string with the path of the executable, empty if it is not found
+ */
+
+func findBrowser(path string) string {
+	if path != "" {
+		if found, err := exec.LookPath(path); err == nil {
+			return found
+		}
+		return ""
+	}
+	for _, name := range browserNames {
+		if found, err := exec.LookPath(name); err == nil {
+			return found
+		}
+	}
+	return ""
+}
+
+/*  The function returns the renderer of a uri
+	Arguments:
+		uri: The uri of the page
+	Returns:
+		The renderer of the first rule matching the uri, nil if the page is not rendered
+ */
+
+func (config renderConfig) rendererFor(uri string) renderer {
+	for _, rule := range config.rules {
+		if rule.pattern.MatchString(uri) {
+			return rule.renderer
+		}
+	}
+	return nil
+}
+
+/*  The function closes every renderer of the rules once
+ */
+
+func (config renderConfig) close() {
+	closed := map[renderer]bool{}
+	for _, rule := range config.rules {
+		if !closed[rule.renderer] {
+			closed[rule.renderer] = true
+			rule.renderer.close()
+		}
+	}
+}
+
+/*  The function replaces the body of an html page matching a render pattern with the html rendered by the
+	browser. The body of the response is kept if the page can not be rendered or is replayed from a stored crawl
+	Arguments:
+		ctx: The context of the crawl, the rendering is stopped if it is cancelled
+		result: The fetchResult of the page
+ */
+
+func (c *Crawler) renderPage(ctx context.Context, result *fetchResult) {
+	if !c.config.render.enabled || c.config.replay.path != "" || !isHTML(result.contentType) || result.statusCode >= 400 {
+		return
+	}
+	pageRenderer := c.config.render.rendererFor(result.finalURI)
+	if pageRenderer == nil {
+		return
+	}
+	body, err := pageRenderer.render(ctx, c.renderRequest(result.finalURI))
+	if err != nil {
+		logger.Warn("error while rendering the page, the html of the response is used", "uri", result.finalURI, "error", err)
+		return
+	}
+	result.body = body
+	result.rendered = true
+	atomic.AddInt64(&c.renderedCounter, 1)
+}
+
+/*  The function returns the page to render with the headers the request hooks and the auth and session
+	transports of the crawl add to the requests of its host and the cookies of the cookie jar, so that the browser is logged in like the
+	crawler
+	Arguments:
+		uri: The uri of the page
+	Returns:
+		A renderRequest for the page
+ */
+
+func (c *Crawler) renderRequest(uri string) renderRequest {
+	request := renderRequest{uri: uri, headers: map[string]string{}}
+	pageURL, err := url.Parse(uri)
+	if err != nil {
+		return request
+	}
+	recorder := &headerRecorder{}
+	transport := http.RoundTripper(recorder)
+	if len(c.config.auth.Hosts) > 0 {
+		transport = &authTransport{base: transport, hosts: c.config.auth.Hosts}
+	}
+	if len(c.config.session.Headers) > 0 {
+		transport = &headerTransport{base: transport, headers: c.config.session.Headers}
+	}
+	transport = &hookTransport{base: transport, hooks: &c.hooks}
+	_, _ = transport.RoundTrip(&http.Request{Method: http.MethodGet, URL: pageURL, Header: http.Header{}})
+	for name := range recorder.header {
+		request.headers[name] = recorder.header.Get(name)
+	}
+	if jar, ok := c.client.Jar.(*cookieJar); ok {
+		request.cookies = jar.browserCookies(pageURL)
+	}
+	return request
+}
+
+/*  The function records the headers of the request
+	Arguments:
+		req: The request
+	Returns:
+		An empty response
+ */
+
+func (r *headerRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.header = req.Header
+	return &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+}
+
+/*  The function returns the html of a page from the map of the fakeRenderer and records the request
+	Arguments:
+		ctx: Not used
+		request: The page to render
+	Returns:
+		A string with the html of the page
+		An error if the map does not hold the uri
+ */
+
+func (r *fakeRenderer) render(ctx context.Context, request renderRequest) (string, error) {
+	r.lock.Lock()
+	r.requests = append(r.requests, request)
+	r.lock.Unlock()
+	page, ok := r.pages[request.uri]
+	if !ok {
+		return "", errors.New("no rendered page for " + request.uri)
+	}
+	return page, nil
+}
+
+func (r *fakeRenderer) close() {}
+
+/*  The function loads a page in a new tab of the browser and returns the html of its document once the load
+	event has fired and the wait time has passed. The cookies of the request are set in the browser and its
+	headers are only added to the requests of the tab to the scheme and host of the page
+	Arguments:
+		ctx: A context to cancel the rendering
+		request: The page to render
+	Returns:
+		A string with the html of the document
+		An error if the browser could not be started or the page could not be loaded
+ */
+
+func (r *cdpRenderer) render(ctx context.Context, request renderRequest) (string, error) {
+	r.start.Do(r.launch)
+	if r.startErr != nil {
+		return "", r.startErr
+	}
+	ctx, cancel := context.WithTimeout(ctx, renderTimeout)
+	defer cancel()
+	var target struct {
+		ID                   string `json:"id"`
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err := r.devtools(ctx, http.MethodPut, "/json/new?about:blank", &target); err != nil {
+		return "", err
+	}
+	defer func() { _ = r.devtools(context.Background(), http.MethodGet, "/json/close/"+target.ID, nil) }()
+	conn, err := websocket.Dial(target.WebSocketDebuggerURL, "", devtoolsOrigin)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	session := &cdpSession{ctx: ctx, conn: conn, events: map[string]bool{}, messages: make(chan cdpMessage),
+		done: make(chan struct{}), headers: request.headers}
+	defer close(session.done)
+	go session.read()
+	if session.page, err = url.Parse(request.uri); err != nil {
+		return "", err
+	}
+	if err := session.call("Page.enable", nil, nil); err != nil {
+		return "", err
+	}
+	if err := session.setRequest(request); err != nil {
+		return "", err
+	}
+	var navigation struct {
+		ErrorText string `json:"errorText"`
+	}
+	session.events = map[string]bool{}
+	if err := session.call("Page.navigate", map[string]string{"url": request.uri}, &navigation); err != nil {
+		return "", err
+	}
+	if navigation.ErrorText != "" {
+		return "", errors.New("navigation failed: " + navigation.ErrorText)
+	}
+	if err := session.waitFor("Page.loadEventFired"); err != nil {
+		return "", err
+	}
+	if err := session.wait(r.wait); err != nil {
+		return "", err
+	}
+	var evaluation struct {
+		Result struct {
+			Value string `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text string `json:"text"`
+		} `json:"exceptionDetails"`
+	}
+	err = session.call("Runtime.evaluate", map[string]interface{}{
+		"expression": "document.documentElement.outerHTML", "returnByValue": true}, &evaluation)
+	if err == nil && evaluation.ExceptionDetails != nil {
+		err = errors.New("evaluation failed: " + evaluation.ExceptionDetails.Text)
+	}
+	return evaluation.Result.Value, err
+}
+
+/*  The function starts the browser with the DevTools Protocol listening on a free port and sets the endpoint
+	of the renderer from the address the browser prints. Nothing is started if the endpoint is set
+ */
+
+func (r *cdpRenderer) launch() {
+	if r.endpoint != "" {
+		return
+	}
+	r.dataDir, r.startErr = os.MkdirTemp("", "webcrawler-browser-")
+	if r.startErr != nil {
+		return
+	}
+	args := []string{"--headless=new", "--disable-gpu", "--no-first-run", "--no-default-browser-check",
+		"--remote-debugging-port=0", "--remote-allow-origins=" + devtoolsOrigin, "--user-data-dir=" + r.dataDir}
+	if r.noSandbox {
+		args = append(args, "--no-sandbox")
+	}
+	r.cmd = exec.Command(r.browser, append(args, "about:blank")...)
+	stderr, err := r.cmd.StderrPipe()
+	if err == nil {
+		err = r.cmd.Start()
+	}
+	if err != nil {
+		r.startErr = err
+		return
+	}
+	listening := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			if address, ok := strings.CutPrefix(scanner.Text(), "DevTools listening on "); ok {
+				listening <- address
+				break
+			}
+		}
+		_, _ = io.Copy(io.Discard, stderr)
+	}()
+	select {
+	case address := <-listening:
+		debuggerURL, err := url.Parse(strings.TrimSpace(address))
+		if err != nil {
+			r.startErr = err
+			return
+		}
+		r.endpoint = "http://" + debuggerURL.Host
+		logger.Info("browser started", "browser", r.browser, "endpoint", r.endpoint)
+	case <-time.After(renderTimeout):
+		r.startErr = errors.New("the browser did not start the DevTools Protocol in " + renderTimeout.String())
+	}
+}
+
+/*  The function sends a request to the DevTools HTTP endpoint of the browser
+	Arguments:
+		ctx: A context to cancel the request
+		method: The method of the request
+		path: The path of the request
+		result: A pointer the JSON response is decoded into, nil to discard the response
+	Returns:
+		An error if the request failed or the response is not valid JSON
+ */
+
+func (r *cdpRenderer) devtools(ctx context.Context, method string, path string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, r.endpoint+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s returned status code %d", method, path, resp.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+/*  The function stops the browser started by the renderer and removes its profile
+ */
+
+func (r *cdpRenderer) close() {
+	if r.cmd == nil || r.cmd.Process == nil {
+		return
+	}
+	_ = r.cmd.Process.Kill()
+	_ = r.cmd.Wait()
+	_ = os.RemoveAll(r.dataDir)
+}
+
+/*  The function reads the messages of the tab until the connection fails or the rendering is finished
+ */
+
+func (s *cdpSession) read() {
+	defer close(s.messages)
+	for {
+		var message cdpMessage
+		if err := websocket.JSON.Receive(s.conn, &message); err != nil {
+			s.err = err
+			return
+		}
+		select {
+		case s.messages <- message:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+/*  The function returns the next message of the tab. The events are recorded and the paused requests of the
+	page are continued
+	Arguments:
+		timeout: A channel that stops the wait, nil to wait until a message is received
+	Returns:
+		The message, nil if the timeout has passed
+		An error if the connection failed or the context is done
+ */
+
+func (s *cdpSession) next(timeout <-chan time.Time) (*cdpMessage, error) {
+	select {
+	case message, ok := <-s.messages:
+		if !ok {
+			return nil, s.err
+		}
+		if message.ID == 0 {
+			s.events[message.Method] = true
+			if message.Method == "Fetch.requestPaused" {
+				return &message, s.continueRequest(message.Params)
+			}
+		}
+		return &message, nil
+	case <-timeout:
+		return nil, nil
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+/*  The function sends a command to the tab without waiting for its response
+	Arguments:
+		method: The name of the command
+		params: The parameters of the command, nil for none
+	Returns:
+		The id of the command
+		An error if the command could not be sent
+ */
+
+func (s *cdpSession) send(method string, params interface{}) (int, error) {
+	s.nextID++
+	message := cdpMessage{ID: s.nextID, Method: method}
+	if params != nil {
+		encoded, err := json.Marshal(params)
+		if err != nil {
+			return 0, err
+		}
+		message.Params = encoded
+	}
+	return s.nextID, websocket.JSON.Send(s.conn, message)
+}
+
+/*  The function sends a command to the tab and waits for its response. The events received in the meantime
+	are recorded
+	Arguments:
+		method: The name of the command
+		params: The parameters of the command, nil for none
+		result: A pointer the result of the command is decoded into, nil to discard the result
+	Returns:
+		An error if the connection failed or the browser returned an error
+ */
+
+func (s *cdpSession) call(method string, params interface{}, result interface{}) error {
+	id, err := s.send(method, params)
+	if err != nil {
+		return err
+	}
+	for {
+		message, err := s.next(nil)
+		if err != nil {
+			return err
+		}
+		if message.ID != id {
+			continue
+		}
+		if message.Error != nil {
+			return errors.New(method + ": " + message.Error.Message)
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(message.Result, result)
+	}
+}
+
+/*  The function sets the cookies of a renderRequest in the browser with their attributes and pauses the
+	requests of the tab so that the headers are only added to the requests of the page host
+	Arguments:
+		request: The page to render
+	Returns:
+		An error if the browser returned an error
+ */
+
+func (s *cdpSession) setRequest(request renderRequest) error {
+	if len(request.cookies) > 0 {
+		if err := s.call("Network.enable", nil, nil); err != nil {
+			return err
+		}
+		if err := s.call("Network.setCookies", map[string]interface{}{"cookies": request.cookies}, nil); err != nil {
+			return err
+		}
+	}
+	if len(request.headers) > 0 {
+		return s.call("Fetch.enable", map[string]interface{}{"patterns": []map[string]string{{"urlPattern": "*"}}}, nil)
+	}
+	return nil
+}
+
+/*  The function continues a request paused by the Fetch domain. The headers of the session are added if the
+	request is sent to the scheme and host of the page, the requests to other hosts are sent unchanged
+	Arguments:
+		params: The parameters of the Fetch.requestPaused event
+	Returns:
+		An error if the command could not be sent
+ */
+
+func (s *cdpSession) continueRequest(params json.RawMessage) error {
+	var paused cdpPausedRequest
+	if err := json.Unmarshal(params, &paused); err != nil {
+		return err
+	}
+	continued := map[string]interface{}{"requestId": paused.RequestID}
+	requestURL, err := url.Parse(paused.Request.URL)
+	if err == nil && requestURL.Scheme == s.page.Scheme && strings.EqualFold(requestURL.Host, s.page.Host) {
+		headers := map[string]string{}
+		for name, value := range paused.Request.Headers {
+			headers[http.CanonicalHeaderKey(name)] = value
+		}
+		for name, value := range s.headers {
+			headers[http.CanonicalHeaderKey(name)] = value
+		}
+		entries := make([]map[string]string, 0, len(headers))
+		for name, value := range headers {
+			entries = append(entries, map[string]string{"name": name, "value": value})
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i]["name"] < entries[j]["name"] })
+		continued["headers"] = entries
+	}
+	_, err = s.send("Fetch.continueRequest", continued)
+	return err
+}
+
+/*  The function waits for an event of the tab
+	Arguments:
+		event: The name of the event
+	Returns:
+		An error if the connection failed before the event was received
+ */
+
+func (s *cdpSession) waitFor(event string) error {
+	for !s.events[event] {
+		if _, err := s.next(nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*  The function handles the messages of the tab for a time, so that the requests of the scripts of the page
+	are continued while they load
+	Arguments:
+		duration: The time to wait
+	Returns:
+		An error if the connection failed or the context is done
+ */
+
+func (s *cdpSession) wait(duration time.Duration) error {
+	timeout := time.After(duration)
+	for {
+		message, err := s.next(timeout)
+		if err != nil || message == nil {
+			return err
+		}
+	}
+}
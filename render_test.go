@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestRenderPage1(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<div id="app"></div><script src="/app.js"></script>`))
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testRenderer := &fakeRenderer{pages: map[string]string{testServer.URL: `<div id="app"><a href="/a">A</a></div>`}}
+	testConfig.render = renderConfig{enabled: true, rules: []renderRule{{
+		pattern:  regexp.MustCompile("^" + regexp.QuoteMeta(testServer.URL) + "$"),
+		renderer: testRenderer,
+	}}}
+	testConfig.session, _ = newSessionConfig(sessionConfig{
+		Headers: map[string]map[string]string{"*": {"X-Client": "crawler"}},
+		Cookies: map[string][]string{testServer.URL + "/": {"consent=all; Path=/"}},
+	})
+	testCrawler := newCrawler(testConfig)
+	var testOutput bytes.Buffer
+	testCrawler.output = &testOutput
+	var testLock sync.Mutex
+	testRendered := map[string]bool{}
+	testCrawler.onPage = func(page pageResult) {
+		testLock.Lock()
+		defer testLock.Unlock()
+		testRendered[strings.TrimPrefix(page.URI, testServer.URL)] = page.Rendered
+	}
+	testCrawler.Run(context.Background())
+	testCrawler.printSummary()
+	if len(testRendered) != 2 || !testRendered[""] || testRendered["/a"] {
+		fmt.Println("The links of the rendered page were not crawled")
+		fmt.Println(testRendered)
+		t.Fail()
+	} else if len(testRenderer.requests) != 1 || testRenderer.requests[0].headers["X-Client"] != "crawler" ||
+		len(testRenderer.requests[0].cookies) != 1 || testRenderer.requests[0].cookies[0].Value != "all" ||
+		testRenderer.requests[0].cookies[0].URL != testServer.URL+"/" {
+		fmt.Println("The headers and cookies of the crawl were not passed to the renderer")
+		fmt.Println(testRenderer.requests)
+		t.Fail()
+	} else if !strings.Contains(testOutput.String(), "Rendered Pages: 1\n") {
+		fmt.Println("The summary did not include the number of rendered pages")
+		fmt.Println(testOutput.String())
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for renderPage passed")
+	}
+}
+
+func newDevToolsTestServer(closed *int64, received *sync.Map) *httptest.Server {
+	mux := http.NewServeMux()
+	var testServer *httptest.Server
+	mux.HandleFunc("/json/new", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "T1", "webSocketDebuggerUrl": "ws://` + strings.TrimPrefix(testServer.URL, "http://") + `/devtools/page/T1"}`))
+	})
+	mux.HandleFunc("/json/close/T1", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(closed, 1)
+		_, _ = w.Write([]byte("Target is closing"))
+	})
+	mux.Handle("/devtools/page/T1", websocket.Server{Handshake: func(config *websocket.Config, r *http.Request) error {
+		if r.Header.Get("Origin") != devtoolsOrigin {
+			return errors.New("rejected origin")
+		}
+		return nil
+	}, Handler: func(conn *websocket.Conn) {
+		fetchEnabled := false
+		for {
+			var message struct {
+				ID     int                    `json:"id"`
+				Method string                 `json:"method"`
+				Params map[string]interface{} `json:"params"`
+			}
+			if websocket.JSON.Receive(conn, &message) != nil {
+				return
+			}
+			key := message.Method
+			if key == "Fetch.continueRequest" {
+				key += " " + fmt.Sprint(message.Params["requestId"])
+			}
+			received.Store(key, message.Params)
+			reply := map[string]interface{}{"id": message.ID, "result": map[string]interface{}{}}
+			switch message.Method {
+			case "Fetch.enable":
+				fetchEnabled = true
+			case "Page.navigate":
+				if message.Params["url"] == "https://test.com/down" {
+					reply["result"] = map[string]string{"frameId": "F1", "errorText": "net::ERR_CONNECTION_REFUSED"}
+					break
+				}
+				_ = websocket.JSON.Send(conn, map[string]string{"method": "Page.frameStartedLoading"})
+				if fetchEnabled {
+					for id, uri := range map[string]string{"R1": "https://test.com/app.js", "R2": "https://cdn.example.com/lib.js"} {
+						_ = websocket.JSON.Send(conn, map[string]interface{}{"method": "Fetch.requestPaused", "params": map[string]interface{}{
+							"requestId": id, "request": map[string]interface{}{"url": uri, "headers": map[string]string{"Accept": "*/*"}}}})
+					}
+				}
+				reply["result"] = map[string]string{"frameId": "F1"}
+				_ = websocket.JSON.Send(conn, reply)
+				reply = map[string]interface{}{"method": "Page.loadEventFired", "params": map[string]float64{"timestamp": 1}}
+			case "Runtime.evaluate":
+				reply["result"] = map[string]interface{}{"result": map[string]string{"type": "string", "value": "<html><body>rendered</body></html>"}}
+			}
+			_ = websocket.JSON.Send(conn, reply)
+		}
+	}})
+	testServer = httptest.NewServer(mux)
+	return testServer
+}
+
+func TestCDPRenderer1(t *testing.T) {
+	var testClosed int64
+	var testReceived sync.Map
+	testServer := newDevToolsTestServer(&testClosed, &testReceived)
+	defer testServer.Close()
+	testRenderer := &cdpRenderer{endpoint: testServer.URL}
+	testHTML, err := testRenderer.render(context.Background(), renderRequest{uri: "https://test.com/",
+		headers: map[string]string{"Authorization": "Bearer token"},
+		cookies: []browserCookie{{Name: "session", Value: "1", Domain: ".test.com", Path: "/app", Secure: true}}})
+	_, testErr := testRenderer.render(context.Background(), renderRequest{uri: "https://test.com/down"})
+	testCtx, testCancel := context.WithCancel(context.Background())
+	testCancel()
+	_, testCancelled := testRenderer.render(testCtx, renderRequest{uri: "https://test.com/"})
+	testRenderer.close()
+	testHeaders, _ := testReceived.Load("Fetch.continueRequest R1")
+	testOtherHost, _ := testReceived.Load("Fetch.continueRequest R2")
+	testCookies, _ := testReceived.Load("Network.setCookies")
+	if err != nil || testHTML != "<html><body>rendered</body></html>" {
+		fmt.Println("The cdpRenderer did not return the html of the page")
+		fmt.Println(testHTML, err)
+		t.Fail()
+	} else if fmt.Sprint(testHeaders) != "map[headers:[map[name:Accept value:*/*] map[name:Authorization value:Bearer token]] requestId:R1]" ||
+		fmt.Sprint(testOtherHost) != "map[requestId:R2]" ||
+		fmt.Sprint(testCookies) != "map[cookies:[map[domain:.test.com name:session path:/app secure:true value:1]]]" {
+		fmt.Println("The cdpRenderer did not set the headers and cookies of the page")
+		fmt.Println(testHeaders, testOtherHost, testCookies)
+		t.Fail()
+	} else if testCancelled == nil {
+		fmt.Println("The cdpRenderer rendered a page with a cancelled context")
+		t.Fail()
+	} else if testErr == nil || testErr.Error() != "navigation failed: net::ERR_CONNECTION_REFUSED" {
+		fmt.Println("The cdpRenderer did not return the navigation error")
+		fmt.Println(testErr)
+		t.Fail()
+	} else if atomic.LoadInt64(&testClosed) != 2 {
+		fmt.Println("The cdpRenderer did not close its tabs")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for cdpRenderer passed")
+	}
+}
+
+func TestNewRenderConfig1(t *testing.T) {
+	t.Setenv("RENDER_CDP_URL", "")
+	t.Setenv("RENDER_BROWSER", "/nonexistent/chromium")
+	_, testInvalid := newRenderConfig([]string{"/app/", "("}, defaultRenderWait)
+	_, testMissing := newRenderConfig([]string{"/app/"}, defaultRenderWait)
+	t.Setenv("RENDER_CDP_URL", "http://127.0.0.1:9222/")
+	testConfig, testErr := newRenderConfig([]string{" /app/ ", ""}, defaultRenderWait)
+	t.Setenv("RENDER_NO_SANDBOX", "true")
+	testNoSandbox, _ := newRenderConfig([]string{"/app/"}, defaultRenderWait)
+	if testInvalid == nil || testMissing != errNoBrowser {
+		fmt.Println("newRenderConfig accepted an invalid config")
+		fmt.Println(testInvalid, testMissing)
+		t.Fail()
+	} else if testErr != nil || len(testConfig.rules) != 1 || testConfig.rendererFor("https://test.com/app/home") == nil ||
+		testConfig.rendererFor("https://test.com/blog") != nil ||
+		testConfig.rules[0].renderer.(*cdpRenderer).endpoint != "http://127.0.0.1:9222" ||
+		testConfig.rules[0].renderer.(*cdpRenderer).noSandbox || !testNoSandbox.rules[0].renderer.(*cdpRenderer).noSandbox {
+		fmt.Println("newRenderConfig returned an invalid config")
+		fmt.Println(testConfig, testErr)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for newRenderConfig passed")
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/* replayConfig holds the stored crawl the responses of a replayed crawl are read from
+	path: Set from REPLAY_PATH, the directory of a crawl saved with STORE_ON_DISK or a WARC file
+	responses: The stored response of every uri of the crawl
+ */
+
+type replayConfig struct {
+	path      string
+	responses map[string]storedResponse
+}
+
+/* replayState holds the counter of the URIs of a replayed crawl that are not in the stored crawl
+ */
+
+type replayState struct {
+	notStoredCounter int64
+}
+
+/* storedResponse is a response of a stored crawl
+	statusCode: The status code of the response
+	header: The headers of the response
+	file: The file the body is read from, empty if the body is held in body or was not downloaded
+	body: The body of a response read from a WARC file
+ */
+
+type storedResponse struct {
+	statusCode int
+	header     http.Header
+	file       string
+	body       []byte
+}
+
+/* replayTransport answers the requests of a Crawler with the responses of a stored crawl instead of sending
+	them over the network
+ */
+
+type replayTransport struct {
+	responses map[string]storedResponse
+}
+
+//The error returned for the requests of the URIs that are not in the stored crawl
+var errNotStored = errors.New("uri not in the stored crawl")
+
+/*  The function reads the stored crawl of the REPLAY_PATH env variable. If the stored crawl can not be read
+	an error message is generated and the program exits.
+	Returns:
+		A replayConfig with the responses of the stored crawl or an empty replayConfig if REPLAY_PATH is not set
+ */
+
+func getReplayConfig() replayConfig {
+	if os.Getenv("REPLAY_PATH") == "" {
+		return replayConfig{}
+	}
+	config, err := newReplayConfig(os.Getenv("REPLAY_PATH"))
+	if err != nil {
+		logger.Error("invalid stored crawl for REPLAY_PATH env variable", "path", os.Getenv("REPLAY_PATH"), "error", err)
+		os.Exit(1)
+	}
+	logger.Info("replaying the stored crawl", "path", config.path, "responses", len(config.responses))
+	return config
+}
+
+/*  The function loads the responses of a stored crawl. A directory is read from the manifest.jsonl file written
+	by STORE_ON_DISK and a file is read as a WARC file, compressed with gzip if its name ends with .gz. The latest
+	response of a uri is kept if it was stored several times
+	Arguments:
+		path: The path of the directory or the WARC file
+	Returns:
+		A replayConfig with the responses of the stored crawl
+		An error if the stored crawl can not be read or holds no response
+ */
+
+func newReplayConfig(path string) (replayConfig, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return replayConfig{}, err
+	}
+	config := replayConfig{path: path}
+	if info.IsDir() {
+		config.responses, err = loadManifest(path)
+	} else {
+		config.responses, err = loadWARC(path)
+	}
+	if err == nil && len(config.responses) == 0 {
+		err = errors.New("no response found")
+	}
+	return config, err
+}
+
+/*  The function reads the manifest.jsonl file of a directory written by STORE_ON_DISK. Every redirect of a
+	record is answered with its status code and a Location header pointing to the next uri of the chain, the
+	uri of a record written without its redirects is answered with a 301 redirect to its final uri. The responses
+	that were skipped because of their content type are answered without a body
+	Arguments:
+		dir: The directory of the stored crawl
+	Returns:
+		The stored responses by uri
+		An error if the manifest can not be read or a record is invalid
+ */
+
+func loadManifest(dir string) (map[string]storedResponse, error) {
+	manifest, err := os.Open(filepath.Join(dir, "manifest.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	defer manifest.Close()
+	responses := map[string]storedResponse{}
+	scanner := bufio.NewScanner(manifest)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record storedPage
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("manifest line %d: %w", line, err)
+		}
+		if record.File == "" && !record.Skipped {
+			continue
+		}
+		header := http.Header{}
+		contentType := record.ContentType
+		if record.Charset != "" {
+			contentType += "; charset=utf-8"
+		}
+		header.Set("Content-Type", contentType)
+		if record.Compression != "" {
+			header.Set("Content-Encoding", record.Compression)
+		}
+		stored := storedResponse{statusCode: record.StatusCode, header: header}
+		if record.File != "" {
+			stored.file = filepath.Join(dir, filepath.Base(record.File))
+		}
+		responses[record.FinalURI] = stored
+		for i, hop := range record.Redirects {
+			location := record.FinalURI
+			if i+1 < len(record.Redirects) {
+				location = record.Redirects[i+1].URI
+			}
+			responses[hop.URI] = storedResponse{statusCode: hop.StatusCode, header: http.Header{"Location": {location}}}
+		}
+		if record.URI != record.FinalURI && len(record.Redirects) == 0 {
+			responses[record.URI] = storedResponse{
+				statusCode: http.StatusMovedPermanently,
+				header:     http.Header{"Location": {record.FinalURI}},
+			}
+		}
+	}
+	return responses, scanner.Err()
+}
+
+/*  The function reads the response records of a WARC file. The request, metadata and revisit records are
+	skipped and the bodies of the responses are kept in memory with the headers they were received with
+	Arguments:
+		path: The path of the WARC file
+	Returns:
+		The stored responses by uri
+		An error if the file is not a valid WARC file
+ */
+
+func loadWARC(path string) (map[string]storedResponse, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+	buffered := bufio.NewReader(reader)
+	responses := map[string]storedResponse{}
+	for {
+		version, err := buffered.ReadString('\n')
+		if err == io.EOF && strings.TrimSpace(version) == "" {
+			return responses, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(version) == "" {
+			continue
+		}
+		if !strings.HasPrefix(version, "WARC/") {
+			return nil, fmt.Errorf("invalid WARC record version %q", strings.TrimSpace(version))
+		}
+		headers, err := textproto.NewReader(buffered).ReadMIMEHeader()
+		if err != nil {
+			return nil, err
+		}
+		length, err := strconv.ParseInt(headers.Get("Content-Length"), 10, 64)
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("invalid WARC record length %q", headers.Get("Content-Length"))
+		}
+		block := make([]byte, length)
+		if _, err := io.ReadFull(buffered, block); err != nil {
+			return nil, err
+		}
+		if headers.Get("WARC-Type") != "response" || mediaType(headers.Get("Content-Type")) != "application/http" {
+			continue
+		}
+		uri := strings.Trim(headers.Get("WARC-Target-URI"), "<>")
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+		if err != nil {
+			return nil, fmt.Errorf("response of %s: %w", uri, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("response of %s: %w", uri, err)
+		}
+		resp.Header.Del("Content-Length")
+		resp.Header.Del("Transfer-Encoding")
+		responses[uri] = storedResponse{statusCode: resp.StatusCode, header: resp.Header, body: body}
+	}
+}
+
+/*  The function checks if the directory the responses are saved in is the directory of the stored crawl so
+	that a replayed crawl does not overwrite the files it reads
+	Arguments:
+		rootPath: The directory of ROOT_PATH
+		replayPath: The path of REPLAY_PATH
+	Returns:
+		A true value if both paths are the same directory
+ */
+
+func sameDirectory(rootPath string, replayPath string) bool {
+	rootInfo, rootErr := os.Stat(rootPath)
+	replayInfo, replayErr := os.Stat(replayPath)
+	return rootErr == nil && replayErr == nil && os.SameFile(rootInfo, replayInfo)
+}
+
+/*  The function answers a request with the stored response of its uri. Only GET and HEAD requests are answered
+	Arguments:
+		req: The request
+	Returns:
+		The stored response
+		errNotStored if the uri is not in the stored crawl
+ */
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	stored, ok := t.responses[req.URL.String()]
+	if !ok || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return nil, errNotStored
+	}
+	body := io.ReadCloser(http.NoBody)
+	if req.Method == http.MethodGet && stored.file != "" {
+		file, err := os.Open(stored.file)
+		if err != nil {
+			return nil, err
+		}
+		body = file
+	} else if req.Method == http.MethodGet {
+		body = io.NopCloser(bytes.NewReader(stored.body))
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(stored.statusCode) + " " + http.StatusText(stored.statusCode),
+		StatusCode:    stored.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        stored.header.Clone(),
+		Body:          body,
+		ContentLength: -1,
+		Request:       req,
+	}, nil
+}
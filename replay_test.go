@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func crawlTestResults(testCrawler *Crawler) map[string]string {
+	var testLock sync.Mutex
+	testResults := map[string]string{}
+	testCrawler.onPage = func(result pageResult) {
+		testLock.Lock()
+		testResults[result.URI] = fmt.Sprintf("%d %s %d %t", result.StatusCode, result.ContentType, result.Links, result.Skipped)
+		testLock.Unlock()
+	}
+	testCrawler.Run(context.Background())
+	return testResults
+}
+
+func TestReplay1(t *testing.T) {
+	testServer := newSiteTestServer()
+	testRoot := t.TempDir()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.writeOnDisk = true
+	testConfig.rootPath = testRoot + string(filepath.Separator)
+	testConfig.compression.store = "gzip"
+	testCrawler := newCrawler(testConfig)
+	var testOutput bytes.Buffer
+	testCrawler.output = &testOutput
+	testStored := crawlTestResults(testCrawler)
+	testServer.Close()
+	testReplayConfig, testErr := newReplayConfig(testRoot)
+	testConfig = defaultCrawlConfig(testServer.URL)
+	testConfig.replay = testReplayConfig
+	testCrawler = newCrawler(testConfig)
+	testCrawler.output = &testOutput
+	testReplayed := crawlTestResults(testCrawler)
+	testCrawler.printSummary()
+	if testErr != nil || len(testStored) != 4 || fmt.Sprint(testReplayed) != fmt.Sprint(testStored) {
+		fmt.Println("The replayed crawl is different from the stored crawl")
+		fmt.Println(testErr, testStored, testReplayed)
+		t.Fail()
+	} else if !strings.Contains(testOutput.String(), "Not In Stored Crawl: 0") {
+		fmt.Println("The summary of the replayed crawl is invalid")
+		fmt.Println(testOutput.String())
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for replay passed")
+	}
+}
+
+func TestReplay2(t *testing.T) {
+	testRecord := func(warcType string, uri string, block string) string {
+		return "WARC/1.0\r\nWARC-Type: " + warcType + "\r\nWARC-Target-URI: " + uri +
+			"\r\nContent-Type: application/http; msgtype=" + warcType + "\r\nContent-Length: " +
+			strconv.Itoa(len(block)) + "\r\n\r\n" + block + "\r\n\r\n"
+	}
+	testWARC := "WARC/1.0\r\nWARC-Type: warcinfo\r\nContent-Type: application/warc-fields\r\nContent-Length: 9\r\n\r\nsoftware:\r\n\r\n" +
+		testRecord("request", "http://test.com/", "GET / HTTP/1.1\r\nHost: test.com\r\n\r\n") +
+		testRecord("response", "http://test.com/", "HTTP/1.1 200 OK\r\nContent-Type: text/html\r\nContent-Length: 36\r\n\r\n"+
+			`<a href="/a">A</a><a href="/b">B</a>`) +
+		testRecord("response", "<http://test.com/a>", "HTTP/1.1 200 OK\r\nContent-Type: text/html\r\nTransfer-Encoding: chunked\r\n\r\n"+
+			"4\r\n<p>a\r\n4\r\n</p>\r\n0\r\n\r\n")
+	testPath := filepath.Join(t.TempDir(), "crawl.warc.gz")
+	var testBuffer bytes.Buffer
+	testWriter := gzip.NewWriter(&testBuffer)
+	_, _ = testWriter.Write([]byte(testWARC))
+	_ = testWriter.Close()
+	_ = os.WriteFile(testPath, testBuffer.Bytes(), 0644)
+	testReplayConfig, testErr := newReplayConfig(testPath)
+	_, testEmptyErr := newReplayConfig(t.TempDir())
+	testConfig := defaultCrawlConfig("http://test.com/")
+	testConfig.replay = testReplayConfig
+	testCrawler := newCrawler(testConfig)
+	var testOutput bytes.Buffer
+	testCrawler.output = &testOutput
+	testResults := crawlTestResults(testCrawler)
+	testCrawler.printSummary()
+	testExpected := "map[http://test.com/:200 text/html 2 false http://test.com/a:200 text/html 0 false http://test.com/b:0  0 true]"
+	if testErr != nil || testEmptyErr == nil || fmt.Sprint(testResults) != testExpected {
+		fmt.Println("The WARC file was not replayed")
+		fmt.Println(testErr, testEmptyErr, testResults)
+		t.Fail()
+	} else if !strings.Contains(testOutput.String(), "Not In Stored Crawl: 1") {
+		fmt.Println("The URIs missing from the WARC file were not counted")
+		fmt.Println(testOutput.String())
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for replay passed")
+	}
+}
+
+func TestReplay3(t *testing.T) {
+	testServer := newRedirectTestServer()
+	testRoot := t.TempDir() + string(filepath.Separator)
+	testCrawler := newCrawler(defaultCrawlConfig(testServer.URL))
+	testStored := testCrawler.fetchPage(context.Background(), testServer.URL+"/start")
+	testCrawler.storePage(testStored, testStored.body, testRoot)
+	testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.replay, _ = newReplayConfig(testRoot)
+	testReplayed := newCrawler(testConfig).fetchPage(context.Background(), testServer.URL+"/start")
+	if len(testStored.redirects) != 2 || fmt.Sprint(testReplayed.redirects) != fmt.Sprint(testStored.redirects) ||
+		testReplayed.finalURI != testStored.finalURI || testReplayed.statusCode != testStored.statusCode {
+		fmt.Println("The redirect chain of the replayed uri is different from the stored chain")
+		fmt.Println(testStored.redirects, testReplayed.redirects, testReplayed.finalURI)
+		t.Fail()
+	} else {
+		fmt.Println("Test 3 for replay passed")
+	}
+}
+
+func TestReplay4(t *testing.T) {
+	testExternal := httptest.NewServer(http.NotFoundHandler())
+	testExternalURI := strings.Replace(testExternal.URL, "127.0.0.1", "localhost", 1) + "/"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<a href="` + testExternalURI + `">External</a>`))
+	})
+	testServer := httptest.NewServer(mux)
+	testRoot := t.TempDir() + string(filepath.Separator)
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.writeOnDisk = true
+	testConfig.rootPath = testRoot
+	testConfig.linkCheck = linkCheckConfig{enabled: true, checkExternal: true}
+	testCrawler := newCrawler(testConfig)
+	testCrawler.output = io.Discard
+	testCrawler.Run(context.Background())
+	testStoredBroken := testCrawler.printBrokenLinks()
+	testServer.Close()
+	testExternal.Close()
+	testConfig = defaultCrawlConfig(testServer.URL)
+	testConfig.replay, _ = newReplayConfig(testRoot)
+	testConfig.linkCheck = linkCheckConfig{enabled: true, checkExternal: true}
+	testCrawler = newCrawler(testConfig)
+	var testOutput bytes.Buffer
+	testCrawler.output = &testOutput
+	testCrawler.Run(context.Background())
+	testReplayedBroken := testCrawler.printBrokenLinks()
+	if testStoredBroken != 1 || testReplayedBroken != 0 || strings.Contains(testOutput.String(), testExternalURI) {
+		fmt.Println("The external link that is not in the stored crawl was reported as broken")
+		fmt.Println(testStoredBroken, testOutput.String())
+		t.Fail()
+	} else {
+		fmt.Println("Test 4 for replay passed")
+	}
+}
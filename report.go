@@ -0,0 +1,386 @@
+package main
+
+import (
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"golang.org/x/net/html"
+)
+
+//The report formats of REPORT_OUTPUT by file extension
+var reportFormats = map[string]string{".md": "markdown", ".markdown": "markdown", ".html": "html", ".htm": "html"}
+
+//The number of pages with the highest PageRank listed in the report
+const topPageRankPages = 20
+
+const defaultReportMaxDepth = 3
+
+/* reportConfig holds the options of the site structure report
+	enabled: Set when REPORT_OUTPUT is set, records the canonical URLs of the pages and the link graph
+	output: Set from REPORT_OUTPUT, the file the report is written to at the end of the crawl
+	maxDepth: Set from REPORT_MAX_DEPTH, pages more than this many clicks away from the crawlURI are reported
+ */
+
+type reportConfig struct {
+	enabled  bool
+	output   string
+	maxDepth int
+}
+
+/* redirectChainReport is a uri that was redirected
+	Redirects: The number of redirects followed
+	Chain: Every uri of the chain with its status code, from the uri to the final uri
+ */
+
+type redirectChainReport struct {
+	URI       string
+	Redirects int
+	Chain     []reportHop
+}
+
+type reportHop struct {
+	URI        string
+	StatusCode int
+}
+
+/* canonicalConflict is a page whose canonical URL can not be used by search engines
+ */
+
+type canonicalConflict struct {
+	Page      string
+	Canonical string
+	Reason    string
+}
+
+/* siteReport holds the results of the site structure analysis
+	TopPages: The pages with the highest PageRank
+	DeepPages: The pages more than MaxDepth clicks away from the crawlURI or not reachable from it
+	Orphans: The pages without inbound links from other pages of the host
+ */
+
+type siteReport struct {
+	CrawlURI           string
+	Pages              int
+	Links              int
+	MaxDepth           int
+	TopPages           []graphNode
+	DeepPages          []graphNode
+	Orphans            []graphNode
+	RedirectChains     []redirectChainReport
+	CanonicalConflicts []canonicalConflict
+}
+
+var markdownReport = template.Must(template.New("report").Funcs(template.FuncMap{"cell": markdownCell}).Parse(
+	`# Site structure report for {{.CrawlURI}}
+
+{{.Pages}} pages, {{.Links}} links
+
+## Top pages by internal PageRank
+
+| Page | PageRank | Inbound links | Depth |
+| --- | --- | --- | --- |
+{{range .TopPages}}| {{cell .URI}} | {{printf "%.4f" .PageRank}} | {{.Inbound}} | {{.Depth}} |
+{{end}}
+## Pages deeper than {{.MaxDepth}} clicks
+
+{{if .DeepPages}}| Page | Depth |
+| --- | --- |
+{{range .DeepPages}}| {{cell .URI}} | {{if lt .Depth 0}}unreachable{{else}}{{.Depth}}{{end}} |
+{{end}}{{else}}None
+{{end}}
+## Pages without inbound internal links
+
+{{if .Orphans}}{{range .Orphans}}- {{.URI}}
+{{end}}{{else}}None
+{{end}}
+## Redirect chains
+
+{{if .RedirectChains}}| URI | Redirects | Chain |
+| --- | --- | --- |
+{{range .RedirectChains}}| {{cell .URI}} | {{.Redirects}} | {{range $i, $hop := .Chain}}{{if $i}} → {{end}}{{cell $hop.URI}} ({{$hop.StatusCode}}){{end}} |
+{{end}}{{else}}None
+{{end}}
+## Canonical conflicts
+
+{{if .CanonicalConflicts}}| Page | Canonical | Conflict |
+| --- | --- | --- |
+{{range .CanonicalConflicts}}| {{cell .Page}} | {{cell .Canonical}} | {{.Reason}} |
+{{end}}{{else}}None
+{{end}}`))
+
+var htmlReport = htmltemplate.Must(htmltemplate.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Site structure report for {{.CrawlURI}}</title>
+<style>body{font-family:sans-serif}table{border-collapse:collapse}td,th{border:1px solid #ccc;padding:4px 8px;text-align:left}</style>
+</head>
+<body>
+<h1>Site structure report for {{.CrawlURI}}</h1>
+<p>{{.Pages}} pages, {{.Links}} links</p>
+<h2>Top pages by internal PageRank</h2>
+<table>
+<tr><th>Page</th><th>PageRank</th><th>Inbound links</th><th>Depth</th></tr>
+{{range .TopPages}}<tr><td><a href="{{.URI}}">{{.URI}}</a></td><td>{{printf "%.4f" .PageRank}}</td><td>{{.Inbound}}</td><td>{{.Depth}}</td></tr>
+{{end}}</table>
+<h2>Pages deeper than {{.MaxDepth}} clicks</h2>
+{{if .DeepPages}}<table>
+<tr><th>Page</th><th>Depth</th></tr>
+{{range .DeepPages}}<tr><td><a href="{{.URI}}">{{.URI}}</a></td><td>{{if lt .Depth 0}}unreachable{{else}}{{.Depth}}{{end}}</td></tr>
+{{end}}</table>{{else}}<p>None</p>{{end}}
+<h2>Pages without inbound internal links</h2>
+{{if .Orphans}}<ul>
+{{range .Orphans}}<li><a href="{{.URI}}">{{.URI}}</a></li>
+{{end}}</ul>{{else}}<p>None</p>{{end}}
+<h2>Redirect chains</h2>
+{{if .RedirectChains}}<table>
+<tr><th>URI</th><th>Redirects</th><th>Chain</th></tr>
+{{range .RedirectChains}}<tr><td>{{.URI}}</td><td>{{.Redirects}}</td><td>{{range $i, $hop := .Chain}}{{if $i}} &rarr; {{end}}{{$hop.URI}} ({{$hop.StatusCode}}){{end}}</td></tr>
+{{end}}</table>{{else}}<p>None</p>{{end}}
+<h2>Canonical conflicts</h2>
+{{if .CanonicalConflicts}}<table>
+<tr><th>Page</th><th>Canonical</th><th>Conflict</th></tr>
+{{range .CanonicalConflicts}}<tr><td>{{.Page}}</td><td>{{.Canonical}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>{{else}}<p>None</p>{{end}}
+</body>
+</html>
+`))
+
+/*  The function reads the REPORT_OUTPUT and REPORT_MAX_DEPTH env variables. The format of the report is
+	chosen by the extension of REPORT_OUTPUT: .md or .markdown for Markdown and .html or .htm for HTML.
+	If a value is invalid an error message is generated and the program exits.
+	Returns:
+		A reportConfig with the options set by the user
+ */
+
+func getReportConfig() reportConfig {
+	config := reportConfig{maxDepth: defaultReportMaxDepth}
+	if os.Getenv("REPORT_MAX_DEPTH") != "" {
+		maxDepth, err := strconv.Atoi(os.Getenv("REPORT_MAX_DEPTH"))
+		if err != nil || maxDepth < 0 {
+			logger.Error("invalid value for REPORT_MAX_DEPTH env variable", "value", os.Getenv("REPORT_MAX_DEPTH"))
+			os.Exit(1)
+		}
+		config.maxDepth = maxDepth
+	}
+	if os.Getenv("REPORT_OUTPUT") == "" {
+		return config
+	}
+	config.output = os.Getenv("REPORT_OUTPUT")
+	if _, ok := reportFormats[strings.ToLower(filepath.Ext(config.output))]; !ok {
+		logger.Error("invalid value for REPORT_OUTPUT env variable, supported extensions are .md, .markdown, .html and .htm",
+			"value", config.output)
+		os.Exit(1)
+	}
+	config.enabled = true
+	return config
+}
+
+/*  The function returns the canonical URLs declared with <link rel="canonical"> in the head of an HTML page
+	Arguments:
+		httpBody: Response body is passed as a reader object
+	Returns:
+		The unique hrefs of the canonical links in the order they were found
+ */
+
+func getCanonicalLinks(httpBody io.Reader) []string {
+	var canonicals []string
+	page := html.NewTokenizer(httpBody)
+	for {
+		tokenType := page.Next()
+		if tokenType == html.ErrorToken {
+			return canonicals
+		}
+		token := page.Token()
+		if token.DataAtom.String() == "body" || (tokenType == html.EndTagToken && token.DataAtom.String() == "head") {
+			return canonicals
+		}
+		if (tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken) || token.DataAtom.String() != "link" {
+			continue
+		}
+		var rel, href string
+		for _, attr := range token.Attr {
+			switch attr.Key {
+			case "rel":
+				rel = strings.ToLower(attr.Val)
+			case "href":
+				href = strings.TrimSpace(attr.Val)
+			}
+		}
+		if hasRel(rel, "canonical") && href != "" {
+			appendHrefURL(&canonicals, []string{href})
+		}
+	}
+}
+
+/*  The function records the canonical URLs of a page for the report
+	Arguments:
+		pageURI: The uri the page was served from
+		body: The response body of the page
+ */
+
+func (c *Crawler) recordCanonical(pageURI string, body string) {
+	if !c.config.report.enabled {
+		return
+	}
+	var canonicals []string
+	for _, canonical := range getCanonicalLinks(strings.NewReader(body)) {
+		appendHrefURL(&canonicals, []string{graphKey(absoluteURL(canonical, pageURI))})
+	}
+	if len(canonicals) == 0 {
+		return
+	}
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	if c.graphCanonicals == nil {
+		c.graphCanonicals = map[string][]string{}
+	}
+	c.graphCanonicals[graphKey(pageURI)] = canonicals
+}
+
+/*  The function analyses the link graph, the redirects and the canonical URLs recorded so far
+	Returns:
+		A siteReport with the results of the analysis
+ */
+
+func (c *Crawler) siteReport() siteReport {
+	graph := c.linkGraph()
+	report := siteReport{CrawlURI: c.config.crawlURI, Pages: len(graph.nodes), Links: len(graph.edges),
+		MaxDepth: c.config.report.maxDepth}
+	for _, node := range graph.nodes {
+		if node.Depth > report.MaxDepth || node.Depth < 0 {
+			report.DeepPages = append(report.DeepPages, node)
+		}
+		if node.Orphan {
+			report.Orphans = append(report.Orphans, node)
+		}
+	}
+	report.TopPages = append([]graphNode(nil), graph.nodes...)
+	sort.SliceStable(report.TopPages, func(i, j int) bool { return report.TopPages[i].PageRank > report.TopPages[j].PageRank })
+	if len(report.TopPages) > topPageRankPages {
+		report.TopPages = report.TopPages[:topPageRankPages]
+	}
+	c.redirectChains.Range(func(key, value interface{}) bool {
+		hops := value.([]redirectHop)
+		chain := redirectChainReport{URI: key.(string), Redirects: len(hops) - 1}
+		for _, hop := range hops {
+			chain.Chain = append(chain.Chain, reportHop{URI: hop.uri, StatusCode: hop.statusCode})
+		}
+		report.RedirectChains = append(report.RedirectChains, chain)
+		return true
+	})
+	sort.Slice(report.RedirectChains, func(i, j int) bool {
+		if report.RedirectChains[i].Redirects != report.RedirectChains[j].Redirects {
+			return report.RedirectChains[i].Redirects > report.RedirectChains[j].Redirects
+		}
+		return report.RedirectChains[i].URI < report.RedirectChains[j].URI
+	})
+	c.graphLock.Lock()
+	canonicals := map[string][]string{}
+	for page, pageCanonicals := range c.graphCanonicals {
+		canonicals[page] = pageCanonicals
+	}
+	c.graphLock.Unlock()
+	var pages []string
+	for page := range canonicals {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	for _, page := range pages {
+		report.CanonicalConflicts = append(report.CanonicalConflicts, c.canonicalConflicts(page, canonicals, graph)...)
+	}
+	return report
+}
+
+/*  The function checks the canonical URLs of a page. A page conflicts when it declares several canonical
+	URLs or its canonical URL is on another host, redirects, fails or declares another canonical URL
+	Arguments:
+		page: The uri of the page
+		canonicals: The canonical URLs of every page
+		graph: The link graph with the status codes of the pages
+	Returns:
+		The canonicalConflicts of the page
+ */
+
+func (c *Crawler) canonicalConflicts(page string, canonicals map[string][]string, graph linkGraph) []canonicalConflict {
+	if len(canonicals[page]) > 1 {
+		return []canonicalConflict{{Page: page, Canonical: strings.Join(canonicals[page], ", "),
+			Reason: "multiple canonical URLs"}}
+	}
+	canonical := canonicals[page][0]
+	if canonical == page {
+		return nil
+	}
+	conflict := canonicalConflict{Page: page, Canonical: canonical}
+	canonicalURL, err := url.Parse(canonical)
+	index, fetched := graph.index[canonical]
+	switch {
+	case err != nil || canonicalURL.Hostname() != c.config.hostBaseURL:
+		conflict.Reason = "canonical URL on another host"
+	case fetched && graph.nodes[index].StatusCode >= 300 && graph.nodes[index].StatusCode < 400:
+		conflict.Reason = "canonical URL redirects (" + strconv.Itoa(graph.nodes[index].StatusCode) + ")"
+	case fetched && graph.nodes[index].StatusCode >= 400:
+		conflict.Reason = "canonical URL returns " + strconv.Itoa(graph.nodes[index].StatusCode)
+	case len(canonicals[canonical]) > 0 && canonicals[canonical][0] != canonical:
+		conflict.Reason = "canonical URL declares another canonical URL " + canonicals[canonical][0]
+	default:
+		return nil
+	}
+	return []canonicalConflict{conflict}
+}
+
+/*  The function writes the site report in one of the report formats
+	Arguments:
+		w: The io.Writer the report is written to
+		format: markdown or html
+	Returns:
+		An error if the format is not supported or the report could not be written
+ */
+
+func (report siteReport) write(w io.Writer, format string) error {
+	switch format {
+	case "markdown":
+		return markdownReport.Execute(w, report)
+	case "html":
+		return htmlReport.Execute(w, report)
+	}
+	return errors.New("unsupported report format " + format + ", supported values are markdown and html")
+}
+
+/*  The function writes the site report to REPORT_OUTPUT
+ */
+
+func (c *Crawler) writeReport() {
+	report := c.siteReport()
+	file, err := os.Create(c.config.report.output)
+	if err == nil {
+		err = report.write(file, reportFormats[strings.ToLower(filepath.Ext(c.config.report.output))])
+		err = errors.Join(err, file.Close())
+	}
+	if err != nil {
+		logger.Error("error while writing the site report", "path", c.config.report.output, "error", err)
+		return
+	}
+	logger.Info("site report written", "path", c.config.report.output, "deep_pages", len(report.DeepPages),
+		"orphans", len(report.Orphans), "redirect_chains", len(report.RedirectChains),
+		"canonical_conflicts", len(report.CanonicalConflicts))
+}
+
+/*  The function escapes a value for a cell of a Markdown table
+	Arguments:
+		s: The value of the cell
+	Returns:
+		A string with the pipes and line breaks escaped
+ */
+
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
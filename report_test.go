@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestComputePageRank1(t *testing.T) {
+	testCrawler := newCrawler(defaultCrawlConfig("https://test.com"))
+	testCrawler.config.graph.enabled = true
+	testCrawler.recordGraph("https://test.com", []anchor{{href: "/b"}, {href: "/c", rel: "nofollow"}})
+	testCrawler.recordGraph("https://test.com/c", []anchor{{href: "/b"}})
+	testCrawler.recordGraph("https://test.com/b", []anchor{{href: "/"}, {href: "/b"}})
+	testGraph := testCrawler.linkGraph()
+	var testTotal float64
+	for _, node := range testGraph.nodes {
+		testTotal += node.PageRank
+	}
+	testRoot := testGraph.nodes[testGraph.index["https://test.com"]].PageRank
+	testB := testGraph.nodes[testGraph.index["https://test.com/b"]].PageRank
+	testC := testGraph.nodes[testGraph.index["https://test.com/c"]].PageRank
+	if math.Abs(testTotal-1) > 1e-6 || testB <= testRoot || testRoot <= testC || math.Abs(testC-0.05) > 1e-6 {
+		fmt.Println("computePageRank returned invalid ranks")
+		fmt.Println(testGraph.nodes)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for computePageRank passed")
+	}
+}
+
+func TestGetCanonicalLinks1(t *testing.T) {
+	testCanonicals := getCanonicalLinks(strings.NewReader(`<html><head><link rel="stylesheet" href="/s.css">` +
+		`<link rel="Canonical" href=" /a "><link rel="canonical" href="/a"/></head>` +
+		`<body><link rel="canonical" href="/b"></body></html>`))
+	if len(testCanonicals) != 1 || testCanonicals[0] != "/a" {
+		fmt.Println("getCanonicalLinks returned invalid canonical links")
+		fmt.Println(testCanonicals)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for getCanonicalLinks passed")
+	}
+}
+
+func newReportTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<head><link rel="canonical" href="/"></head><a href="/old">Old</a><a href="/a">A</a>`))
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/older", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/older", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusFound)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<head><link rel="canonical" href="/gone"></head>`))
+	})
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<head><link rel="canonical" href="/a"><link rel="canonical" href="/b"></head>` +
+			`<a href="/b">B</a><a href="/gone">Gone</a>`))
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<head><link rel="canonical" href="/old"></head><a href="/c|d">C</a>`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestSiteReport1(t *testing.T) {
+	testServer := newReportTestServer()
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.graph.enabled = true
+	testConfig.report = reportConfig{enabled: true, maxDepth: 1}
+	testCrawler := newCrawler(testConfig)
+	testCrawler.output = io.Discard
+	testCrawler.Run(context.Background())
+	testReport := testCrawler.siteReport()
+	testConflicts := map[string]string{}
+	for _, conflict := range testReport.CanonicalConflicts {
+		testConflicts[conflict.Page[len(testServer.URL):]] = conflict.Reason
+	}
+	if len(testReport.RedirectChains) != 1 || testReport.RedirectChains[0].Redirects != 2 ||
+		len(testReport.RedirectChains[0].Chain) != 3 || testReport.RedirectChains[0].Chain[2].StatusCode != 200 {
+		fmt.Println("The site report did not list the redirect chain")
+		fmt.Println(testReport.RedirectChains)
+		t.Fail()
+	} else if len(testReport.DeepPages) != 4 || len(testReport.Orphans) != 0 || len(testReport.TopPages) != 7 {
+		fmt.Println("The site report did not list the deep pages")
+		fmt.Println(testReport.DeepPages, testReport.TopPages)
+		t.Fail()
+	} else if len(testConflicts) != 3 || testConflicts["/a"] != "multiple canonical URLs" ||
+		testConflicts["/b"] != "canonical URL redirects (301)" || testConflicts["/new"] != "canonical URL returns 404" {
+		fmt.Println("The site report did not list the canonical conflicts")
+		fmt.Println(testConflicts)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for siteReport passed")
+	}
+}
+
+func TestSiteReport2(t *testing.T) {
+	testReport := siteReport{CrawlURI: "https://test.com", MaxDepth: 3,
+		TopPages:       []graphNode{{URI: "https://test.com/a|b", PageRank: 0.5}},
+		RedirectChains: []redirectChainReport{{URI: "https://test.com/old", Redirects: 1, Chain: []reportHop{{"https://test.com/old", 301}, {"https://test.com/<new>", 200}}}}}
+	var testMarkdown, testHTML bytes.Buffer
+	testMarkdownErr := testReport.write(&testMarkdown, "markdown")
+	testHTMLErr := testReport.write(&testHTML, "html")
+	if testMarkdownErr != nil || !strings.Contains(testMarkdown.String(), `| https://test.com/a\|b | 0.5000 | 0 | 0 |`) ||
+		!strings.Contains(testMarkdown.String(), "https://test.com/old (301) → https://test.com/<new> (200)") ||
+		!strings.Contains(testMarkdown.String(), "## Canonical conflicts\n\nNone") {
+		fmt.Println("The site report was not written as Markdown")
+		fmt.Println(testMarkdownErr, testMarkdown.String())
+		t.Fail()
+	} else if testHTMLErr != nil || !strings.Contains(testHTML.String(), "https://test.com/&lt;new&gt; (200)") ||
+		testReport.write(io.Discard, "pdf") == nil {
+		fmt.Println("The site report was not written as HTML")
+		fmt.Println(testHTMLErr, testHTML.String())
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for siteReport passed")
+	}
+}
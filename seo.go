@@ -0,0 +1,382 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+/* pageAudit holds the on-page SEO metadata of an html page
+	Title: The text of the first title element
+	Description: The content of the description meta element
+	Headings: The h1 to h6 elements in the order they appear
+	Canonical: The href of every canonical link element
+	Hreflang: The alternate versions of the page for other languages and regions
+	OpenGraph, Twitter: The og: and twitter: meta properties, the first value of a property is kept
+	Robots: The directives of the robots and googlebot meta elements in lower case
+	WordCount: The number of words of the text outside the title, scripts and styles
+	MissingAlt: The src of every image without an alt attribute
+	Issues: The problems found on the page
+ */
+
+type pageAudit struct {
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Headings    []heading         `json:"headings,omitempty"`
+	Canonical   []string          `json:"canonical,omitempty"`
+	Hreflang    []hreflangLink    `json:"hreflang,omitempty"`
+	OpenGraph   map[string]string `json:"open_graph,omitempty"`
+	Twitter     map[string]string `json:"twitter,omitempty"`
+	Robots      []string          `json:"robots,omitempty"`
+	WordCount   int               `json:"word_count"`
+	MissingAlt  []string          `json:"missing_alt,omitempty"`
+	Issues      []string          `json:"issues,omitempty"`
+}
+
+type heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+}
+
+type hreflangLink struct {
+	Lang string `json:"lang"`
+	Href string `json:"href"`
+}
+
+/* seoState holds the audits of the pages fetched by a Crawler
+	audits: The pageAudit of every page by the uri it was served from
+ */
+
+type seoState struct {
+	seoLock sync.Mutex
+	audits  map[string]pageAudit
+}
+
+/* auditIssue is a problem found by the SEO audit with the pages it was found on
+	Value: The title or description shared by the pages of a duplicate title or description
+ */
+
+type auditIssue struct {
+	Issue string   `json:"issue"`
+	Value string   `json:"value,omitempty"`
+	Pages []string `json:"pages"`
+}
+
+/* auditSummary holds the issues found on the pages audited so far
+ */
+
+type auditSummary struct {
+	Pages  int          `json:"pages"`
+	Issues []auditIssue `json:"issues"`
+}
+
+//The problems reported by the SEO audit
+const (
+	issueMissingTitle         = "missing title"
+	issueMissingDescription   = "missing description"
+	issueMissingH1            = "missing h1"
+	issueMultipleH1           = "multiple h1"
+	issueMissingAlt           = "images without alt"
+	issueNoindex              = "noindex"
+	issueDuplicateTitle       = "duplicate title"
+	issueDuplicateDescription = "duplicate description"
+)
+
+//The order the issues of a single page are summarized in
+var pageIssues = []string{issueMissingTitle, issueMissingDescription, issueMissingH1, issueMultipleH1,
+	issueMissingAlt, issueNoindex}
+
+//The elements whose text is not counted in the word count of a page
+var uncountedElements = map[string]bool{"script": true, "style": true, "noscript": true, "template": true, "title": true}
+
+/*  The function checks the value of the SEO_AUDIT env variable and returns false if an invalid value is
+	provided or returns the value of the SEO_AUDIT env variable
+ */
+
+func checkSEOAudit() bool {
+	if os.Getenv("SEO_AUDIT") == "" {
+		return false
+	}
+	audit, err := strconv.ParseBool(os.Getenv("SEO_AUDIT"))
+	if err != nil {
+		logger.Warn("invalid value specified for SEO_AUDIT env variable, the pages are not audited",
+			"value", os.Getenv("SEO_AUDIT"))
+		return false
+	}
+	return audit
+}
+
+/*  The function extracts the SEO metadata of an html page
+	Arguments:
+		doc: The parsed html page
+	Returns:
+		A pageAudit of the page with the issues found
+ */
+
+func auditDocument(doc *html.Node) pageAudit {
+	var audit pageAudit
+	titleDone := false
+	var walk func(n *html.Node, counted bool)
+	walk = func(n *html.Node, counted bool) {
+		if n.Type == html.TextNode && counted {
+			audit.WordCount += len(strings.Fields(n.Data))
+		}
+		if n.Type == html.ElementNode {
+			name := n.DataAtom.String()
+			switch {
+			case name == "title" && !titleDone:
+				audit.Title = nodeText(n)
+				titleDone = true
+			case headingLevel(name) > 0:
+				audit.Headings = append(audit.Headings, heading{Level: headingLevel(name), Text: nodeText(n)})
+			case name == "meta":
+				audit.addMeta(n.Attr)
+			case name == "link":
+				audit.addLink(n.Attr)
+			case name == "img":
+				audit.addImage(n.Attr)
+			}
+			counted = counted && !uncountedElements[name]
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, counted)
+		}
+	}
+	walk(doc, true)
+	audit.check()
+	return audit
+}
+
+/*  The function returns the level of a heading element
+	Arguments:
+		name: The name of the element
+	Returns:
+		An int from 1 for h1 to 6 for h6, 0 for the other elements
+ */
+
+func headingLevel(name string) int {
+	if len(name) != 2 || name[0] != 'h' || name[1] < '1' || name[1] > '6' {
+		return 0
+	}
+	return int(name[1] - '0')
+}
+
+/*  The function records the description, robots directives and Open Graph and Twitter properties of a
+	meta element
+	Arguments:
+		attrs: The attributes of the meta element
+ */
+
+func (audit *pageAudit) addMeta(attrs []html.Attribute) {
+	var name, content string
+	for _, attr := range attrs {
+		if (attr.Key == "name" || attr.Key == "property") && name == "" {
+			name = strings.ToLower(strings.TrimSpace(attr.Val))
+		} else if attr.Key == "content" {
+			content = strings.TrimSpace(attr.Val)
+		}
+	}
+	switch {
+	case name == "description" && audit.Description == "":
+		audit.Description = content
+	case name == "robots" || name == "googlebot":
+		for _, directive := range strings.Split(strings.ToLower(content), ",") {
+			if directive = strings.TrimSpace(directive); directive != "" {
+				appendHrefURL(&audit.Robots, []string{directive})
+			}
+		}
+	case strings.HasPrefix(name, "og:"):
+		if audit.OpenGraph == nil {
+			audit.OpenGraph = map[string]string{}
+		}
+		if _, ok := audit.OpenGraph[name]; !ok {
+			audit.OpenGraph[name] = content
+		}
+	case strings.HasPrefix(name, "twitter:"):
+		if audit.Twitter == nil {
+			audit.Twitter = map[string]string{}
+		}
+		if _, ok := audit.Twitter[name]; !ok {
+			audit.Twitter[name] = content
+		}
+	}
+}
+
+/*  The function records the canonical and hreflang alternate URLs of a link element
+	Arguments:
+		attrs: The attributes of the link element
+ */
+
+func (audit *pageAudit) addLink(attrs []html.Attribute) {
+	var rel, href, lang string
+	for _, attr := range attrs {
+		switch attr.Key {
+		case "rel":
+			rel = strings.Join(strings.Fields(strings.ToLower(attr.Val)), " ")
+		case "href":
+			href = strings.TrimSpace(attr.Val)
+		case "hreflang":
+			lang = strings.TrimSpace(attr.Val)
+		}
+	}
+	if hasRel(rel, "canonical") && href != "" {
+		audit.Canonical = append(audit.Canonical, href)
+	}
+	if hasRel(rel, "alternate") && lang != "" && href != "" {
+		audit.Hreflang = append(audit.Hreflang, hreflangLink{Lang: lang, Href: href})
+	}
+}
+
+/*  The function records the images without an alt attribute. An empty alt attribute marks a decorative
+	image and is not reported
+	Arguments:
+		attrs: The attributes of the img element
+ */
+
+func (audit *pageAudit) addImage(attrs []html.Attribute) {
+	var src string
+	for _, attr := range attrs {
+		if attr.Key == "alt" {
+			return
+		}
+		if attr.Key == "src" {
+			src = attr.Val
+		}
+	}
+	audit.MissingAlt = append(audit.MissingAlt, src)
+}
+
+/*  The function sets the issues of the page from its metadata
+ */
+
+func (audit *pageAudit) check() {
+	h1 := 0
+	for _, h := range audit.Headings {
+		if h.Level == 1 {
+			h1++
+		}
+	}
+	audit.Issues = nil
+	if audit.Title == "" {
+		audit.Issues = append(audit.Issues, issueMissingTitle)
+	}
+	if audit.Description == "" {
+		audit.Issues = append(audit.Issues, issueMissingDescription)
+	}
+	if h1 == 0 {
+		audit.Issues = append(audit.Issues, issueMissingH1)
+	} else if h1 > 1 {
+		audit.Issues = append(audit.Issues, issueMultipleH1)
+	}
+	if len(audit.MissingAlt) > 0 {
+		audit.Issues = append(audit.Issues, issueMissingAlt)
+	}
+	if checkURI(audit.Robots, "noindex") || checkURI(audit.Robots, "none") {
+		audit.Issues = append(audit.Issues, issueNoindex)
+	}
+}
+
+/*  The function records the audit of a page for the summary
+	Arguments:
+		pageURI: The uri the page was served from
+		audit: The pageAudit of the page
+ */
+
+func (c *Crawler) recordAudit(pageURI string, audit pageAudit) {
+	c.seoLock.Lock()
+	defer c.seoLock.Unlock()
+	if c.audits == nil {
+		c.audits = map[string]pageAudit{}
+	}
+	c.audits[pageURI] = audit
+}
+
+/*  The function groups the issues of the pages audited so far and finds the pages sharing a title or a
+	description
+	Returns:
+		An auditSummary with the pages of every issue
+ */
+
+func (c *Crawler) auditSummary() auditSummary {
+	c.seoLock.Lock()
+	audits := make(map[string]pageAudit, len(c.audits))
+	for page, audit := range c.audits {
+		audits[page] = audit
+	}
+	c.seoLock.Unlock()
+	var pages []string
+	for page := range audits {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	issuePages := map[string][]string{}
+	titles := map[string][]string{}
+	descriptions := map[string][]string{}
+	for _, page := range pages {
+		audit := audits[page]
+		for _, issue := range audit.Issues {
+			issuePages[issue] = append(issuePages[issue], page)
+		}
+		if audit.Title != "" {
+			titles[audit.Title] = append(titles[audit.Title], page)
+		}
+		if audit.Description != "" {
+			descriptions[audit.Description] = append(descriptions[audit.Description], page)
+		}
+	}
+	summary := auditSummary{Pages: len(pages), Issues: []auditIssue{}}
+	for _, issue := range pageIssues {
+		if len(issuePages[issue]) > 0 {
+			summary.Issues = append(summary.Issues, auditIssue{Issue: issue, Pages: issuePages[issue]})
+		}
+	}
+	summary.Issues = append(summary.Issues, duplicateIssues(issueDuplicateTitle, titles)...)
+	summary.Issues = append(summary.Issues, duplicateIssues(issueDuplicateDescription, descriptions)...)
+	return summary
+}
+
+/*  The function returns an issue for every value shared by more than one page
+	Arguments:
+		issue: The name of the issue
+		values: The pages of every value
+	Returns:
+		The auditIssues sorted by value
+ */
+
+func duplicateIssues(issue string, values map[string][]string) []auditIssue {
+	var shared []string
+	for value, pages := range values {
+		if len(pages) > 1 {
+			shared = append(shared, value)
+		}
+	}
+	sort.Strings(shared)
+	var issues []auditIssue
+	for _, value := range shared {
+		issues = append(issues, auditIssue{Issue: issue, Value: value, Pages: values[value]})
+	}
+	return issues
+}
+
+/*  The function prints the issues found by the SEO audit with the pages they were found on
+ */
+
+func (c *Crawler) printAuditSummary() {
+	summary := c.auditSummary()
+	_, _ = fmt.Fprintln(c.output, "SEO Audit: "+strconv.Itoa(summary.Pages)+" pages, "+strconv.Itoa(len(summary.Issues))+" issues")
+	for _, issue := range summary.Issues {
+		label := issue.Issue
+		if issue.Value != "" {
+			label += " " + strconv.Quote(issue.Value)
+		}
+		_, _ = fmt.Fprintln(c.output, "\t"+label+": "+strconv.Itoa(len(issue.Pages)))
+		for _, page := range issue.Pages {
+			_, _ = fmt.Fprintln(c.output, "\t\t"+page)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAuditDocument1(t *testing.T) {
+	testDoc := parseDocument(strings.NewReader(`<html><head><title> Home  page </title>` +
+		`<meta name="Description" content=" The home page ">` +
+		`<meta property="og:title" content="Home"><meta property="og:title" content="Other">` +
+		`<meta name="twitter:card" content="summary">` +
+		`<link rel="canonical" href="https://test.com/">` +
+		`<link rel="alternate" hreflang="de" href="https://test.com/de/">` +
+		`<script>var words = "not counted";</script></head>` +
+		`<body><h1>Welcome <a href="/a#top">home</a></h1><h2>Second</h2>` +
+		`<p>Some text here</p><img src="/logo.png" alt=""></body></html>`))
+	testAnchors, testAudit := documentAnchors(testDoc), auditDocument(testDoc)
+	testExpected := pageAudit{
+		Title:       "Home page",
+		Description: "The home page",
+		Headings:    []heading{{Level: 1, Text: "Welcome home"}, {Level: 2, Text: "Second"}},
+		Canonical:   []string{"https://test.com/"},
+		Hreflang:    []hreflangLink{{Lang: "de", Href: "https://test.com/de/"}},
+		OpenGraph:   map[string]string{"og:title": "Home"},
+		Twitter:     map[string]string{"twitter:card": "summary"},
+		WordCount:   6,
+	}
+	if len(testAnchors) != 1 || testAnchors[0].href != "/a" || testAnchors[0].text != "home" {
+		fmt.Println("documentAnchors returned invalid anchors")
+		fmt.Println(testAnchors)
+		t.Fail()
+	} else if !reflect.DeepEqual(testAudit, testExpected) {
+		fmt.Println("auditDocument returned an invalid audit")
+		fmt.Printf("%+v\n", testAudit)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for auditDocument passed")
+	}
+}
+
+func TestAuditDocument2(t *testing.T) {
+	testAudit := auditDocument(parseDocument(strings.NewReader(`<meta name="robots" content="NoIndex, nofollow">` +
+		`<meta name="googlebot" content="noindex"><h1>One</h1><h1>Two</h1><img src="/a.png"><img/>`)))
+	testIssues := []string{issueMissingTitle, issueMissingDescription, issueMultipleH1, issueMissingAlt, issueNoindex}
+	if !reflect.DeepEqual(testAudit.Issues, testIssues) || !reflect.DeepEqual(testAudit.Robots, []string{"noindex", "nofollow"}) ||
+		!reflect.DeepEqual(testAudit.MissingAlt, []string{"/a.png", ""}) {
+		fmt.Println("auditDocument returned invalid issues")
+		fmt.Printf("%+v\n", testAudit)
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for auditDocument passed")
+	}
+}
+
+func TestAuditSummary1(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`<title>Shop</title><meta name="description" content="Buy"><h1>Shop</h1><a href="/a">A</a><a href="/b">B</a>`))
+		case "/a":
+			_, _ = w.Write([]byte(`<title>Shop</title><meta name="description" content="Buy A"><h1>A</h1>`))
+		default:
+			_, _ = w.Write([]byte(`<title>B</title><h1>B</h1>`))
+		}
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.seoAudit = true
+	testCrawler := newCrawler(testConfig)
+	var testOutput bytes.Buffer
+	testCrawler.output = &testOutput
+	var testResults []pageResult
+	testCrawler.onPage = func(result pageResult) {
+		testResults = append(testResults, result)
+	}
+	testCrawler.config.threads = 1
+	testCrawler.Run(context.Background())
+	testSummary := testCrawler.auditSummary()
+	testExpected := []auditIssue{
+		{Issue: issueMissingDescription, Pages: []string{testServer.URL + "/b"}},
+		{Issue: issueDuplicateTitle, Value: "Shop", Pages: []string{testServer.URL, testServer.URL + "/a"}},
+	}
+	testOutput.Reset()
+	testCrawler.printAuditSummary()
+	if len(testResults) != 3 || testResults[0].SEO == nil || testResults[0].SEO.Title != "Shop" {
+		fmt.Println("The page results did not include the SEO audit")
+		t.Fail()
+	} else if testSummary.Pages != 3 || !reflect.DeepEqual(testSummary.Issues, testExpected) {
+		fmt.Println("auditSummary returned invalid issues")
+		fmt.Printf("%+v\n", testSummary)
+		t.Fail()
+	} else if !strings.Contains(testOutput.String(), "SEO Audit: 3 pages, 2 issues\n") ||
+		!strings.Contains(testOutput.String(), "\tduplicate title \"Shop\": 2\n") {
+		fmt.Println("printAuditSummary printed an invalid summary")
+		fmt.Println(testOutput.String())
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for auditSummary passed")
+	}
+}
+
+func TestAuditSummary2(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<p>Not found</p>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<title>Shop</title><meta name="description" content="Buy"><h1>Shop</h1><a href="/missing">A</a>`))
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.seoAudit = true
+	testCrawler := newCrawler(testConfig)
+	testCrawler.output = io.Discard
+	testAudits := map[string]*pageAudit{}
+	testCrawler.onPage = func(result pageResult) {
+		testAudits[strings.TrimPrefix(result.URI, testServer.URL)] = result.SEO
+	}
+	testCrawler.config.threads = 1
+	testCrawler.Run(context.Background())
+	testSummary := testCrawler.auditSummary()
+	if len(testAudits) != 2 || testAudits[""] == nil || testAudits["/missing"] != nil {
+		fmt.Println("The 404 page was audited")
+		fmt.Println(testAudits)
+		t.Fail()
+	} else if testSummary.Pages != 1 || len(testSummary.Issues) != 0 {
+		fmt.Println("auditSummary returned issues for the 404 page")
+		fmt.Printf("%+v\n", testSummary)
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for auditSummary passed")
+	}
+}
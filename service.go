@@ -0,0 +1,678 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/* jobRequest is the configuration of a crawl job submitted to the control API. Options that are not set
+	use the same defaults as the env variables
+ */
+
+type jobRequest struct {
+	URL                   string             `json:"url"`
+	Threads               int64              `json:"threads,omitempty"`
+	MaxPages              int64              `json:"max_pages,omitempty"`
+	MaxRedirects          *int               `json:"max_redirects,omitempty"`
+	RateLimit             float64            `json:"rate_limit,omitempty"`
+	AdaptiveConcurrency   bool               `json:"adaptive_concurrency,omitempty"`
+	StoreOnDisk           bool               `json:"store_on_disk,omitempty"`
+	RootPath              string             `json:"root_path,omitempty"`
+	HeadFirst             bool               `json:"head_first,omitempty"`
+	MaxBodySize           int64              `json:"max_body_size,omitempty"`
+	DownloadTypes         []string           `json:"download_types,omitempty"`
+	AcceptEncoding        string             `json:"accept_encoding,omitempty"`
+	StoreCompression      string             `json:"store_compression,omitempty"`
+	LinkCheck             bool               `json:"link_check,omitempty"`
+	CheckExternal         bool               `json:"check_external,omitempty"`
+	DetectDuplicates      bool               `json:"detect_duplicates,omitempty"`
+	NearDuplicateDistance *int               `json:"near_duplicate_distance,omitempty"`
+	SkipDuplicateLinks    bool               `json:"skip_duplicate_links,omitempty"`
+	Strategy              string             `json:"strategy,omitempty"`
+	ScorePatterns         map[string]float64 `json:"score_patterns,omitempty"`
+	SitemapWeight         float64            `json:"sitemap_weight,omitempty"`
+	InboundWeight         float64            `json:"inbound_weight,omitempty"`
+	LinkGraph             bool               `json:"link_graph,omitempty"`
+	Report                bool               `json:"report,omitempty"`
+	ReportMaxDepth        *int               `json:"report_max_depth,omitempty"`
+	SEOAudit              bool               `json:"seo_audit,omitempty"`
+	StructuredData        bool               `json:"structured_data,omitempty"`
+	ExtractRules          []extractRule      `json:"extract_rules,omitempty"`
+	Text                  bool               `json:"text,omitempty"`
+	Auth                  *authConfig        `json:"auth,omitempty"`
+	Session               *sessionConfig     `json:"session,omitempty"`
+	RenderPatterns        []string           `json:"render_patterns,omitempty"`
+}
+
+/* crawlJob is a crawl started through the control API
+	endState: Set to completed or cancelled once the crawl has finished
+	results: The pageResult of the last maxJobResults URIs fetched so far
+	dropped: The number of results removed from the start of results to keep it under maxJobResults
+	updated: Closed and replaced every time a result is added or the job finishes
+ */
+
+type crawlJob struct {
+	id       string
+	request  jobRequest
+	crawler  *Crawler
+	cancel   context.CancelFunc
+	created  time.Time
+	lock     sync.Mutex
+	finished time.Time
+	endState string
+	results  []pageResult
+	dropped  int
+	updated  chan struct{}
+}
+
+/* jobStatus is the representation of a crawlJob returned by the control API. The secrets of the auth and
+	session objects of the job are replaced with ***
+ */
+
+type jobStatus struct {
+	ID       string        `json:"id"`
+	State    string        `json:"state"`
+	Config   jobRequest    `json:"config"`
+	Created  time.Time     `json:"created"`
+	Finished *time.Time    `json:"finished,omitempty"`
+	Results  int           `json:"results"`
+	Stats    crawlSnapshot `json:"stats"`
+}
+
+/* jobSettings holds the settings of a crawl job that can be changed while it is running
+ */
+
+type jobSettings struct {
+	Threads   *int64   `json:"threads"`
+	RateLimit *float64 `json:"rate_limit"`
+	MaxPages  *int64   `json:"max_pages"`
+}
+
+/* jobManager holds the crawl jobs of the control API
+	jobsRoot: Set from JOBS_ROOT, the directory the root_path of the jobs storing their responses is created in.
+		Jobs can not store their responses on disk if it is not set
+ */
+
+type jobManager struct {
+	lock     sync.Mutex
+	counter  int64
+	jobs     map[string]*crawlJob
+	jobsRoot string
+}
+
+const (
+	jobRunning   = "running"
+	jobPaused    = "paused"
+	jobCancelled = "cancelled"
+	jobCompleted = "completed"
+)
+
+//The maximum number of results of a job kept in memory, the oldest results are removed first
+const maxJobResults = 10000
+
+//The maximum number of finished jobs kept, the oldest finished jobs are removed when a job is created
+const maxFinishedJobs = 100
+
+var errJobFinished = errors.New("job has already finished")
+
+var errJobRunning = errors.New("job has not finished, cancel it first")
+
+/*  The function serves the control API on the address set in the SERVE_ADDR env variable. The responses of
+	the jobs are stored in the JOBS_ROOT directory. If JOBS_ROOT is not a directory or the listener fails an error
+	message is generated and the program exits.
+	Arguments:
+		serveAddr: A string with the address to listen on like 127.0.0.1:8080
+ */
+
+func serveAPI(serveAddr string) {
+	manager := newJobManager()
+	if jobsRoot := os.Getenv("JOBS_ROOT"); jobsRoot != "" {
+		info, err := os.Stat(jobsRoot)
+		if err != nil || !info.IsDir() {
+			logger.Error("invalid directory for JOBS_ROOT env variable", "path", jobsRoot)
+			os.Exit(1)
+		}
+		manager.jobsRoot = jobsRoot
+	}
+	logger.Info("serving the control API", "address", serveAddr)
+	if err := http.ListenAndServe(serveAddr, manager.handler()); err != nil {
+		logger.Error("error while serving the control API", "address", serveAddr, "error", err)
+		os.Exit(1)
+	}
+}
+
+func newJobManager() *jobManager {
+	return &jobManager{jobs: map[string]*crawlJob{}}
+}
+
+/*  The function returns the http.Handler with the routes of the control API
+	Returns:
+		An http.Handler
+ */
+
+func (manager *jobManager) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", manager.createJob)
+	mux.HandleFunc("GET /jobs", manager.listJobs)
+	mux.HandleFunc("GET /jobs/{id}", manager.withJob(func(w http.ResponseWriter, r *http.Request, job *crawlJob) {
+		writeJSON(w, http.StatusOK, job.status())
+	}))
+	mux.HandleFunc("PATCH /jobs/{id}", manager.withJob(updateJob))
+	mux.HandleFunc("DELETE /jobs/{id}", manager.withJob(manager.deleteJob))
+	mux.HandleFunc("POST /jobs/{id}/pause", manager.withJob(func(w http.ResponseWriter, r *http.Request, job *crawlJob) {
+		controlJob(w, job, job.crawler.Pause)
+	}))
+	mux.HandleFunc("POST /jobs/{id}/resume", manager.withJob(func(w http.ResponseWriter, r *http.Request, job *crawlJob) {
+		controlJob(w, job, job.crawler.Resume)
+	}))
+	mux.HandleFunc("POST /jobs/{id}/cancel", manager.withJob(func(w http.ResponseWriter, r *http.Request, job *crawlJob) {
+		controlJob(w, job, job.cancel)
+	}))
+	mux.HandleFunc("GET /jobs/{id}/results", manager.withJob(streamResults))
+	mux.HandleFunc("GET /jobs/{id}/graph", manager.withJob(writeJobGraph))
+	mux.HandleFunc("GET /jobs/{id}/report", manager.withJob(writeJobReport))
+	mux.HandleFunc("GET /jobs/{id}/seo", manager.withJob(writeJobAudit))
+	return mux
+}
+
+/*  The function validates a jobRequest and converts it to the options of a crawl. The root_path of a job
+	storing its responses on disk is a relative path that is created in the jobsRoot directory
+	Arguments:
+		jobsRoot: The directory of JOBS_ROOT, empty if the jobs can not store their responses
+	Returns:
+		A crawlConfig with the options of the job
+		An error if an option is invalid
+ */
+
+func (request jobRequest) crawlConfig(jobsRoot string) (crawlConfig, error) {
+	if request.URL == "" {
+		return crawlConfig{}, errors.New("url is required")
+	}
+	crawlURI := checkValidBaseURL(request.URL)
+	crawlURL, err := url.Parse(crawlURI)
+	if crawlURI == "false" || err != nil {
+		return crawlConfig{}, errors.New("invalid url " + request.URL)
+	}
+	config := defaultCrawlConfig(crawlURL.String())
+	if request.Threads < 0 || request.MaxPages < 0 || request.MaxBodySize < 0 || request.RateLimit < 0 {
+		return config, errors.New("threads, max_pages, max_body_size and rate_limit can not be negative")
+	}
+	if request.Threads > 0 {
+		config.threads = request.Threads
+	}
+	config.maxPages = request.MaxPages
+	config.rateLimit = request.RateLimit
+	config.adaptive = request.AdaptiveConcurrency
+	config.seoAudit = request.SEOAudit
+	config.structured.enabled = request.StructuredData
+	config.text.enabled = request.Text
+	if request.Auth != nil {
+		if request.Auth.CookiesFile != "" {
+			return config, errors.New("auth: cookies_file can not be read by a job, set the cookies in session.cookies")
+		}
+		auth, err := newAuthConfig(*request.Auth)
+		if err != nil {
+			return config, errors.New("auth: " + err.Error())
+		}
+		config.auth = auth
+	}
+	if request.Session != nil {
+		session, err := newSessionConfig(*request.Session)
+		if err != nil {
+			return config, errors.New("session: " + err.Error())
+		}
+		config.session = session
+	}
+	if len(request.RenderPatterns) > 0 {
+		render, err := newRenderConfig(request.RenderPatterns, defaultRenderWait)
+		if err != nil {
+			return config, errors.New("render_patterns: " + err.Error())
+		}
+		config.render = render
+	}
+	if len(request.ExtractRules) > 0 {
+		rules, err := newExtractRules(request.ExtractRules)
+		if err != nil {
+			return config, err
+		}
+		config.extract = extractConfig{enabled: true, rules: rules}
+	}
+	if request.MaxRedirects != nil {
+		if *request.MaxRedirects < 0 {
+			return config, errors.New("max_redirects can not be negative")
+		}
+		config.maxRedirects = *request.MaxRedirects
+	}
+	if request.StoreOnDisk {
+		if jobsRoot == "" {
+			return config, errors.New("store_on_disk is not available, JOBS_ROOT is not set on the service")
+		}
+		if !filepath.IsLocal(request.RootPath) {
+			return config, errors.New("invalid root_path " + request.RootPath + ", it must be a relative path without ..")
+		}
+		rootPath := filepath.Join(jobsRoot, request.RootPath)
+		if err := os.MkdirAll(rootPath, 0755); err != nil {
+			return config, errors.New("invalid root_path " + request.RootPath)
+		}
+		config.writeOnDisk = true
+		config.rootPath = rootPath + string(filepath.Separator)
+	}
+	config.content.headFirst = request.HeadFirst
+	if request.MaxBodySize > 0 {
+		config.content.maxBodySize = request.MaxBodySize
+	}
+	if len(request.DownloadTypes) > 0 {
+		config.content.downloadTypes = parseDownloadTypes(request.DownloadTypes)
+	}
+	if config.compression, err = newCompressionConfig(request.AcceptEncoding, request.StoreCompression); err != nil {
+		return config, err
+	}
+	config.linkCheck = linkCheckConfig{enabled: request.LinkCheck, checkExternal: request.LinkCheck && request.CheckExternal}
+	if request.DetectDuplicates {
+		config.dedup.detect = true
+		config.dedup.skipLinks = request.SkipDuplicateLinks
+		if request.NearDuplicateDistance != nil {
+			if *request.NearDuplicateDistance > 64 {
+				return config, errors.New("near_duplicate_distance can not be greater than 64")
+			}
+			config.dedup.nearDistance = *request.NearDuplicateDistance
+		}
+	}
+	config.report.enabled = request.Report
+	config.graph.enabled = request.LinkGraph || request.Report
+	if request.ReportMaxDepth != nil {
+		if *request.ReportMaxDepth < 0 {
+			return config, errors.New("report_max_depth can not be negative")
+		}
+		config.report.maxDepth = *request.ReportMaxDepth
+	}
+	config.priority, err = newPriorityConfig(request.Strategy, request.ScorePatterns, request.SitemapWeight,
+		request.InboundWeight)
+	return config, err
+}
+
+/*  The function handles POST /jobs. The job is started right away and its status is returned. The oldest
+	finished jobs are removed once there are more than maxFinishedJobs
+ */
+
+func (manager *jobManager) createJob(w http.ResponseWriter, r *http.Request) {
+	var request jobRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid job: "+err.Error()))
+		return
+	}
+	config, err := request.crawlConfig(manager.jobsRoot)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &crawlJob{
+		request: request,
+		crawler: newCrawler(config),
+		cancel:  cancel,
+		created: time.Now(),
+		updated: make(chan struct{}),
+	}
+	job.crawler.output = io.Discard
+	job.crawler.onPage = job.addResult
+	manager.lock.Lock()
+	manager.counter++
+	job.id = strconv.FormatInt(manager.counter, 10)
+	manager.jobs[job.id] = job
+	manager.evictJobs()
+	manager.lock.Unlock()
+	logger.Info("crawl job started", "job", job.id, "uri", config.crawlURI)
+	go func() {
+		job.crawler.Run(ctx)
+		job.finish(ctx.Err() != nil)
+		cancel()
+		logger.Info("crawl job finished", "job", job.id, "state", job.state())
+	}()
+	writeJSON(w, http.StatusCreated, job.status())
+}
+
+/*  The function removes the oldest finished jobs so that at most maxFinishedJobs are kept. The lock of the
+	manager must be held
+ */
+
+func (manager *jobManager) evictJobs() {
+	var finished []*crawlJob
+	for _, job := range manager.jobs {
+		if job.done() {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		first, _ := strconv.Atoi(finished[i].id)
+		second, _ := strconv.Atoi(finished[j].id)
+		return first < second
+	})
+	for _, job := range finished[:len(finished)-maxFinishedJobs] {
+		delete(manager.jobs, job.id)
+		logger.Info("crawl job removed", "job", job.id)
+	}
+}
+
+/*  The function handles DELETE /jobs/{id} and removes a finished job with its results
+ */
+
+func (manager *jobManager) deleteJob(w http.ResponseWriter, r *http.Request, job *crawlJob) {
+	if !job.done() {
+		writeError(w, http.StatusConflict, errJobRunning)
+		return
+	}
+	manager.lock.Lock()
+	delete(manager.jobs, job.id)
+	manager.lock.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*  The function handles GET /jobs and returns the status of every job in the order they were created
+ */
+
+func (manager *jobManager) listJobs(w http.ResponseWriter, r *http.Request) {
+	manager.lock.Lock()
+	var jobs []*crawlJob
+	for _, job := range manager.jobs {
+		jobs = append(jobs, job)
+	}
+	manager.lock.Unlock()
+	sort.Slice(jobs, func(i, j int) bool {
+		first, _ := strconv.Atoi(jobs[i].id)
+		second, _ := strconv.Atoi(jobs[j].id)
+		return first < second
+	})
+	statuses := []jobStatus{}
+	for _, job := range jobs {
+		statuses = append(statuses, job.status())
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+/*  The function wraps a handler of a single job and replies with 404 if the job in the path does not exist
+	Arguments:
+		next: The handler called with the job
+	Returns:
+		An http.HandlerFunc
+ */
+
+func (manager *jobManager) withJob(next func(http.ResponseWriter, *http.Request, *crawlJob)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		manager.lock.Lock()
+		job, ok := manager.jobs[r.PathValue("id")]
+		manager.lock.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, errors.New("job "+r.PathValue("id")+" not found"))
+			return
+		}
+		next(w, r, job)
+	}
+}
+
+/*  The function pauses, resumes or cancels a job that has not finished and returns its status
+	Arguments:
+		job: The crawlJob to control
+		action: The function that changes the state of the crawl
+ */
+
+func controlJob(w http.ResponseWriter, job *crawlJob, action func()) {
+	if job.done() {
+		writeError(w, http.StatusConflict, errJobFinished)
+		return
+	}
+	action()
+	writeJSON(w, http.StatusOK, job.status())
+}
+
+/*  The function handles PATCH /jobs/{id} and changes the number of threads, the rate limit or the maximum number
+	of pages of a job that has not finished. Pausing the job first lets the settings be inspected and changed before
+	any other request is sent
+ */
+
+func updateJob(w http.ResponseWriter, r *http.Request, job *crawlJob) {
+	var settings jobSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid settings: "+err.Error()))
+		return
+	}
+	if (settings.RateLimit != nil && *settings.RateLimit < 0) || (settings.MaxPages != nil && *settings.MaxPages < 0) {
+		writeError(w, http.StatusBadRequest, errors.New("rate_limit and max_pages can not be negative"))
+		return
+	}
+	if settings.Threads != nil && *settings.Threads < 1 {
+		writeError(w, http.StatusBadRequest, errors.New("threads must be at least 1"))
+		return
+	}
+	controlJob(w, job, func() {
+		if settings.Threads != nil {
+			job.crawler.SetWorkers(*settings.Threads)
+		}
+		if settings.RateLimit != nil {
+			job.crawler.SetRateLimit(*settings.RateLimit)
+		}
+		if settings.MaxPages != nil {
+			job.crawler.SetMaxPages(*settings.MaxPages)
+		}
+	})
+}
+
+/*  The function handles GET /jobs/{id}/graph and writes the link graph recorded so far in the format of the
+	format query parameter: graphml (the default), dot or csv
+ */
+
+func writeJobGraph(w http.ResponseWriter, r *http.Request, job *crawlJob) {
+	if !job.crawler.config.graph.enabled {
+		writeError(w, http.StatusConflict, errors.New("link_graph was not enabled for the job"))
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "graphml"
+	}
+	contentTypes := map[string]string{"graphml": "application/xml", "dot": "text/vnd.graphviz", "csv": "text/csv"}
+	if _, ok := contentTypes[format]; !ok {
+		writeError(w, http.StatusBadRequest, errors.New("unsupported format "+format+", supported values are graphml, dot and csv"))
+		return
+	}
+	w.Header().Set("Content-Type", contentTypes[format])
+	_ = job.crawler.linkGraph().write(w, format)
+}
+
+/*  The function handles GET /jobs/{id}/report and writes the site report of the crawl so far in the format
+	of the format query parameter: markdown (the default) or html
+ */
+
+func writeJobReport(w http.ResponseWriter, r *http.Request, job *crawlJob) {
+	if !job.crawler.config.report.enabled {
+		writeError(w, http.StatusConflict, errors.New("report was not enabled for the job"))
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "markdown"
+	}
+	contentTypes := map[string]string{"markdown": "text/markdown; charset=utf-8", "html": "text/html; charset=utf-8"}
+	if _, ok := contentTypes[format]; !ok {
+		writeError(w, http.StatusBadRequest, errors.New("unsupported format "+format+", supported values are markdown and html"))
+		return
+	}
+	w.Header().Set("Content-Type", contentTypes[format])
+	_ = job.crawler.siteReport().write(w, format)
+}
+
+/*  The function handles GET /jobs/{id}/seo and returns the issues found by the SEO audit of the job so far
+ */
+
+func writeJobAudit(w http.ResponseWriter, r *http.Request, job *crawlJob) {
+	if !job.crawler.config.seoAudit {
+		writeError(w, http.StatusConflict, errors.New("seo_audit was not enabled for the job"))
+		return
+	}
+	writeJSON(w, http.StatusOK, job.crawler.auditSummary())
+}
+
+/*  The function handles GET /jobs/{id}/results and streams the results of the job as JSON lines.
+	The results fetched so far are written first and the stream follows the job until it finishes
+ */
+
+func streamResults(w http.ResponseWriter, r *http.Request, job *crawlJob) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	next := 0
+	for {
+		results, last, updated, done := job.resultsFrom(next)
+		for _, result := range results {
+			if err := encoder.Encode(result); err != nil {
+				return
+			}
+		}
+		next = last
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done {
+			return
+		}
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (job *crawlJob) addResult(result pageResult) {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	job.results = append(job.results, result)
+	if len(job.results) > maxJobResults {
+		job.results[0] = pageResult{}
+		job.results = job.results[1:]
+		job.dropped++
+	}
+	close(job.updated)
+	job.updated = make(chan struct{})
+}
+
+func (job *crawlJob) finish(cancelled bool) {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	job.finished = time.Now()
+	job.endState = jobCompleted
+	if cancelled {
+		job.endState = jobCancelled
+	}
+	close(job.updated)
+	job.updated = make(chan struct{})
+}
+
+func (job *crawlJob) done() bool {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	return !job.finished.IsZero()
+}
+
+/*  The function returns the results of the job starting at an index. The results that were removed to keep
+	the results of the job under maxJobResults are skipped
+	Arguments:
+		next: The index of the first result to return
+	Returns:
+		A slice of pageResults
+		The index of the result following the last result returned
+		A channel that is closed when the job is updated
+		A true value if the job has finished and no more results will be added
+ */
+
+func (job *crawlJob) resultsFrom(next int) ([]pageResult, int, chan struct{}, bool) {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	start := next - job.dropped
+	if start < 0 {
+		start = 0
+	}
+	return job.results[start:], job.dropped + len(job.results), job.updated, !job.finished.IsZero()
+}
+
+/*  The function returns the state of the job
+	Returns:
+		A string with one of running, paused, cancelled or completed
+ */
+
+func (job *crawlJob) state() string {
+	job.lock.Lock()
+	endState := job.endState
+	job.lock.Unlock()
+	if endState != "" {
+		return endState
+	}
+	if job.crawler.Paused() {
+		return jobPaused
+	}
+	return jobRunning
+}
+
+/*  The function returns a copy of the jobRequest without the secrets of its auth and session objects so that
+	it can be returned by the control API
+	Returns:
+		The redacted jobRequest
+ */
+
+func (request jobRequest) redacted() jobRequest {
+	if request.Auth != nil {
+		request.Auth = request.Auth.redacted()
+	}
+	if request.Session != nil {
+		request.Session = request.Session.redacted()
+	}
+	return request
+}
+
+func (job *crawlJob) status() jobStatus {
+	status := jobStatus{
+		ID:      job.id,
+		State:   job.state(),
+		Config:  job.request.redacted(),
+		Created: job.created,
+		Stats:   job.crawler.snapshot(),
+	}
+	job.lock.Lock()
+	status.Results = job.dropped + len(job.results)
+	if !job.finished.IsZero() {
+		finished := job.finished
+		status.Finished = &finished
+	}
+	job.lock.Unlock()
+	return status
+}
+
+/*  The function writes a value as a JSON response
+	Arguments:
+		w: The http.ResponseWriter of the request
+		statusCode: The status code of the response
+		value: The value to encode
+ */
+
+func writeJSON(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, map[string]string{"error": err.Error()})
+}
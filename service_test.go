@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func postTestJob(apiURL string, body string) (*http.Response, jobStatus) {
+	var status jobStatus
+	resp, err := http.Post(apiURL+"/jobs", "application/json", strings.NewReader(body))
+	if err != nil {
+		return nil, status
+	}
+	defer resp.Body.Close()
+	_ = json.NewDecoder(resp.Body).Decode(&status)
+	return resp, status
+}
+
+func TestJobAPI1(t *testing.T) {
+	testSite := newSiteTestServer()
+	defer testSite.Close()
+	testAPI := httptest.NewServer(newJobManager().handler())
+	defer testAPI.Close()
+	resp, testStatus := postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`", "threads": 2}`)
+	if resp == nil || resp.StatusCode != http.StatusCreated || testStatus.ID != "1" {
+		fmt.Println("POST /jobs did not create the job")
+		t.FailNow()
+	}
+	results, err := http.Get(testAPI.URL + "/jobs/1/results")
+	if err != nil {
+		fmt.Println("Error while streaming the results")
+		t.FailNow()
+	}
+	var testResults []pageResult
+	scanner := bufio.NewScanner(results.Body)
+	for scanner.Scan() {
+		var result pageResult
+		_ = json.Unmarshal(scanner.Bytes(), &result)
+		testResults = append(testResults, result)
+	}
+	results.Body.Close()
+	resp, _ = http.Get(testAPI.URL + "/jobs/1")
+	_ = json.NewDecoder(resp.Body).Decode(&testStatus)
+	resp.Body.Close()
+	if len(testResults) != 4 {
+		fmt.Println("The results stream did not return every page")
+		fmt.Println(testResults)
+		t.Fail()
+	} else if testStatus.State != jobCompleted || testStatus.Stats.Visited != 4 || testStatus.Results != 4 {
+		fmt.Println("GET /jobs/{id} returned an invalid status")
+		fmt.Println(testStatus)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for the job API passed")
+	}
+}
+
+func TestJobAPI2(t *testing.T) {
+	testAPI := httptest.NewServer(newJobManager().handler())
+	defer testAPI.Close()
+	testInvalidURL, _ := postTestJob(testAPI.URL, `{"url": "localhost"}`)
+	testInvalidOption, _ := postTestJob(testAPI.URL, `{"url": "https://test.com", "accept_encoding": "lzma"}`)
+	testInvalidRules, _ := postTestJob(testAPI.URL, `{"url": "https://test.com", "extract_rules": [{"name": "title", "fields": [{"name": "title", "css": "h1["}]}]}`)
+	testInvalidAuth, _ := postTestJob(testAPI.URL, `{"url": "https://test.com", "auth": {"login": {"url": "https://test.com/login"}}}`)
+	testMissing, _ := http.Post(testAPI.URL+"/jobs/5/pause", "application/json", nil)
+	testList, _ := http.Get(testAPI.URL + "/jobs")
+	var testJobs []jobStatus
+	_ = json.NewDecoder(testList.Body).Decode(&testJobs)
+	testList.Body.Close()
+	if testInvalidURL.StatusCode != http.StatusBadRequest || testInvalidOption.StatusCode != http.StatusBadRequest ||
+		testInvalidRules.StatusCode != http.StatusBadRequest || testInvalidAuth.StatusCode != http.StatusBadRequest {
+		fmt.Println("POST /jobs accepted an invalid job")
+		t.Fail()
+	} else if testMissing.StatusCode != http.StatusNotFound {
+		fmt.Println("The API did not return 404 for a missing job")
+		t.Fail()
+	} else if testJobs == nil || len(testJobs) != 0 {
+		fmt.Println("GET /jobs returned an invalid list")
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for the job API passed")
+	}
+}
+
+func TestJobAPI3(t *testing.T) {
+	testSite := newSiteTestServer()
+	defer testSite.Close()
+	testAPI := httptest.NewServer(newJobManager().handler())
+	defer testAPI.Close()
+	_, _ = postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`"}`)
+	resp, _ := http.Post(testAPI.URL+"/jobs/1/cancel", "application/json", nil)
+	resp.Body.Close()
+	results, _ := http.Get(testAPI.URL + "/jobs/1/results")
+	results.Body.Close()
+	var testStatus jobStatus
+	resp, _ = http.Get(testAPI.URL + "/jobs/1")
+	_ = json.NewDecoder(resp.Body).Decode(&testStatus)
+	resp.Body.Close()
+	testFinished, _ := http.Post(testAPI.URL+"/jobs/1/pause", "application/json", nil)
+	testFinished.Body.Close()
+	if testStatus.State != jobCancelled && testStatus.State != jobCompleted {
+		fmt.Println("The job did not finish after it was cancelled")
+		fmt.Println(testStatus)
+		t.Fail()
+	} else if testFinished.StatusCode != http.StatusConflict {
+		fmt.Println("The API paused a job that has already finished")
+		t.Fail()
+	} else {
+		fmt.Println("Test 3 for the job API passed")
+	}
+}
+
+func TestJobAPI4(t *testing.T) {
+	testSite := newSiteTestServer()
+	defer testSite.Close()
+	testAPI := httptest.NewServer(newJobManager().handler())
+	defer testAPI.Close()
+	_, _ = postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`", "rate_limit": 1}`)
+	resp, _ := http.Post(testAPI.URL+"/jobs/1/pause", "application/json", nil)
+	resp.Body.Close()
+	request, _ := http.NewRequest(http.MethodPatch, testAPI.URL+"/jobs/1", strings.NewReader(`{"threads": 2, "rate_limit": 0, "max_pages": 2}`))
+	resp, _ = http.DefaultClient.Do(request)
+	var testStatus jobStatus
+	_ = json.NewDecoder(resp.Body).Decode(&testStatus)
+	resp.Body.Close()
+	resp, _ = http.Post(testAPI.URL+"/jobs/1/resume", "application/json", nil)
+	resp.Body.Close()
+	results, _ := http.Get(testAPI.URL + "/jobs/1/results")
+	results.Body.Close()
+	if testStatus.State != jobPaused || testStatus.Stats.Threads != 2 || testStatus.Stats.RateLimit != 0 ||
+		testStatus.Stats.MaxPages != 2 {
+		fmt.Println("PATCH /jobs/{id} did not change the settings of the paused job")
+		fmt.Println(testStatus)
+		t.Fail()
+	} else {
+		fmt.Println("Test 4 for the job API passed")
+	}
+}
+
+func TestJobAPI5(t *testing.T) {
+	testSite := newSiteTestServer()
+	defer testSite.Close()
+	testAPI := httptest.NewServer(newJobManager().handler())
+	defer testAPI.Close()
+	_, _ = postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`", "link_graph": true}`)
+	_, _ = postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`"}`)
+	results, _ := http.Get(testAPI.URL + "/jobs/1/results")
+	_, _ = io.Copy(io.Discard, results.Body)
+	results.Body.Close()
+	resp, _ := http.Get(testAPI.URL + "/jobs/1/graph?format=csv")
+	testGraph, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	testDisabled, _ := http.Get(testAPI.URL + "/jobs/2/graph")
+	testDisabled.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/csv" || strings.Count(string(testGraph), "\n") != 5 {
+		fmt.Println("GET /jobs/{id}/graph did not return the link graph")
+		fmt.Println(string(testGraph))
+		t.Fail()
+	} else if testDisabled.StatusCode != http.StatusConflict {
+		fmt.Println("GET /jobs/{id}/graph returned a graph for a job without link_graph")
+		t.Fail()
+	} else {
+		fmt.Println("Test 5 for the job API passed")
+	}
+}
+
+func TestJobAPI6(t *testing.T) {
+	testSite := newSiteTestServer()
+	defer testSite.Close()
+	testAPI := httptest.NewServer(newJobManager().handler())
+	defer testAPI.Close()
+	_, _ = postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`", "report": true, "report_max_depth": 1}`)
+	testInvalid, _ := postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`", "report": true, "report_max_depth": -1}`)
+	results, _ := http.Get(testAPI.URL + "/jobs/1/results")
+	_, _ = io.Copy(io.Discard, results.Body)
+	results.Body.Close()
+	resp, _ := http.Get(testAPI.URL + "/jobs/1/report?format=html")
+	testReport, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	testFormat, _ := http.Get(testAPI.URL + "/jobs/1/report?format=pdf")
+	testFormat.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/html; charset=utf-8" || !strings.Contains(string(testReport), testSite.URL+"/missing") {
+		fmt.Println("GET /jobs/{id}/report did not return the site report")
+		fmt.Println(string(testReport))
+		t.Fail()
+	} else if testInvalid.StatusCode != http.StatusBadRequest || testFormat.StatusCode != http.StatusBadRequest {
+		fmt.Println("The job API accepted an invalid report option")
+		t.Fail()
+	} else {
+		fmt.Println("Test 6 for the job API passed")
+	}
+}
+
+func TestJobAPI7(t *testing.T) {
+	testSite := newSiteTestServer()
+	defer testSite.Close()
+	testAPI := httptest.NewServer(newJobManager().handler())
+	defer testAPI.Close()
+	_, _ = postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`", "seo_audit": true}`)
+	_, _ = postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`"}`)
+	results, _ := http.Get(testAPI.URL + "/jobs/1/results")
+	_, _ = io.Copy(io.Discard, results.Body)
+	results.Body.Close()
+	resp, _ := http.Get(testAPI.URL + "/jobs/1/seo")
+	var testSummary auditSummary
+	_ = json.NewDecoder(resp.Body).Decode(&testSummary)
+	resp.Body.Close()
+	testDisabled, _ := http.Get(testAPI.URL + "/jobs/2/seo")
+	testDisabled.Body.Close()
+	if testSummary.Pages != 3 || len(testSummary.Issues) != 3 || testSummary.Issues[0].Issue != issueMissingTitle {
+		fmt.Println("GET /jobs/{id}/seo did not return the SEO audit")
+		fmt.Println(testSummary)
+		t.Fail()
+	} else if testDisabled.StatusCode != http.StatusConflict {
+		fmt.Println("GET /jobs/{id}/seo returned an audit for a job without seo_audit")
+		t.Fail()
+	} else {
+		fmt.Println("Test 7 for the job API passed")
+	}
+}
+
+func TestJobAPI8(t *testing.T) {
+	testSite := newSiteTestServer()
+	defer testSite.Close()
+	testAPI := httptest.NewServer(newJobManager().handler())
+	defer testAPI.Close()
+	_, _ = postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`", "auth": {"hosts": {"*.test.com": {"username": "user",
+		"password": "secret-password", "headers": {"X-Api-Key": "secret-key"}}}, "login": {"url": "`+testSite.URL+`/login",
+		"fields": {"password": "secret-field"}}}, "session": {"headers": {"*": {"Authorization": "secret-token"}},
+		"cookies": {"https://test.com/": ["session=secret-cookie"]}}}`)
+	testCookiesFile, _ := postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`", "auth": {"cookies_file": "/etc/passwd"}}`)
+	resp, _ := http.Get(testAPI.URL + "/jobs")
+	testList, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.Contains(string(testList), "secret") || !strings.Contains(string(testList), `"password":"***"`) ||
+		!strings.Contains(string(testList), `"username":"user"`) {
+		fmt.Println("GET /jobs returned the secrets of the auth and session objects")
+		fmt.Println(string(testList))
+		t.Fail()
+	} else if testCookiesFile.StatusCode != http.StatusBadRequest {
+		fmt.Println("POST /jobs accepted a cookies file")
+		t.Fail()
+	} else {
+		fmt.Println("Test 8 for the job API passed")
+	}
+}
+
+func TestJobAPI9(t *testing.T) {
+	testSite := newSiteTestServer()
+	defer testSite.Close()
+	testManager := newJobManager()
+	testAPI := httptest.NewServer(testManager.handler())
+	defer testAPI.Close()
+	testNoRoot, _ := postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`", "store_on_disk": true, "root_path": "crawl"}`)
+	testManager.jobsRoot = t.TempDir()
+	testAbsolute, _ := postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`", "store_on_disk": true, "root_path": "/tmp"}`)
+	testParent, _ := postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`", "store_on_disk": true, "root_path": "crawl/../.."}`)
+	testStored, testStatus := postTestJob(testAPI.URL, `{"url": "`+testSite.URL+`", "store_on_disk": true, "root_path": "crawl"}`)
+	results, _ := http.Get(testAPI.URL + "/jobs/" + testStatus.ID + "/results")
+	_, _ = io.Copy(io.Discard, results.Body)
+	results.Body.Close()
+	_, testManifestErr := os.Stat(filepath.Join(testManager.jobsRoot, "crawl", "manifest.jsonl"))
+	testRequest, _ := http.NewRequest(http.MethodDelete, testAPI.URL+"/jobs/"+testStatus.ID, nil)
+	testDelete, _ := http.DefaultClient.Do(testRequest)
+	testDelete.Body.Close()
+	testDeleted, _ := http.Get(testAPI.URL + "/jobs/" + testStatus.ID)
+	testDeleted.Body.Close()
+	if testNoRoot.StatusCode != http.StatusBadRequest || testAbsolute.StatusCode != http.StatusBadRequest ||
+		testParent.StatusCode != http.StatusBadRequest {
+		fmt.Println("POST /jobs accepted a root_path outside of the jobs root")
+		t.Fail()
+	} else if testStored.StatusCode != http.StatusCreated || testManifestErr != nil {
+		fmt.Println("The job did not store its responses in the jobs root")
+		fmt.Println(testManifestErr)
+		t.Fail()
+	} else if testDelete.StatusCode != http.StatusNoContent || testDeleted.StatusCode != http.StatusNotFound {
+		fmt.Println("DELETE /jobs/{id} did not remove the finished job")
+		t.Fail()
+	} else {
+		fmt.Println("Test 9 for the job API passed")
+	}
+}
+
+func TestJobResults1(t *testing.T) {
+	testJob := &crawlJob{updated: make(chan struct{})}
+	for i := 0; i <= maxJobResults; i++ {
+		testJob.addResult(pageResult{Depth: i})
+	}
+	testResults, testNext, _, _ := testJob.resultsFrom(0)
+	testLater, testLast, _, _ := testJob.resultsFrom(maxJobResults)
+	if len(testResults) != maxJobResults || testResults[0].Depth != 1 || testNext != maxJobResults+1 {
+		fmt.Println("The results of the job were not capped")
+		fmt.Println(len(testResults), testNext)
+		t.Fail()
+	} else if len(testLater) != 1 || testLater[0].Depth != maxJobResults || testLast != maxJobResults+1 {
+		fmt.Println("resultsFrom returned invalid results after the removed results")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for the job results passed")
+	}
+}
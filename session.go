@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+	"golang.org/x/net/publicsuffix"
+)
+
+/* sessionConfig holds the static headers and the cookies of the crawl, read from the SESSION_CONFIG file or
+	the session object of a job
+	Headers: The headers sent to every host. The * key holds the headers of every host, a key starting with *.
+		matches the subdomains of the domain that follows it and the headers of a more specific key replace
+		the headers of the same name of a less specific one
+	Cookies: The Set-Cookie values loaded into the cookie jar before the crawl by the uri they are set from
+	cookies: The cookies parsed from Cookies
+ */
+
+type sessionConfig struct {
+	Headers map[string]map[string]string `json:"headers,omitempty"`
+	Cookies map[string][]string          `json:"cookies,omitempty"`
+	cookies []seedCookie
+}
+
+/* cookieJar is the cookie jar of a Crawler. The http.CookieJar interface only returns the names and values of
+	the cookies, the jar also keeps the cookies with their attributes so that they can be set in the browser
+	cookies: The browserCookies set in the jar by domain, path and name
+ */
+
+type cookieJar struct {
+	*cookiejar.Jar
+	lock    sync.Mutex
+	cookies map[string]browserCookie
+}
+
+/* browserCookie is a cookie with the attributes it was set with, in the format of the Network.setCookies
+	command of the DevTools Protocol. A cookie without a Domain attribute is only sent to the host of its URL
+ */
+
+type browserCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	URL      string  `json:"url,omitempty"`
+	Domain   string  `json:"domain,omitempty"`
+	Path     string  `json:"path"`
+	Secure   bool    `json:"secure,omitempty"`
+	HTTPOnly bool    `json:"httpOnly,omitempty"`
+	SameSite string  `json:"sameSite,omitempty"`
+	Expires  float64 `json:"expires,omitempty"`
+}
+
+/* headerTransport adds the static headers of the host of every request
+ */
+
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]map[string]string
+}
+
+/*  The function reads the file of the SESSION_CONFIG env variable. If the file can not be read or is invalid
+	an error message is generated and the program exits.
+	Returns:
+		A sessionConfig with the headers and cookies set by the user
+ */
+
+func getSessionConfig() sessionConfig {
+	var config sessionConfig
+	if os.Getenv("SESSION_CONFIG") == "" {
+		return config
+	}
+	data, err := os.ReadFile(os.Getenv("SESSION_CONFIG"))
+	if err == nil {
+		err = json.Unmarshal(data, &config)
+	}
+	if err == nil {
+		config, err = newSessionConfig(config)
+	}
+	if err != nil {
+		logger.Error("invalid session file for SESSION_CONFIG env variable", "path", os.Getenv("SESSION_CONFIG"), "error", err)
+		os.Exit(1)
+	}
+	return config
+}
+
+/*  The function validates the headers and parses the cookies of a sessionConfig
+	Arguments:
+		config: The sessionConfig read from the session file or the job request
+	Returns:
+		The sessionConfig ready to be used by a Crawler
+		An error naming the first invalid header or cookie
+ */
+
+func newSessionConfig(config sessionConfig) (sessionConfig, error) {
+	for host, headers := range config.Headers {
+		if host != "*" && strings.TrimPrefix(host, "*.") == "" {
+			return config, errors.New("empty host in headers")
+		}
+		for name, value := range headers {
+			if !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+				return config, errors.New("host " + host + ": invalid header " + name)
+			}
+		}
+	}
+	config.cookies = nil
+	for uri, values := range config.Cookies {
+		cookieURL, err := url.Parse(uri)
+		if err != nil || (cookieURL.Scheme != "http" && cookieURL.Scheme != "https") || cookieURL.Host == "" {
+			return config, errors.New("invalid cookie url " + uri)
+		}
+		cookies := (&http.Response{Header: http.Header{"Set-Cookie": values}}).Cookies()
+		if len(cookies) != len(values) {
+			return config, errors.New("invalid cookie for " + uri)
+		}
+		for _, cookie := range cookies {
+			config.cookies = append(config.cookies, seedCookie{uri: cookieURL, cookie: cookie})
+		}
+	}
+	return config, nil
+}
+
+/*  The function returns a copy of the sessionConfig that can be shown in the status of a job. The header
+	values and the cookies are replaced with *** as they can hold API keys and session ids
+	Returns:
+		A pointer to the redacted sessionConfig
+ */
+
+func (config sessionConfig) redacted() *sessionConfig {
+	var redacted sessionConfig
+	if config.Headers != nil {
+		redacted.Headers = map[string]map[string]string{}
+	}
+	for host, headers := range config.Headers {
+		redacted.Headers[host] = redactValues(headers)
+	}
+	if config.Cookies != nil {
+		redacted.Cookies = map[string][]string{}
+	}
+	for uri, values := range config.Cookies {
+		hidden := make([]string, len(values))
+		for i := range values {
+			hidden[i] = redactedValue
+		}
+		redacted.Cookies[uri] = hidden
+	}
+	return &redacted
+}
+
+/*  The function creates the cookie jar shared by the requests of a Crawler. The jar follows the domain, path
+	and expiry rules of the cookies and uses the public suffix list so that a site can not set cookies for a
+	whole top level domain such as co.uk
+	Arguments:
+		seeds: The cookies loaded into the jar
+	Returns:
+		A pointer to the cookieJar
+ */
+
+func newCookieJar(seeds []seedCookie) *cookieJar {
+	base, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	jar := &cookieJar{Jar: base, cookies: map[string]browserCookie{}}
+	for _, seed := range seeds {
+		jar.SetCookies(seed.uri, []*http.Cookie{seed.cookie})
+	}
+	return jar
+}
+
+/*  The function sets the cookies of a response in the jar and keeps their attributes. The cookies the jar
+	rejects are kept too, they are never returned by browserCookies as the jar does not return them
+	Arguments:
+		u: The url of the response
+		cookies: The cookies of the response
+ */
+
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.Jar.SetCookies(u, cookies)
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	for _, cookie := range cookies {
+		stored := browserCookie{Name: cookie.Name, Value: cookie.Value, Path: cookie.Path, Secure: cookie.Secure,
+			HTTPOnly: cookie.HttpOnly}
+		if stored.Path == "" || !strings.HasPrefix(stored.Path, "/") {
+			stored.Path = defaultCookiePath(u.Path)
+		}
+		if cookie.Domain == "" {
+			stored.URL = u.Scheme + "://" + u.Host + stored.Path
+		} else {
+			stored.Domain = "." + strings.TrimPrefix(strings.ToLower(cookie.Domain), ".")
+		}
+		switch cookie.SameSite {
+		case http.SameSiteLaxMode:
+			stored.SameSite = "Lax"
+		case http.SameSiteStrictMode:
+			stored.SameSite = "Strict"
+		case http.SameSiteNoneMode:
+			stored.SameSite = "None"
+		}
+		if cookie.MaxAge > 0 {
+			stored.Expires = float64(time.Now().Add(time.Duration(cookie.MaxAge) * time.Second).Unix())
+		} else if !cookie.Expires.IsZero() {
+			stored.Expires = float64(cookie.Expires.Unix())
+		}
+		j.cookies[stored.URL+stored.Domain+";"+stored.Path+";"+stored.Name] = stored
+	}
+}
+
+/*  The function returns the cookies the jar sends to a url with the attributes they were set with
+	Arguments:
+		u: The url of the page
+	Returns:
+		The browserCookies of the url sorted by domain, path and name
+ */
+
+func (j *cookieJar) browserCookies(u *url.URL) []browserCookie {
+	sent := map[string]bool{}
+	for _, cookie := range j.Jar.Cookies(u) {
+		sent[cookie.Name+"="+cookie.Value] = true
+	}
+	host := strings.ToLower(u.Hostname())
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	keys := make([]string, 0, len(j.cookies))
+	for key := range j.cookies {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var cookies []browserCookie
+	for _, key := range keys {
+		cookie := j.cookies[key]
+		if !sent[cookie.Name+"="+cookie.Value] {
+			continue
+		}
+		if cookie.URL != "" {
+			cookieURL, err := url.Parse(cookie.URL)
+			if err != nil || strings.ToLower(cookieURL.Hostname()) != host {
+				continue
+			}
+		} else if host != cookie.Domain[1:] && !strings.HasSuffix(host, cookie.Domain) {
+			continue
+		}
+		if cookie.Path != "/" && u.Path != cookie.Path && !strings.HasPrefix(u.Path, strings.TrimSuffix(cookie.Path, "/")+"/") {
+			continue
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
+
+/*  The function returns the path of a cookie set without a Path attribute, the directory of the path of the
+	url that set it
+	Arguments:
+		path: The path of the url
+	Returns:
+		A string with the path of the cookie
+ */
+
+func defaultCookiePath(path string) string {
+	if path == "" || path[0] != '/' {
+		return "/"
+	}
+	index := strings.LastIndex(path, "/")
+	if index == 0 {
+		return "/"
+	}
+	return path[:index]
+}
+
+/*  The function returns the static headers of a host
+	Arguments:
+		headers: The headers by host
+		host: The hostname of the request
+	Returns:
+		The headers of the * key, the *. domains of the host from the shortest to the longest and the host
+ */
+
+func hostHeaders(headers map[string]map[string]string, host string) map[string]string {
+	keys := []string{"*"}
+	labels := strings.Split(host, ".")
+	for i := len(labels) - 1; i > 0; i-- {
+		keys = append(keys, "*."+strings.Join(labels[i:], "."))
+	}
+	keys = append(keys, host)
+	merged := map[string]string{}
+	for _, key := range keys {
+		for name, value := range headers[key] {
+			merged[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+	return merged
+}
+
+/*  The function adds the static headers of the host to a request. The headers already set on the request,
+	e.g. by a requestHook, are kept
+	Arguments:
+		req: The request
+	Returns:
+		The response of the base http.RoundTripper
+ */
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers := hostHeaders(t.headers, req.URL.Hostname())
+	if len(headers) == 0 {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for name, value := range headers {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, value)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewSessionConfig1(t *testing.T) {
+	testCases := map[string]sessionConfig{
+		"host *: invalid header Accept Language": {Headers: map[string]map[string]string{"*": {"Accept Language": "en"}}},
+		"empty host in headers":                  {Headers: map[string]map[string]string{"*.": {"X-Client": "crawler"}}},
+		"invalid cookie url test.com":            {Cookies: map[string][]string{"test.com": {"consent=yes"}}},
+		"invalid cookie for https://test.com/":   {Cookies: map[string][]string{"https://test.com/": {"consent"}}},
+	}
+	for testExpected, testConfig := range testCases {
+		if _, err := newSessionConfig(testConfig); err == nil || err.Error() != testExpected {
+			fmt.Println("newSessionConfig did not return " + testExpected)
+			fmt.Println(err)
+			t.Fail()
+			return
+		}
+	}
+	testHeaders := hostHeaders(map[string]map[string]string{
+		"*":             {"accept-language": "en", "X-Client": "crawler"},
+		"*.test.com":    {"Accept-Language": "de"},
+		"shop.test.com": {"X-Client": "shop"},
+		"test.com":      {"X-Client": "root"},
+	}, "shop.test.com")
+	testConfig, _ := newSessionConfig(sessionConfig{Cookies: map[string][]string{
+		"https://shop.co.uk/": {"tracker=1; Domain=co.uk", "cart=2; Domain=shop.co.uk"},
+	}})
+	testJar := newCookieJar(testConfig.cookies)
+	testOther, _ := url.Parse("https://other.co.uk/")
+	testShop, _ := url.Parse("https://www.shop.co.uk/")
+	if !reflect.DeepEqual(testHeaders, map[string]string{"Accept-Language": "de", "X-Client": "shop"}) {
+		fmt.Println("hostHeaders returned invalid headers")
+		fmt.Println(testHeaders)
+		t.Fail()
+	} else if len(testJar.Cookies(testOther)) != 0 || len(testJar.Cookies(testShop)) != 1 {
+		fmt.Println("The cookie jar accepted a cookie for a public suffix")
+		fmt.Println(testJar.Cookies(testOther), testJar.Cookies(testShop))
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for newSessionConfig passed")
+	}
+}
+
+func TestCookieJar1(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "ok", Path: "/"})
+		http.SetCookie(w, &http.Cookie{Name: "old", Value: "expired", Path: "/", MaxAge: -1})
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<a href="/a">A</a>`))
+	})
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		session, sessionErr := r.Cookie("session")
+		consent, consentErr := r.Cookie("consent")
+		_, oldErr := r.Cookie("old")
+		if sessionErr != nil || session.Value != "ok" || consentErr != nil || consent.Value != "yes" ||
+			oldErr == nil || r.Header.Get("Accept-Language") != "de-DE" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.session, _ = newSessionConfig(sessionConfig{
+		Headers: map[string]map[string]string{"*": {"Accept-Language": "de-DE"}},
+		Cookies: map[string][]string{testServer.URL + "/": {"consent=yes; Path=/", "old=seeded; Path=/"}},
+	})
+	testCrawler := newCrawler(testConfig)
+	testCrawler.output = io.Discard
+	var testLock sync.Mutex
+	testCodes := map[string]int{}
+	testCrawler.onPage = func(page pageResult) {
+		testLock.Lock()
+		defer testLock.Unlock()
+		testCodes[strings.TrimPrefix(page.URI, testServer.URL)] = page.StatusCode
+	}
+	testCrawler.config.threads = 1
+	testCrawler.Run(context.Background())
+	if testCodes["/a"] != 200 {
+		fmt.Println("The cookies and headers of the session were not sent")
+		fmt.Println(testCodes)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for the cookie jar passed")
+	}
+}
+
+func TestBrowserCookies1(t *testing.T) {
+	testLogin, _ := url.Parse("https://test.com/app/login")
+	testJar := newCookieJar(nil)
+	testJar.SetCookies(testLogin, (&http.Response{Header: http.Header{"Set-Cookie": {
+		"session=1",
+		"consent=all; Domain=test.com; Path=/; Secure; HttpOnly; SameSite=Lax",
+		"old=1; Path=/; Max-Age=0",
+	}}}).Cookies())
+	testHome, _ := url.Parse("https://test.com/app/home")
+	testSubdomain, _ := url.Parse("https://www.test.com/")
+	testHomeCookies := testJar.browserCookies(testHome)
+	testSubdomainCookies := testJar.browserCookies(testSubdomain)
+	if !reflect.DeepEqual(testHomeCookies, []browserCookie{
+		{Name: "consent", Value: "all", Domain: ".test.com", Path: "/", Secure: true, HTTPOnly: true, SameSite: "Lax"},
+		{Name: "session", Value: "1", URL: "https://test.com/app", Path: "/app"},
+	}) {
+		fmt.Println("browserCookies did not return the cookies of the page with their attributes")
+		fmt.Println(testHomeCookies)
+		t.Fail()
+	} else if len(testSubdomainCookies) != 1 || testSubdomainCookies[0].Name != "consent" {
+		fmt.Println("browserCookies returned a host only cookie for another host")
+		fmt.Println(testSubdomainCookies)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for browserCookies passed")
+	}
+}
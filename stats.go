@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/* crawlStatistics holds the statistics collected for every fetched uri
+	statusCodes: The number of responses for every status code
+	contentTypes: The number of responses for every media type
+	latencies: A uniform sample of at most maxLatencySamples of the times taken to fetch the URIs, kept with
+		reservoir sampling so that the memory used by a long crawl does not grow with the number of URIs
+	latencyCount: The number of fetch times added to the sample
+	maxLatency: The longest time taken to fetch a uri
+	errors: The number of URIs that could not be fetched
+	wireBytes: The number of bytes received over the network
+	decodedBytes: The number of bytes of the bodies after they were decoded
+ */
+
+type crawlStatistics struct {
+	lock         sync.Mutex
+	start        time.Time
+	statusCodes  map[int]int64
+	contentTypes map[string]int64
+	latencies    []time.Duration
+	latencyCount int64
+	maxLatency   time.Duration
+	random       *rand.Rand
+	errors       int64
+	wireBytes    int64
+	decodedBytes int64
+}
+
+func newCrawlStatistics() *crawlStatistics {
+	return &crawlStatistics{
+		start:        time.Now(),
+		statusCodes:  map[int]int64{},
+		contentTypes: map[string]int64{},
+		random:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+//The maximum number of fetch times kept to compute the latency percentiles
+const maxLatencySamples = 10000
+
+/*  The function checks the value of the env variable MAX_PAGES if the value specified in the env variable
+	is invalid an error message is generated and the program exits.
+	Defaults to 0 which crawls every page found
+	Returns:
+		An int64 with the maximum number of pages to crawl
+ */
+
+func getMaxPages() int64 {
+	if os.Getenv("MAX_PAGES") == "" {
+		return 0
+	}
+	pages, err := strconv.ParseInt(os.Getenv("MAX_PAGES"), 10, 64)
+	if err != nil || pages < 0 {
+		logger.Error("invalid value for MAX_PAGES env variable", "value", os.Getenv("MAX_PAGES"))
+		os.Exit(1)
+	}
+	return pages
+}
+
+/*  The function checks the value of the env variable PROGRESS_INTERVAL which is a duration like 5s or 1m
+	If the value specified in the env variable is invalid an error message is generated and the program exits.
+	Defaults to 0 which does not print any progress
+	Returns:
+		A time.Duration with the interval at which progress is printed
+ */
+
+func getProgressInterval() time.Duration {
+	if os.Getenv("PROGRESS_INTERVAL") == "" {
+		return 0
+	}
+	interval, err := time.ParseDuration(os.Getenv("PROGRESS_INTERVAL"))
+	if err != nil || interval < 0 {
+		logger.Error("invalid value for PROGRESS_INTERVAL env variable", "value", os.Getenv("PROGRESS_INTERVAL"))
+		os.Exit(1)
+	}
+	return interval
+}
+
+/*  The function reserves a place in the crawl for a new uri. Once MAX_PAGES URIs have been inserted no
+	more URIs are accepted
+	Returns:
+		A true value if the uri can be inserted into the channel
+ */
+
+func (c *Crawler) reserveInsert() bool {
+	for {
+		current := atomic.LoadInt64(&c.insertCounter)
+		if maxPages := atomic.LoadInt64(&c.config.maxPages); maxPages > 0 && current >= maxPages {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&c.insertCounter, current, current+1) {
+			frontierSize.Inc()
+			return true
+		}
+	}
+}
+
+/*  The function adds the fetchResult of a uri to the crawl statistics
+	Arguments:
+		result: The fetchResult of the uri
+ */
+
+func (s *crawlStatistics) record(result fetchResult) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.latencyCount++
+	if result.duration > s.maxLatency {
+		s.maxLatency = result.duration
+	}
+	if len(s.latencies) < maxLatencySamples {
+		s.latencies = append(s.latencies, result.duration)
+	} else if i := s.random.Int63n(s.latencyCount); i < maxLatencySamples {
+		s.latencies[i] = result.duration
+	}
+	s.wireBytes += result.wireSize
+	s.decodedBytes += result.size
+	if result.err != nil {
+		s.errors++
+		return
+	}
+	s.statusCodes[result.statusCode]++
+	contentType := result.contentType
+	if contentType == "" {
+		contentType = "unknown"
+	}
+	s.contentTypes[contentType]++
+}
+
+/*  The function logs a single progress record with the number of pages fetched and queued,
+	the errors, the crawl rate, the bytes downloaded and the estimated time left if MAX_PAGES is set
+ */
+
+func (c *Crawler) printProgress() {
+	c.stats.lock.Lock()
+	errors, wireBytes := c.stats.errors, c.stats.wireBytes
+	c.stats.lock.Unlock()
+	fetched := atomic.LoadInt64(&c.visitedCounter)
+	queued := atomic.LoadInt64(&c.insertCounter) - atomic.LoadInt64(&c.processedCounter)
+	elapsed := time.Since(c.stats.start)
+	rate := float64(fetched) / elapsed.Seconds()
+	fields := []any{"fetched", fetched, "queued", queued, "errors", errors,
+		"pages_per_second", fmt.Sprintf("%.1f", rate), "downloaded", formatBytes(wireBytes)}
+	if maxPages := atomic.LoadInt64(&c.config.maxPages); maxPages > 0 && rate > 0 {
+		remaining := time.Duration(float64(maxPages-fetched) / rate * float64(time.Second))
+		fields = append(fields, "eta", remaining.Round(time.Second).String())
+	}
+	logger.Info("progress", fields...)
+}
+
+/*  The function logs the progress at every interval until the returned function is called
+	Arguments:
+		interval: A time.Duration with the interval at which progress is printed
+	Returns:
+		A function that stops printing the progress
+ */
+
+func (c *Crawler) startProgress(interval time.Duration) func() {
+	if interval <= 0 {
+		return func() {}
+	}
+	ticker := time.NewTicker(interval)
+	stop := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				c.printProgress()
+			case <-stop:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
+
+/*  The function prints the summary of the crawl with the status code histogram, the number of responses
+	per content type and the latency percentiles of the fetched URIs, computed from the sample of the
+	fetch times once the crawl has fetched more than maxLatencySamples URIs
+ */
+
+func (c *Crawler) printSummary() {
+	s := c.stats
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	elapsed := time.Since(s.start)
+	visited := atomic.LoadInt64(&c.visitedCounter)
+	_, _ = fmt.Fprintln(c.output, "Total Visited URIs: "+strconv.FormatInt(visited, 10))
+	_, _ = fmt.Fprintln(c.output, "Errors: "+strconv.FormatInt(s.errors, 10))
+	if redirects := atomic.LoadInt64(&c.redirectCounter); redirects > 0 {
+		_, _ = fmt.Fprintln(c.output, "Redirected URIs: "+strconv.FormatInt(redirects, 10))
+	}
+	if c.config.dedup.detect {
+		_, _ = fmt.Fprintln(c.output, "Duplicate Pages: "+strconv.FormatInt(atomic.LoadInt64(&c.duplicateCounter), 10))
+	}
+	if c.config.extract.enabled {
+		_, _ = fmt.Fprintln(c.output, "Extracted Records: "+strconv.FormatInt(atomic.LoadInt64(&c.recordCounter), 10))
+	}
+	if c.config.render.enabled {
+		_, _ = fmt.Fprintln(c.output, "Rendered Pages: "+strconv.FormatInt(atomic.LoadInt64(&c.renderedCounter), 10))
+	}
+	if c.config.text.enabled {
+		_, _ = fmt.Fprintln(c.output, "Text Pages: "+strconv.FormatInt(atomic.LoadInt64(&c.textCounter), 10))
+	}
+	if c.config.replay.path != "" {
+		_, _ = fmt.Fprintln(c.output, "Not In Stored Crawl: "+strconv.FormatInt(atomic.LoadInt64(&c.notStoredCounter), 10))
+	}
+	_, _ = fmt.Fprintf(c.output, "Downloaded: %s (%s decoded)\n", formatBytes(s.wireBytes), formatBytes(s.decodedBytes))
+	_, _ = fmt.Fprintf(c.output, "Elapsed: %s (%.1f pages/s)\n", elapsed.Round(time.Millisecond), float64(visited)/elapsed.Seconds())
+	var codes []int
+	for code := range s.statusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	_, _ = fmt.Fprintln(c.output, "Status Codes:")
+	for _, code := range codes {
+		_, _ = fmt.Fprintln(c.output, "\t"+strconv.Itoa(code)+": "+strconv.FormatInt(s.statusCodes[code], 10))
+	}
+	var contentTypes []string
+	for contentType := range s.contentTypes {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Strings(contentTypes)
+	_, _ = fmt.Fprintln(c.output, "Content Types:")
+	for _, contentType := range contentTypes {
+		_, _ = fmt.Fprintln(c.output, "\t"+contentType+": "+strconv.FormatInt(s.contentTypes[contentType], 10))
+	}
+	if len(s.latencies) > 0 {
+		latencies := append([]time.Duration(nil), s.latencies...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		_, _ = fmt.Fprintf(c.output, "Latency: p50 %s, p90 %s, p99 %s, max %s\n",
+			percentile(latencies, 50).Round(time.Millisecond), percentile(latencies, 90).Round(time.Millisecond),
+			percentile(latencies, 99).Round(time.Millisecond), s.maxLatency.Round(time.Millisecond))
+	}
+}
+
+/* crawlSnapshot is the state of a crawl at a point in time
+ */
+
+type crawlSnapshot struct {
+	Paused       bool             `json:"paused"`
+	Threads      int64            `json:"threads"`
+	RateLimit    float64          `json:"rate_limit"`
+	MaxPages     int64            `json:"max_pages"`
+	Active       int64            `json:"active"`
+	Visited      int64            `json:"visited"`
+	Queued       int64            `json:"queued"`
+	Errors       int64            `json:"errors"`
+	Redirected   int64            `json:"redirected"`
+	Duplicates   int64            `json:"duplicates"`
+	WireBytes    int64            `json:"wire_bytes"`
+	DecodedBytes int64            `json:"decoded_bytes"`
+	Elapsed      float64          `json:"elapsed_seconds"`
+	StatusCodes  map[int]int64    `json:"status_codes"`
+	ContentTypes map[string]int64 `json:"content_types"`
+}
+
+/*  The function returns the settings of the crawl and the counters and statistics collected so far
+	Returns:
+		A crawlSnapshot of the crawl
+ */
+
+func (c *Crawler) snapshot() crawlSnapshot {
+	c.stats.lock.Lock()
+	defer c.stats.lock.Unlock()
+	snapshot := crawlSnapshot{
+		Paused:       c.Paused(),
+		Threads:      c.workers(),
+		RateLimit:    c.limiter.rate(),
+		MaxPages:     atomic.LoadInt64(&c.config.maxPages),
+		Active:       atomic.LoadInt64(&c.activeCounter),
+		Visited:      atomic.LoadInt64(&c.visitedCounter),
+		Queued:       atomic.LoadInt64(&c.insertCounter) - atomic.LoadInt64(&c.processedCounter),
+		Errors:       c.stats.errors,
+		Redirected:   atomic.LoadInt64(&c.redirectCounter),
+		Duplicates:   atomic.LoadInt64(&c.duplicateCounter),
+		WireBytes:    c.stats.wireBytes,
+		DecodedBytes: c.stats.decodedBytes,
+		Elapsed:      time.Since(c.stats.start).Seconds(),
+		StatusCodes:  map[int]int64{},
+		ContentTypes: map[string]int64{},
+	}
+	for code, count := range c.stats.statusCodes {
+		snapshot.StatusCodes[code] = count
+	}
+	for contentType, count := range c.stats.contentTypes {
+		snapshot.ContentTypes[contentType] = count
+	}
+	return snapshot
+}
+
+/*  The function returns the percentile of a sorted slice of durations using the nearest rank method
+	Arguments:
+		sorted: A sorted slice of durations
+		p: The percentile between 0 and 100
+	Returns:
+		The duration at the percentile
+ */
+
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+/*  The function formats a number of bytes with a binary unit
+	Arguments:
+		n: The number of bytes
+	Returns:
+		A string like 1.5 MiB
+ */
+
+func formatBytes(n int64) string {
+	if n < 1024 {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	value := float64(n)
+	units := []string{"KiB", "MiB", "GiB", "TiB"}
+	unit := -1
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
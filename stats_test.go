@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFetchPageDuration1(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer testServer.Close()
+	testCrawler := newCrawler(defaultCrawlConfig(testServer.URL))
+	testResult := testCrawler.fetchPage(context.Background(), testServer.URL)
+	if testResult.duration < 20*time.Millisecond {
+		fmt.Println("fetchPage recorded an invalid duration")
+		fmt.Println(testResult.duration)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for fetchPage duration passed")
+	}
+}
+
+func TestCrawlStatisticsRecord1(t *testing.T) {
+	testStats := newCrawlStatistics()
+	testStats.record(fetchResult{statusCode: 200, contentType: "text/html", wireSize: 10, size: 30, duration: time.Second})
+	testStats.record(fetchResult{statusCode: 200, contentType: "text/html", wireSize: 5, size: 5, duration: time.Second})
+	testStats.record(fetchResult{statusCode: 404, duration: time.Second})
+	testStats.record(fetchResult{err: errors.New("timeout"), duration: time.Second})
+	if testStats.statusCodes[200] != 2 || testStats.statusCodes[404] != 1 || testStats.errors != 1 {
+		fmt.Println("record returned an invalid status code histogram")
+		fmt.Println(testStats.statusCodes, testStats.errors)
+		t.Fail()
+	} else if testStats.contentTypes["text/html"] != 2 || testStats.contentTypes["unknown"] != 1 {
+		fmt.Println("record returned invalid content type counts")
+		fmt.Println(testStats.contentTypes)
+		t.Fail()
+	} else if testStats.wireBytes != 15 || testStats.decodedBytes != 35 || len(testStats.latencies) != 4 {
+		fmt.Println("record returned invalid byte counts")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for crawlStatistics record passed")
+	}
+}
+
+func TestCrawlStatisticsRecord2(t *testing.T) {
+	testStats := newCrawlStatistics()
+	for i := 1; i <= 3*maxLatencySamples; i++ {
+		testStats.record(fetchResult{statusCode: 200, duration: time.Duration(i) * time.Millisecond})
+	}
+	testLarge := 0
+	for _, testLatency := range testStats.latencies {
+		if testLatency > time.Duration(maxLatencySamples)*time.Millisecond {
+			testLarge++
+		}
+	}
+	if len(testStats.latencies) != maxLatencySamples || testStats.latencyCount != 3*maxLatencySamples ||
+		testStats.maxLatency != time.Duration(3*maxLatencySamples)*time.Millisecond {
+		fmt.Println("record did not bound the latency sample")
+		fmt.Println(len(testStats.latencies), testStats.latencyCount, testStats.maxLatency)
+		t.Fail()
+	} else if testLarge < maxLatencySamples/2 {
+		fmt.Println("record did not replace the sampled latencies")
+		fmt.Println(testLarge)
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for crawlStatistics record passed")
+	}
+}
+
+func TestPercentile1(t *testing.T) {
+	var testLatencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		testLatencies = append(testLatencies, time.Duration(i)*time.Millisecond)
+	}
+	if percentile(testLatencies, 50) != 50*time.Millisecond || percentile(testLatencies, 99) != 99*time.Millisecond ||
+		percentile(testLatencies[:1], 90) != time.Millisecond {
+		fmt.Println("percentile returned an invalid value")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for percentile passed")
+	}
+}
+
+func TestReserveInsert1(t *testing.T) {
+	testConfig := defaultCrawlConfig("https://test.com")
+	testConfig.maxPages = 2
+	testCrawler := newCrawler(testConfig)
+	testResults := []bool{testCrawler.reserveInsert(), testCrawler.reserveInsert(), testCrawler.reserveInsert()}
+	if !testResults[0] || !testResults[1] || testResults[2] {
+		fmt.Println("reserveInsert did not enforce MAX_PAGES")
+		fmt.Println(testResults)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for reserveInsert passed")
+	}
+}
+
+func TestFormatBytes1(t *testing.T) {
+	if formatBytes(512) != "512 B" || formatBytes(1536) != "1.5 KiB" || formatBytes(3<<20) != "3.0 MiB" {
+		fmt.Println("formatBytes returned an invalid value")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for formatBytes passed")
+	}
+}
+
+func TestGetProgressInterval1(t *testing.T) {
+	_ = os.Setenv("PROGRESS_INTERVAL", "2s")
+	testInterval := getProgressInterval()
+	_ = os.Setenv("PROGRESS_INTERVAL", "")
+	if testInterval != 2*time.Second || getProgressInterval() != 0 {
+		fmt.Println("getProgressInterval returned an invalid value")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for getProgressInterval passed")
+	}
+}
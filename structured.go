@@ -0,0 +1,483 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+/* structuredDataConfig holds the options of the structured data extraction
+	enabled: Set when STRUCTURED_DATA_OUTPUT is set, extracts the JSON-LD, Microdata and RDFa of every html page
+	output: Set from STRUCTURED_DATA_OUTPUT, the JSON lines file the structured data of every page is appended to
+ */
+
+type structuredDataConfig struct {
+	enabled bool
+	output  string
+}
+
+/* structuredData holds the structured data items of a page normalized to JSON-LD objects. Microdata and
+	RDFa items get an @context with the vocabulary of their type and an @type with the name of the type
+	JSONLD: The objects of every application/ld+json script, the items of a top level array are split
+	Microdata: The items with an itemscope that are not the property of another item
+	RDFa: The items with a typeof that are not the property of another item
+	Errors: The validation errors of the malformed JSON-LD scripts
+ */
+
+type structuredData struct {
+	JSONLD    []map[string]interface{} `json:"json_ld,omitempty"`
+	Microdata []map[string]interface{} `json:"microdata,omitempty"`
+	RDFa      []map[string]interface{} `json:"rdfa,omitempty"`
+	Errors    []string                 `json:"errors,omitempty"`
+}
+
+/* structuredDataRecord is a line of the STRUCTURED_DATA_OUTPUT file
+ */
+
+type structuredDataRecord struct {
+	URI string `json:"uri"`
+	structuredData
+}
+
+/* structuredDataState holds the structured data found by a Crawler for the summary
+	dataPages: The number of pages with at least one item
+	dataErrors: The number of JSON-LD validation errors
+	dataTypes: The number of items of every type
+ */
+
+type structuredDataState struct {
+	dataLock   sync.Mutex
+	dataPages  int
+	dataErrors int
+	dataTypes  map[string]int
+}
+
+//The elements whose Microdata value is the url of their src attribute
+var microdataSources = map[atom.Atom]bool{atom.Audio: true, atom.Embed: true, atom.Iframe: true, atom.Img: true,
+	atom.Source: true, atom.Track: true, atom.Video: true}
+
+/*  The function checks the value of the STRUCTURED_DATA_OUTPUT env variable which is the JSON lines file the
+	structured data of every page is written to
+	Returns:
+		A structuredDataConfig with the options set by the user
+ */
+
+func getStructuredDataConfig() structuredDataConfig {
+	output := strings.TrimSpace(os.Getenv("STRUCTURED_DATA_OUTPUT"))
+	return structuredDataConfig{enabled: output != "", output: output}
+}
+
+/*  The function extracts the JSON-LD, Microdata and RDFa items of an html page
+	Arguments:
+		doc: The parsed html page
+		pageURI: The uri the page was served from, used to resolve the URLs of the properties
+	Returns:
+		The structuredData of the page
+ */
+
+func extractStructuredData(doc *html.Node, pageURI string) structuredData {
+	var data structuredData
+	blocks := 0
+	var walk func(n *html.Node, vocab string)
+	walk = func(n *html.Node, vocab string) {
+		if n.Type == html.ElementNode {
+			if value, ok := nodeAttr(n, "vocab"); ok {
+				vocab = value
+			}
+			if n.DataAtom == atom.Script {
+				if value, _ := nodeAttr(n, "type"); strings.EqualFold(strings.TrimSpace(value), "application/ld+json") {
+					blocks++
+					data.addJSONLD(nodeText(n), blocks)
+				}
+				return
+			}
+			if _, ok := nodeAttr(n, "itemscope"); ok && !hasNodeAttr(n, "itemprop") {
+				data.Microdata = append(data.Microdata, microdataItem(n, pageURI))
+			}
+			if _, ok := nodeAttr(n, "typeof"); ok && !hasNodeAttr(n, "property") {
+				data.RDFa = append(data.RDFa, rdfaItem(n, vocab, pageURI))
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, vocab)
+		}
+	}
+	walk(doc, "")
+	return data
+}
+
+/*  The function validates a JSON-LD script and adds its objects. Scripts that are not valid JSON or do not
+	hold objects are reported as errors, objects without @context or @type are added and reported
+	Arguments:
+		block: The text of the script
+		index: The position of the script among the JSON-LD scripts of the page starting at 1
+ */
+
+func (data *structuredData) addJSONLD(block string, index int) {
+	prefix := "JSON-LD block " + strconv.Itoa(index) + ": "
+	var value interface{}
+	if err := json.Unmarshal([]byte(block), &value); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			data.Errors = append(data.Errors, prefix+err.Error()+" at offset "+strconv.FormatInt(syntaxErr.Offset, 10))
+		} else {
+			data.Errors = append(data.Errors, prefix+err.Error())
+		}
+		return
+	}
+	values := []interface{}{value}
+	if list, ok := value.([]interface{}); ok {
+		values = list
+	}
+	for _, value := range values {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			data.Errors = append(data.Errors, prefix+"expected an object")
+			continue
+		}
+		if _, ok := object["@context"]; !ok {
+			data.Errors = append(data.Errors, prefix+"missing @context")
+		}
+		if object["@type"] == nil && object["@graph"] == nil {
+			data.Errors = append(data.Errors, prefix+"missing @type")
+		}
+		data.JSONLD = append(data.JSONLD, object)
+	}
+}
+
+/*  The function converts an element with an itemscope to an item with its properties. Properties of
+	elements referenced with itemref are not collected
+	Arguments:
+		n: The element with the itemscope
+		pageURI: The uri the page was served from
+	Returns:
+		A map with the @context, @type, @id and properties of the item
+ */
+
+func microdataItem(n *html.Node, pageURI string) map[string]interface{} {
+	item := map[string]interface{}{}
+	itemType, _ := nodeAttr(n, "itemtype")
+	setItemType(item, strings.Fields(itemType), "")
+	if id, ok := nodeAttr(n, "itemid"); ok {
+		item["@id"] = resolveProperty(id, pageURI)
+	}
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			_, scoped := nodeAttr(child, "itemscope")
+			if names, ok := nodeAttr(child, "itemprop"); ok {
+				var value interface{}
+				if scoped {
+					value = microdataItem(child, pageURI)
+				} else {
+					value = microdataValue(child, pageURI)
+				}
+				for _, name := range strings.Fields(names) {
+					addProperty(item, name, value)
+				}
+			}
+			if !scoped {
+				collect(child)
+			}
+		}
+	}
+	collect(n)
+	return item
+}
+
+/*  The function returns the Microdata value of an element with an itemprop
+	Arguments:
+		n: The element
+		pageURI: The uri the page was served from
+	Returns:
+		A string with the attribute holding the value for the element or its text
+ */
+
+func microdataValue(n *html.Node, pageURI string) string {
+	switch {
+	case n.DataAtom == atom.Meta:
+		value, _ := nodeAttr(n, "content")
+		return value
+	case microdataSources[n.DataAtom]:
+		value, _ := nodeAttr(n, "src")
+		return resolveProperty(value, pageURI)
+	case n.DataAtom == atom.A || n.DataAtom == atom.Area || n.DataAtom == atom.Link:
+		value, _ := nodeAttr(n, "href")
+		return resolveProperty(value, pageURI)
+	case n.DataAtom == atom.Object:
+		value, _ := nodeAttr(n, "data")
+		return resolveProperty(value, pageURI)
+	case n.DataAtom == atom.Data || n.DataAtom == atom.Meter:
+		value, _ := nodeAttr(n, "value")
+		return value
+	case n.DataAtom == atom.Time && hasNodeAttr(n, "datetime"):
+		value, _ := nodeAttr(n, "datetime")
+		return value
+	}
+	return nodeText(n)
+}
+
+/*  The function converts an element with a typeof to an item with its RDFa properties
+	Arguments:
+		n: The element with the typeof
+		vocab: The vocabulary set by the vocab attribute of the element or its ancestors
+		pageURI: The uri the page was served from
+	Returns:
+		A map with the @context, @type, @id and properties of the item
+ */
+
+func rdfaItem(n *html.Node, vocab string, pageURI string) map[string]interface{} {
+	item := map[string]interface{}{}
+	types, _ := nodeAttr(n, "typeof")
+	setItemType(item, strings.Fields(types), vocab)
+	for _, key := range []string{"resource", "about"} {
+		if id, ok := nodeAttr(n, key); ok {
+			item["@id"] = resolveProperty(id, pageURI)
+			break
+		}
+	}
+	var collect func(n *html.Node, vocab string)
+	collect = func(n *html.Node, vocab string) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			childVocab := vocab
+			if value, ok := nodeAttr(child, "vocab"); ok {
+				childVocab = value
+			}
+			_, typed := nodeAttr(child, "typeof")
+			if names, ok := nodeAttr(child, "property"); ok {
+				var value interface{}
+				if typed {
+					value = rdfaItem(child, childVocab, pageURI)
+				} else {
+					value = rdfaValue(child, pageURI)
+				}
+				for _, name := range strings.Fields(names) {
+					addProperty(item, name, value)
+				}
+			}
+			if !typed {
+				collect(child, childVocab)
+			}
+		}
+	}
+	collect(n, vocab)
+	return item
+}
+
+/*  The function returns the RDFa value of an element with a property
+	Arguments:
+		n: The element
+		pageURI: The uri the page was served from
+	Returns:
+		A string with the content, the resolved href, src or resource, the datetime or the text of the element
+ */
+
+func rdfaValue(n *html.Node, pageURI string) string {
+	if value, ok := nodeAttr(n, "content"); ok {
+		return value
+	}
+	for _, key := range []string{"href", "src", "resource"} {
+		if value, ok := nodeAttr(n, key); ok {
+			return resolveProperty(value, pageURI)
+		}
+	}
+	if value, ok := nodeAttr(n, "datetime"); ok {
+		return value
+	}
+	return nodeText(n)
+}
+
+/*  The function sets the @context and @type of an item from its type URLs, e.g. https://schema.org/Product
+	is split into the https://schema.org context and the Product type
+	Arguments:
+		item: The item
+		types: The types of the item
+		vocab: The vocabulary used for the types that are not URLs
+ */
+
+func setItemType(item map[string]interface{}, types []string, vocab string) {
+	var names []interface{}
+	for _, itemType := range types {
+		index := strings.LastIndexAny(itemType, "/#")
+		if strings.Contains(itemType, "://") && index < len(itemType)-1 {
+			if vocab == "" {
+				vocab = itemType[:index]
+			}
+			itemType = itemType[index+1:]
+		}
+		names = append(names, itemType)
+	}
+	if vocab != "" {
+		item["@context"] = vocab
+	}
+	if len(names) == 1 {
+		item["@type"] = names[0]
+	} else if len(names) > 1 {
+		item["@type"] = names
+	}
+}
+
+/*  The function adds a value to a property of an item. A property with several values holds an array
+	Arguments:
+		item: The item
+		name: The name of the property
+		value: The value to add
+ */
+
+func addProperty(item map[string]interface{}, name string, value interface{}) {
+	switch current := item[name].(type) {
+	case nil:
+		item[name] = value
+	case []interface{}:
+		item[name] = append(current, value)
+	default:
+		item[name] = []interface{}{current, value}
+	}
+}
+
+/*  The function resolves a URL value against the uri of the page
+	Arguments:
+		value: The value of the attribute
+		pageURI: The uri the page was served from
+	Returns:
+		A string with the absolute URL, empty if the value is empty
+ */
+
+func resolveProperty(value string, pageURI string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	if absolute := absoluteURL(value, pageURI); absolute != "" {
+		return absolute
+	}
+	return value
+}
+
+/*  The function returns the value of an attribute of an element
+	Arguments:
+		n: The element
+		key: The name of the attribute
+	Returns:
+		A string with the value of the attribute
+		A true value if the element has the attribute
+ */
+
+func nodeAttr(n *html.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+/*  The function checks if an element has an attribute
+ */
+
+func hasNodeAttr(n *html.Node, key string) bool {
+	_, ok := nodeAttr(n, key)
+	return ok
+}
+
+/*  The function returns the text of an element with the whitespace collapsed
+	Arguments:
+		n: The element
+	Returns:
+		A string with the text of the element and its descendants
+ */
+
+func nodeText(n *html.Node) string {
+	var words []string
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			words = append(words, strings.Fields(n.Data)...)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(n)
+	return strings.Join(words, " ")
+}
+
+/*  The function records the structured data of a page for the summary and appends it to the
+	STRUCTURED_DATA_OUTPUT file. Pages without items or errors are not written
+	Arguments:
+		pageURI: The uri the page was served from
+		data: The structuredData of the page
+ */
+
+func (c *Crawler) recordStructuredData(pageURI string, data structuredData) {
+	items := append(append(append([]map[string]interface{}(nil), data.JSONLD...), data.Microdata...), data.RDFa...)
+	if len(items) == 0 && len(data.Errors) == 0 {
+		return
+	}
+	line, err := json.Marshal(structuredDataRecord{URI: pageURI, structuredData: data})
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+	if c.dataTypes == nil {
+		c.dataTypes = map[string]int{}
+	}
+	if len(items) > 0 {
+		c.dataPages++
+	}
+	c.dataErrors += len(data.Errors)
+	for _, item := range items {
+		switch itemType := item["@type"].(type) {
+		case string:
+			c.dataTypes[itemType]++
+		case []interface{}:
+			for _, name := range itemType {
+				c.dataTypes[fmt.Sprint(name)]++
+			}
+		}
+	}
+	if c.config.structured.output == "" {
+		return
+	}
+	if err != nil {
+		logger.Error("error while encoding the structured data", "uri", pageURI, "error", err)
+		return
+	}
+	file, err := os.OpenFile(c.config.structured.output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logger.Error("error opening the structured data file", "path", c.config.structured.output, "error", err)
+		return
+	}
+	defer file.Close()
+	_, _ = file.Write(append(line, '\n'))
+}
+
+/*  The function prints the number of pages with structured data, the JSON-LD errors and the number of
+	items of every type
+ */
+
+func (c *Crawler) printStructuredDataSummary() {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+	_, _ = fmt.Fprintln(c.output, "Structured Data: "+strconv.Itoa(c.dataPages)+" pages, "+strconv.Itoa(c.dataErrors)+" JSON-LD errors")
+	var types []string
+	for itemType := range c.dataTypes {
+		types = append(types, itemType)
+	}
+	sort.Strings(types)
+	for _, itemType := range types {
+		_, _ = fmt.Fprintln(c.output, "\t"+itemType+": "+strconv.Itoa(c.dataTypes[itemType]))
+	}
+}
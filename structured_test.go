@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractStructuredData1(t *testing.T) {
+	testData := extractStructuredData(parseDocument(strings.NewReader(`<html><head>`+
+		`<script type="application/ld+json">{"@context": "https://schema.org", "@type": "Product", "name": "Lamp"}</script>`+
+		`<script type="Application/LD+JSON">[{"@context": "https://schema.org", "@type": "Brand"}, {"name": "No type"}, 3]</script>`+
+		`<script type="application/ld+json">{"@context": "https://schema.org", "@type": "Offer",}</script>`+
+		`<script>var ignored = {"@type": "Thing"};</script></head></html>`)), "https://test.com/lamp")
+	testErrors := []string{
+		"JSON-LD block 2: missing @context",
+		"JSON-LD block 2: missing @type",
+		"JSON-LD block 2: expected an object",
+		"JSON-LD block 3: invalid character '}' looking for beginning of object key string at offset 53",
+	}
+	if len(testData.JSONLD) != 3 || testData.JSONLD[0]["name"] != "Lamp" || testData.JSONLD[1]["@type"] != "Brand" {
+		fmt.Println("extractStructuredData returned invalid JSON-LD items")
+		fmt.Println(testData.JSONLD)
+		t.Fail()
+	} else if !reflect.DeepEqual(testData.Errors, testErrors) {
+		fmt.Println("extractStructuredData returned invalid JSON-LD errors")
+		fmt.Println(strings.Join(testData.Errors, "\n"))
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for extractStructuredData passed")
+	}
+}
+
+func TestExtractStructuredData2(t *testing.T) {
+	testData := extractStructuredData(parseDocument(strings.NewReader(`<div itemscope itemtype="https://schema.org/Product" itemid="/lamp">`+
+		`<h1 itemprop="name"> Desk   lamp </h1><img itemprop="image" src="lamp.jpg">`+
+		`<span itemprop="color">red</span><span itemprop="color">blue</span>`+
+		`<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">`+
+		`<meta itemprop="priceCurrency" content="EUR"><data itemprop="price" value="20">20 €</data></div>`+
+		`<div itemscope itemtype="https://schema.org/Review"><span itemprop="author">Ann</span></div></div>`)),
+		"https://test.com/shop/")
+	testItems, _ := json.Marshal(testData.Microdata)
+	testExpected := `[{"@context":"https://schema.org","@id":"https://test.com/lamp","@type":"Product","color":["red","blue"],` +
+		`"image":"https://test.com/shop/lamp.jpg","name":"Desk lamp",` +
+		`"offers":{"@context":"https://schema.org","@type":"Offer","price":"20","priceCurrency":"EUR"}},` +
+		`{"@context":"https://schema.org","@type":"Review","author":"Ann"}]`
+	if string(testItems) != testExpected {
+		fmt.Println("extractStructuredData returned invalid Microdata items")
+		fmt.Println(string(testItems))
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for extractStructuredData passed")
+	}
+}
+
+func TestExtractStructuredData3(t *testing.T) {
+	testData := extractStructuredData(parseDocument(strings.NewReader(`<body vocab="https://schema.org/"><div typeof="Person" resource="#ann">`+
+		`<span property="name">Ann</span><a property="url" href="/ann">Profile</a>`+
+		`<div property="address" typeof="PostalAddress"><span property="addressLocality">Berlin</span></div>`+
+		`<time property="birthDate" datetime="1990-01-02">2 January</time></div></body>`)), "https://test.com/team")
+	testItems, _ := json.Marshal(testData.RDFa)
+	testExpected := `[{"@context":"https://schema.org/","@id":"https://test.com/team#ann","@type":"Person",` +
+		`"address":{"@context":"https://schema.org/","@type":"PostalAddress","addressLocality":"Berlin"},` +
+		`"birthDate":"1990-01-02","name":"Ann","url":"https://test.com/ann"}]`
+	if string(testItems) != testExpected || len(testData.Microdata) != 0 || len(testData.JSONLD) != 0 {
+		fmt.Println("extractStructuredData returned invalid RDFa items")
+		fmt.Println(string(testItems))
+		t.Fail()
+	} else {
+		fmt.Println("Test 3 for extractStructuredData passed")
+	}
+}
+
+func TestRecordStructuredData1(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			_, _ = w.Write([]byte(`<a href="/a">A</a><a href="/b">B</a>` +
+				`<script type="application/ld+json">{"@context": "https://schema.org", "@type": "WebSite"}</script>`))
+			return
+		}
+		if r.URL.Path == "/a" {
+			_, _ = w.Write([]byte(`<div itemscope itemtype="https://schema.org/Product"><b itemprop="name">A</b></div>` +
+				`<script type="application/ld+json">{</script>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<p>No data</p>`))
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.structured = structuredDataConfig{enabled: true, output: filepath.Join(t.TempDir(), "data.jsonl")}
+	testCrawler := newCrawler(testConfig)
+	var testOutput bytes.Buffer
+	testCrawler.output = &testOutput
+	testResults := map[string]*structuredData{}
+	testCrawler.onPage = func(result pageResult) {
+		testResults[result.URI] = result.StructuredData
+	}
+	testCrawler.config.threads = 1
+	testCrawler.Run(context.Background())
+	testFile, _ := os.ReadFile(testConfig.structured.output)
+	var testRecords []structuredDataRecord
+	for _, line := range strings.Split(strings.TrimSpace(string(testFile)), "\n") {
+		var record structuredDataRecord
+		_ = json.Unmarshal([]byte(line), &record)
+		testRecords = append(testRecords, record)
+	}
+	testOutput.Reset()
+	testCrawler.printStructuredDataSummary()
+	if len(testRecords) != 2 || testRecords[1].URI != testServer.URL+"/a" || len(testRecords[1].Errors) != 1 {
+		fmt.Println("The structured data was not written to the output file")
+		fmt.Println(string(testFile))
+		t.Fail()
+	} else if testResults[testServer.URL] == nil || len(testResults[testServer.URL].JSONLD) != 1 {
+		fmt.Println("The page results did not include the structured data")
+		t.Fail()
+	} else if testOutput.String() != "Structured Data: 2 pages, 1 JSON-LD errors\n\tProduct: 1\n\tWebSite: 1\n" {
+		fmt.Println("printStructuredDataSummary printed an invalid summary")
+		fmt.Println(testOutput.String())
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for recordStructuredData passed")
+	}
+}
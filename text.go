@@ -0,0 +1,406 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+/* textConfig holds the options of the readable text extraction
+	enabled: Set when TEXT_OUTPUT is set, extracts the main text of every html page
+	output: Set from TEXT_OUTPUT, the JSON lines file the text of every page is appended to
+ */
+
+type textConfig struct {
+	enabled bool
+	output  string
+}
+
+/* pageText holds the readable content of an html page for a search index
+	Title: The text of the first title element
+	Language: The language detected from the main text as an ISO 639-1 code, empty if it is not known
+	DeclaredLanguage: The lang attribute of the html element
+	Text: The headings, paragraphs and list items of the main content separated by blank lines
+	WordCount: The number of words of Text
+ */
+
+type pageText struct {
+	URI              string `json:"uri,omitempty"`
+	Title            string `json:"title"`
+	Language         string `json:"language,omitempty"`
+	DeclaredLanguage string `json:"declared_language,omitempty"`
+	Text             string `json:"text"`
+	WordCount        int    `json:"word_count"`
+}
+
+/* textState holds the text extracted by a Crawler
+	textLock: Serializes the writes to the TEXT_OUTPUT file
+	textCounter: A counter to keep a track of the number of pages whose text was extracted
+ */
+
+type textState struct {
+	textLock    sync.Mutex
+	textCounter int64
+}
+
+//The elements that never hold the main content of a page
+var boilerplateElements = map[atom.Atom]bool{atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Nav: true, atom.Aside: true, atom.Footer: true, atom.Form: true, atom.Iframe: true,
+	atom.Svg: true, atom.Button: true, atom.Select: true, atom.Head: true}
+
+//The ARIA roles of the navigation, header, footer and sidebars of a page
+var boilerplateRoles = map[string]bool{"navigation": true, "banner": true, "contentinfo": true,
+	"complementary": true, "search": true, "dialog": true}
+
+//The class and id names of the blocks that are not part of the main content
+var boilerplateNames = regexp.MustCompile(`(?i)\b(nav|navbar|navigation|menu|footer|sidebar|breadcrumbs?|comments?|cookies?|banner|share|social|related|advert|ads|popup|modal|newsletter)\b`)
+
+//The elements that start a new block of text
+var blockElements = map[atom.Atom]bool{atom.Address: true, atom.Article: true, atom.Blockquote: true,
+	atom.Br: true, atom.Dd: true, atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Figcaption: true,
+	atom.Figure: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true, atom.Ol: true, atom.P: true, atom.Pre: true,
+	atom.Section: true, atom.Table: true, atom.Td: true, atom.Th: true, atom.Tr: true, atom.Ul: true}
+
+//The elements dropped when most of their text is the text of links, e.g. menus and lists of related pages
+var linkListElements = map[atom.Atom]bool{atom.Div: true, atom.Dl: true, atom.Ol: true, atom.Section: true,
+	atom.Table: true, atom.Ul: true}
+
+//The minimum number of characters of a paragraph to count for the main content
+const minParagraphLength = 25
+
+/*  The function checks the value of the TEXT_OUTPUT env variable which is the JSON lines file the readable
+	text of every page is written to
+	Returns:
+		A textConfig with the options set by the user
+ */
+
+func getTextConfig() textConfig {
+	output := strings.TrimSpace(os.Getenv("TEXT_OUTPUT"))
+	return textConfig{enabled: output != "", output: output}
+}
+
+/*  The function extracts the readable content of an html page. The main content is the main element, the
+	article with the most text or the element with the most paragraph text. Scripts, styles, navigation,
+	footers, sidebars and lists of links are removed, as is the header of a page without a main content element
+	Arguments:
+		doc: The parsed html page
+	Returns:
+		A pageText with the main text and its language
+ */
+
+func extractText(doc *html.Node) pageText {
+	var text pageText
+	if root := findElement(doc, atom.Html); root != nil {
+		text.DeclaredLanguage, _ = nodeAttr(root, "lang")
+		text.DeclaredLanguage = strings.TrimSpace(text.DeclaredLanguage)
+	}
+	if title := findElement(doc, atom.Title); title != nil {
+		text.Title = nodeText(title)
+	}
+	var blocks []string
+	var words []string
+	flush := func() {
+		if len(words) > 0 {
+			blocks = append(blocks, strings.Join(words, " "))
+			text.WordCount += len(words)
+			words = nil
+		}
+	}
+	root := mainContent(doc)
+	pageHeader := root == doc || root.DataAtom == atom.Body
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			words = append(words, strings.Fields(n.Data)...)
+			return
+		}
+		if n.Type == html.ElementNode && (isBoilerplate(n) || (pageHeader && n.DataAtom == atom.Header) ||
+			(linkListElements[n.DataAtom] && linkDensity(n) > 0.5)) {
+			return
+		}
+		block := n.Type == html.ElementNode && blockElements[n.DataAtom]
+		if block {
+			flush()
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if block {
+			flush()
+		}
+	}
+	walk(root)
+	flush()
+	text.Text = strings.Join(blocks, "\n\n")
+	text.Language = detectLanguage(text.Text)
+	return text
+}
+
+/*  The function finds the element holding the main content of a page
+	Arguments:
+		doc: The parsed html page
+	Returns:
+		The main element, the article with the most text, the element with the highest paragraph score or
+		the body of the page
+ */
+
+func mainContent(doc *html.Node) *html.Node {
+	var mainElement, article, body *html.Node
+	articleLength := 0
+	scores := map[*html.Node]float64{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				walk(child)
+			}
+			return
+		}
+		if isBoilerplate(n) {
+			return
+		}
+		role, _ := nodeAttr(n, "role")
+		switch {
+		case mainElement == nil && (n.DataAtom == atom.Main || strings.TrimSpace(role) == "main"):
+			mainElement = n
+		case n.DataAtom == atom.Article:
+			if length := len(nodeText(n)); length > articleLength {
+				article, articleLength = n, length
+			}
+		case n.DataAtom == atom.Body:
+			body = n
+		case n.DataAtom == atom.P || n.DataAtom == atom.Pre:
+			if length := len(nodeText(n)); length >= minParagraphLength && n.Parent != nil {
+				score := 1 + float64(strings.Count(nodeText(n), ",")) + min(float64(length)/100, 3)
+				scores[n.Parent] += score
+				if n.Parent.Parent != nil {
+					scores[n.Parent.Parent] += score / 2
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	if mainElement != nil {
+		return mainElement
+	}
+	if article != nil {
+		return article
+	}
+	var best *html.Node
+	for n, score := range scores {
+		if best == nil || score > scores[best] || (score == scores[best] && len(nodeText(n)) > len(nodeText(best))) {
+			best = n
+		}
+	}
+	if best != nil {
+		return best
+	}
+	if body != nil {
+		return body
+	}
+	return doc
+}
+
+/*  The function checks if an element is part of the navigation, header, footer or sidebars of a page
+	Arguments:
+		n: The element
+	Returns:
+		A true value if the element and its descendants are not part of the main content
+ */
+
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateElements[n.DataAtom] {
+		return true
+	}
+	if _, hidden := nodeAttr(n, "hidden"); hidden {
+		return true
+	}
+	if role, _ := nodeAttr(n, "role"); boilerplateRoles[strings.TrimSpace(role)] {
+		return true
+	}
+	if n.DataAtom == atom.Body || n.DataAtom == atom.Main || n.DataAtom == atom.Article {
+		return false
+	}
+	class, _ := nodeAttr(n, "class")
+	id, _ := nodeAttr(n, "id")
+	return boilerplateNames.MatchString(class + " " + id)
+}
+
+/*  The function returns the share of the text of an element that is inside links
+	Arguments:
+		n: The element
+	Returns:
+		A float64 between 0 and 1, 0 for an element without text
+ */
+
+func linkDensity(n *html.Node) float64 {
+	total := len(nodeText(n))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			linked += len(nodeText(n))
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return float64(linked) / float64(total)
+}
+
+/*  The function returns the first element of a kind in a document
+	Arguments:
+		n: The node to search from
+		element: The atom of the element
+	Returns:
+		The first matching element in document order, nil if there is none
+ */
+
+func findElement(n *html.Node, element atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == element {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, element); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+/*  The function counts the text of a page and appends it to the TEXT_OUTPUT file
+	Arguments:
+		text: The pageText of the page with its uri
+ */
+
+func (c *Crawler) writeText(text pageText) {
+	atomic.AddInt64(&c.textCounter, 1)
+	if c.config.text.output == "" {
+		return
+	}
+	line, err := json.Marshal(text)
+	if err != nil {
+		logger.Error("error while encoding the text", "uri", text.URI, "error", err)
+		return
+	}
+	c.textLock.Lock()
+	defer c.textLock.Unlock()
+	file, err := os.OpenFile(c.config.text.output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logger.Error("error opening the text output file", "path", c.config.text.output, "error", err)
+		return
+	}
+	defer file.Close()
+	_, _ = file.Write(append(line, '\n'))
+}
+
+//The languages written in their own script
+var scriptLanguages = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "ko"}, {unicode.Hiragana, "ja"}, {unicode.Katakana, "ja"}, {unicode.Han, "zh"},
+	{unicode.Greek, "el"}, {unicode.Arabic, "ar"}, {unicode.Hebrew, "he"}, {unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"}, {unicode.Cyrillic, "ru"},
+}
+
+//The most frequent words of the languages written in the latin script
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "in", "is", "that", "it", "for", "with", "as", "was", "on", "are", "this", "be", "by", "not", "you", "or"},
+	"de": {"der", "die", "und", "den", "von", "zu", "das", "mit", "sich", "des", "auf", "für", "ist", "im", "dem", "nicht", "ein", "eine", "auch", "wird"},
+	"fr": {"le", "la", "les", "et", "des", "est", "un", "une", "du", "que", "qui", "dans", "pour", "pas", "sur", "au", "avec", "par", "ce", "sont"},
+	"es": {"el", "la", "de", "que", "y", "en", "los", "del", "se", "las", "por", "un", "para", "con", "una", "su", "al", "es", "lo", "como"},
+	"it": {"il", "di", "che", "e", "la", "per", "un", "una", "sono", "del", "non", "della", "le", "si", "con", "da", "gli", "è", "al", "nel"},
+	"pt": {"de", "que", "e", "o", "do", "da", "em", "um", "para", "com", "não", "uma", "os", "no", "se", "na", "por", "mais", "as", "dos"},
+	"nl": {"de", "en", "van", "het", "een", "is", "dat", "op", "te", "zijn", "voor", "met", "die", "niet", "aan", "er", "om", "ook", "als", "bij"},
+	"sv": {"och", "att", "det", "som", "en", "på", "är", "av", "för", "med", "till", "den", "har", "de", "inte", "om", "ett", "var", "jag", "men"},
+	"pl": {"i", "w", "na", "z", "się", "nie", "do", "to", "że", "jest", "o", "jak", "ale", "po", "co", "tak", "za", "od", "są", "czy"},
+	"tr": {"ve", "bir", "bu", "da", "de", "için", "ile", "çok", "olan", "gibi", "daha", "ne", "olarak", "en", "ama", "kadar", "var", "sonra", "mi", "değil"},
+}
+
+//The minimum number of words needed to detect the language of a latin text
+const minLanguageWords = 5
+
+/*  The function detects the language of a text from the script of its letters or, for texts in the latin
+	script, from the share of the most frequent words of every language
+	Arguments:
+		text: The text
+	Returns:
+		A string with the ISO 639-1 code of the language, empty if it can not be detected
+ */
+
+func detectLanguage(text string) string {
+	scripts := map[string]int{}
+	latin, letters := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, script := range scriptLanguages {
+			if unicode.Is(script.table, r) {
+				scripts[script.language]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	if latin*2 < letters {
+		if scripts["ja"] > 0 {
+			return "ja"
+		}
+		best := ""
+		for _, script := range scriptLanguages {
+			if scripts[script.language] > scripts[best] {
+				best = script.language
+			}
+		}
+		return best
+	}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+	if len(words) < minLanguageWords {
+		return ""
+	}
+	counts := map[string]int{}
+	for _, word := range words {
+		counts[word]++
+	}
+	best, bestScore, secondScore := "", 0, 0
+	for language, stopwords := range languageStopwords {
+		score := 0
+		for _, stopword := range stopwords {
+			score += counts[stopword]
+		}
+		if score > bestScore || (score == bestScore && language < best) {
+			best, bestScore, secondScore = language, score, max(bestScore, secondScore)
+		} else if score > secondScore {
+			secondScore = score
+		}
+	}
+	if bestScore == 0 || bestScore == secondScore {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractText1(t *testing.T) {
+	testText := extractText(parseDocument(strings.NewReader(`<html lang=" en-GB "><head><title>News</title>` +
+		`<style>p { color: red }</style></head><body><header><a href="/">Home</a></header>` +
+		`<nav><a href="/a">A</a></nav><main><h1>The headline</h1><script>var x = 1;</script>` +
+		`<p>The first paragraph of the story.</p><div class="share-buttons">Share this</div>` +
+		`<ul><li><a href="/b">Related story</a></li><li><a href="/c">Other story</a></li></ul>` +
+		`<p>The second <b>paragraph</b>.</p></main><footer>Copyright</footer></body></html>`)))
+	testExpected := pageText{
+		Title:            "News",
+		Language:         "en",
+		DeclaredLanguage: "en-GB",
+		Text:             "The headline\n\nThe first paragraph of the story.\n\nThe second paragraph .",
+		WordCount:        12,
+	}
+	if testText != testExpected {
+		fmt.Println("extractText returned an invalid text")
+		fmt.Printf("%+v\n", testText)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for extractText passed")
+	}
+}
+
+func TestExtractText2(t *testing.T) {
+	testText := extractText(parseDocument(strings.NewReader(`<body><header>Site name</header>` +
+		`<div id="sidebar"><p>Sign up for our newsletter, it is free and weekly.</p></div>` +
+		`<div class="content"><h2>Rezept</h2><p>Der Teig wird mit dem Mehl und den Eiern verrührt, das dauert nicht lange.</p>` +
+		`<p>Die Form ist mit Butter zu fetten, damit sich der Kuchen löst.</p></div></body>`)))
+	if testText.Language != "de" || testText.DeclaredLanguage != "" ||
+		!strings.HasPrefix(testText.Text, "Rezept\n\nDer Teig") || strings.Contains(testText.Text, "newsletter") {
+		fmt.Println("extractText did not find the main content")
+		fmt.Printf("%+v\n", testText)
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for extractText passed")
+	}
+}
+
+func TestDetectLanguage1(t *testing.T) {
+	testCases := map[string]string{
+		"Le chat est sur la table et il dort dans le salon avec les enfants": "fr",
+		"El perro de la casa come con los niños en el jardín":                "es",
+		"Привет, как дела? Это текст на русском языке":                       "ru",
+		"これは日本語の文章です":                                                        "ja",
+		"東京是日本的首都":                                                           "zh",
+		"Hello world":                                                        "",
+		"1234 5678":                                                          "",
+	}
+	for testInput, testLanguage := range testCases {
+		if detectLanguage(testInput) != testLanguage {
+			fmt.Println("detectLanguage returned " + detectLanguage(testInput) + " for " + testInput)
+			t.Fail()
+			return
+		}
+	}
+	fmt.Println("Test 1 for detectLanguage passed")
+}
+
+func TestWriteText1(t *testing.T) {
+	testServer := newSiteTestServer()
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.text = textConfig{enabled: true, output: filepath.Join(t.TempDir(), "text.jsonl")}
+	testCrawler := newCrawler(testConfig)
+	var testOutput bytes.Buffer
+	testCrawler.output = &testOutput
+	testResults := 0
+	testCrawler.onPage = func(result pageResult) {
+		if result.Text != nil {
+			testResults++
+		}
+	}
+	testCrawler.config.threads = 1
+	testCrawler.Run(context.Background())
+	testCrawler.printSummary()
+	testFile, err := os.Open(testConfig.text.output)
+	if err != nil {
+		fmt.Println("The text output file was not created")
+		t.Fail()
+		return
+	}
+	defer testFile.Close()
+	var testTexts []pageText
+	scanner := bufio.NewScanner(testFile)
+	for scanner.Scan() {
+		var text pageText
+		_ = json.Unmarshal(scanner.Bytes(), &text)
+		testTexts = append(testTexts, text)
+	}
+	if len(testTexts) != 3 || testTexts[0].URI != testServer.URL || testTexts[0].Text != "A B" || testResults != 3 {
+		fmt.Println("The text of the pages was not written")
+		fmt.Println(testTexts)
+		t.Fail()
+	} else if !strings.Contains(testOutput.String(), "Text Pages: 3\n") {
+		fmt.Println("The summary did not include the number of pages")
+		fmt.Println(testOutput.String())
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for writeText passed")
+	}
+}
+
+func TestWriteText2(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`<p>Home</p><a href="/missing">Missing</a><a href="/error">Error</a>`))
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`<p>Server error</p>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<p>Not found</p>`))
+		}
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.text = textConfig{enabled: true, output: filepath.Join(t.TempDir(), "text.jsonl")}
+	testCrawler := newCrawler(testConfig)
+	testCrawler.output = io.Discard
+	testCrawler.config.threads = 1
+	testCrawler.Run(context.Background())
+	testData, _ := os.ReadFile(testConfig.text.output)
+	testLines := strings.Split(strings.TrimSpace(string(testData)), "\n")
+	var testText pageText
+	_ = json.Unmarshal([]byte(testLines[0]), &testText)
+	if len(testLines) != 1 || testText.URI != testServer.URL || testText.Text != "Home\n\nMissing Error" {
+		fmt.Println("The text of the error pages was written")
+		fmt.Println(testLines)
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for writeText passed")
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/* workerPool holds the threads of a Crawler so that their number can be changed while the crawl is running
+	target: The number of threads the pool is resized to
+	running: The number of threads currently started
+	ctx: The context of the running crawl, nil before Run is called and after the frontier is closed
+ */
+
+type workerPool struct {
+	lock    sync.Mutex
+	target  int64
+	running int64
+	ctx     context.Context
+	workers sync.WaitGroup
+}
+
+/* concurrencyController adjusts the number of threads of a Crawler to the responses of the host. The number
+	of threads is increased by one after every window of successful responses and halved when the host
+	answers with 429 or 503, a request times out or the latency grows beyond latencyFactor times the lowest
+	latency observed
+	limit: The current number of threads, starts at maxLimit
+	maxLimit: The maximum number of threads, set from THREAD_COUNT
+	successes: The number of successful responses since the last change
+	cooldown: The number of responses ignored after a decrease, they were sent with the previous limit
+	latency: The moving average of the response latency
+	baseline: The lowest moving average of the latency observed
+ */
+
+type concurrencyController struct {
+	lock      sync.Mutex
+	limit     int64
+	maxLimit  int64
+	successes int64
+	cooldown  int64
+	latency   time.Duration
+	baseline  time.Duration
+}
+
+const latencyFactor = 3
+
+/*  The function checks the value of the ADAPTIVE_CONCURRENCY env variable and returns false if an invalid
+	value is provided or returns the value of the ADAPTIVE_CONCURRENCY env variable
+ */
+
+func checkAdaptiveConcurrency() bool {
+	if os.Getenv("ADAPTIVE_CONCURRENCY") == "" {
+		return false
+	}
+	adaptive, err := strconv.ParseBool(os.Getenv("ADAPTIVE_CONCURRENCY"))
+	if err != nil {
+		logger.Warn("invalid value specified for ADAPTIVE_CONCURRENCY env variable, the number of threads is fixed",
+			"value", os.Getenv("ADAPTIVE_CONCURRENCY"))
+		return false
+	}
+	return adaptive
+}
+
+/*  The function starts the threads of the crawl
+	Arguments:
+		ctx: The context of the crawl passed on to the threads
+ */
+
+func (c *Crawler) startWorkers(ctx context.Context) {
+	c.pool.lock.Lock()
+	defer c.pool.lock.Unlock()
+	c.pool.ctx = ctx
+	c.pool.target = atomic.LoadInt64(&c.config.threads)
+	if c.concurrency != nil {
+		c.pool.target = c.concurrency.current()
+	}
+	c.spawnWorkers()
+}
+
+/*  The function starts threads until the number of running threads reaches the target of the pool
+	The caller must hold the lock of the pool
+ */
+
+func (c *Crawler) spawnWorkers() {
+	for ; c.pool.ctx != nil && c.pool.running < c.pool.target; c.pool.running++ {
+		c.pool.workers.Add(1)
+		workerCount.Inc()
+		go c.work(c.pool.ctx)
+	}
+}
+
+/*  The function is run by every thread. It processes the URIs of the frontier until it is closed or
+	the pool has more threads than its target
+	Arguments:
+		ctx: The context of the crawl
+ */
+
+func (c *Crawler) work(ctx context.Context) {
+	defer c.pool.workers.Done()
+	defer workerCount.Dec()
+	for {
+		item, ok := c.frontier.pop()
+		if !ok {
+			break
+		}
+		c.process(ctx, item.uri, item.depth)
+		atomic.AddInt64(&c.processedCounter, 1)
+		frontierSize.Dec()
+		c.checkCounters()
+		if c.retireWorker() {
+			return
+		}
+	}
+	c.pool.lock.Lock()
+	c.pool.running--
+	c.pool.lock.Unlock()
+}
+
+/*  The function stops the calling thread if the pool has more threads than its target
+	Returns:
+		A true value if the thread has to stop
+ */
+
+func (c *Crawler) retireWorker() bool {
+	c.pool.lock.Lock()
+	defer c.pool.lock.Unlock()
+	if c.pool.running > c.pool.target {
+		c.pool.running--
+		return true
+	}
+	return false
+}
+
+/*  The function stops starting new threads once the frontier is closed
+ */
+
+func (c *Crawler) stopWorkers() {
+	c.pool.lock.Lock()
+	defer c.pool.lock.Unlock()
+	c.pool.ctx = nil
+}
+
+/*  The function changes the number of threads of the pool. New threads are started right away while
+	surplus threads stop once they have finished their current uri
+	Arguments:
+		threads: The number of threads, at least 1
+ */
+
+func (c *Crawler) resizeWorkers(threads int64) {
+	c.pool.lock.Lock()
+	defer c.pool.lock.Unlock()
+	c.pool.target = threads
+	c.spawnWorkers()
+}
+
+/*  The function returns the number of threads the crawl is running with
+	Returns:
+		An int64 with the target of the pool, or the configured number of threads before Run is called
+ */
+
+func (c *Crawler) workers() int64 {
+	c.pool.lock.Lock()
+	defer c.pool.lock.Unlock()
+	if c.pool.target == 0 {
+		return atomic.LoadInt64(&c.config.threads)
+	}
+	return c.pool.target
+}
+
+/*  The function changes the number of threads of the Crawler while it is running. In adaptive mode the
+	value is the maximum number of threads and the current number is lowered to it if needed
+	Arguments:
+		threads: The number of threads, values lower than 1 are raised to 1
+ */
+
+func (c *Crawler) SetWorkers(threads int64) {
+	if threads < 1 {
+		threads = 1
+	}
+	atomic.StoreInt64(&c.config.threads, threads)
+	if c.concurrency != nil {
+		threads = c.concurrency.setMax(threads)
+	}
+	c.pool.lock.Lock()
+	started := c.pool.target != 0
+	c.pool.lock.Unlock()
+	if started {
+		c.resizeWorkers(threads)
+	}
+}
+
+/*  The function creates a concurrencyController that starts with the maximum number of threads
+	Arguments:
+		maxLimit: The maximum number of threads
+	Returns:
+		A pointer to a concurrencyController
+ */
+
+func newConcurrencyController(maxLimit int64) *concurrencyController {
+	if maxLimit < 1 {
+		maxLimit = 1
+	}
+	return &concurrencyController{limit: maxLimit, maxLimit: maxLimit}
+}
+
+/*  The function returns the current number of threads
+	Returns:
+		An int64 with the number of threads
+ */
+
+func (controller *concurrencyController) current() int64 {
+	controller.lock.Lock()
+	defer controller.lock.Unlock()
+	return controller.limit
+}
+
+/*  The function changes the maximum number of threads
+	Arguments:
+		maxLimit: The maximum number of threads
+	Returns:
+		An int64 with the current number of threads, lowered to maxLimit if needed
+ */
+
+func (controller *concurrencyController) setMax(maxLimit int64) int64 {
+	controller.lock.Lock()
+	defer controller.lock.Unlock()
+	controller.maxLimit = maxLimit
+	if controller.limit > maxLimit {
+		controller.limit = maxLimit
+	}
+	return controller.limit
+}
+
+/*  The function records the response of a uri and adjusts the number of threads
+	Arguments:
+		result: The fetchResult of the uri
+	Returns:
+		An int64 with the new number of threads, 0 if the number did not change
+		A string with the reason of the change
+ */
+
+func (controller *concurrencyController) observe(result fetchResult) (int64, string) {
+	controller.lock.Lock()
+	defer controller.lock.Unlock()
+	reason := congestionReason(result)
+	if result.err == nil {
+		if controller.latency == 0 {
+			controller.latency = result.duration
+		} else {
+			controller.latency += (result.duration - controller.latency) / 8
+		}
+		if controller.baseline == 0 || controller.latency < controller.baseline {
+			controller.baseline = controller.latency
+		}
+		if reason == "" && controller.latency > latencyFactor*controller.baseline {
+			reason = "latency"
+		}
+	}
+	if controller.cooldown > 0 {
+		controller.cooldown--
+		return 0, ""
+	}
+	if reason != "" {
+		controller.successes = 0
+		if controller.limit == 1 {
+			controller.cooldown = 1
+			return 0, ""
+		}
+		controller.limit /= 2
+		controller.cooldown = controller.limit
+		return controller.limit, reason
+	}
+	if result.err != nil {
+		return 0, ""
+	}
+	controller.successes++
+	if controller.successes >= controller.limit && controller.limit < controller.maxLimit {
+		controller.successes = 0
+		controller.limit++
+		return controller.limit, "success"
+	}
+	return 0, ""
+}
+
+/*  The function checks if the response of a uri shows that the host is overloaded
+	Arguments:
+		result: The fetchResult of the uri
+	Returns:
+		A string with the reason, empty if the host is not overloaded
+ */
+
+func congestionReason(result fetchResult) string {
+	var netErr net.Error
+	switch {
+	case result.statusCode == http.StatusTooManyRequests:
+		return "too many requests"
+	case result.statusCode == http.StatusServiceUnavailable:
+		return "service unavailable"
+	case errors.Is(result.err, context.DeadlineExceeded) || (errors.As(result.err, &netErr) && netErr.Timeout()):
+		return "timeout"
+	}
+	return ""
+}
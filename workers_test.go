@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSetWorkers1(t *testing.T) {
+	testServer := newSiteTestServer()
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.threads = 1
+	testCrawler := newCrawler(testConfig)
+	testCrawler.output = io.Discard
+	testCrawler.Pause()
+	testDone := make(chan bool)
+	go func() {
+		testCrawler.Run(context.Background())
+		close(testDone)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	testCrawler.SetWorkers(3)
+	testCrawler.pool.lock.Lock()
+	testRunning := testCrawler.pool.running
+	testCrawler.pool.lock.Unlock()
+	testCrawler.SetWorkers(0)
+	testCrawler.Resume()
+	<-testDone
+	if testRunning != 3 {
+		fmt.Println("SetWorkers did not start new threads")
+		fmt.Println(testRunning)
+		t.Fail()
+	} else if testCrawler.workers() != 1 || testCrawler.snapshot().Visited != 4 || testCrawler.pool.running != 0 {
+		fmt.Println("The crawl did not finish after the threads were stopped")
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for SetWorkers passed")
+	}
+}
+
+func TestConcurrencyController1(t *testing.T) {
+	testController := newConcurrencyController(4)
+	testOK := fetchResult{statusCode: 200, duration: 10 * time.Millisecond}
+	testLimits := []int64{}
+	testLimit, _ := testController.observe(fetchResult{statusCode: 429})
+	testLimits = append(testLimits, testLimit)
+	testLimit, _ = testController.observe(fetchResult{statusCode: 503})
+	testLimits = append(testLimits, testLimit)
+	for i := 0; i < 4; i++ {
+		testController.observe(testOK)
+	}
+	if testLimits[0] != 2 || testLimits[1] != 0 || testController.current() != 3 {
+		fmt.Println("concurrencyController did not adjust the number of threads")
+		fmt.Println(testLimits)
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for concurrencyController passed")
+	}
+}
+
+func TestConcurrencyController2(t *testing.T) {
+	testController := newConcurrencyController(2)
+	testController.observe(fetchResult{statusCode: 200, duration: 10 * time.Millisecond})
+	testSlow := fetchResult{statusCode: 200, duration: time.Second}
+	var testLimit int64
+	var testReason string
+	for i := 0; i < 20 && testLimit == 0; i++ {
+		testLimit, testReason = testController.observe(testSlow)
+	}
+	if testLimit != 1 || testReason != "latency" || testController.setMax(5) != 1 {
+		fmt.Println("concurrencyController did not back off when the latency grew")
+		fmt.Println(testLimit, testReason)
+		t.Fail()
+	} else {
+		fmt.Println("Test 2 for concurrencyController passed")
+	}
+}
+
+func TestCrawlerAdaptive1(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			_, _ = w.Write([]byte(`<a href="/a">A</a><a href="/b">B</a>`))
+			return
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer testServer.Close()
+	testConfig := defaultCrawlConfig(testServer.URL)
+	testConfig.adaptive = true
+	testConfig.threads = 8
+	testCrawler := newCrawler(testConfig)
+	testCrawler.output = io.Discard
+	testCrawler.Run(context.Background())
+	if testCrawler.workers() != 4 || testCrawler.snapshot().Visited != 3 {
+		fmt.Println("The adaptive crawl did not back off after a 429 response")
+		fmt.Println(testCrawler.workers())
+		t.Fail()
+	} else {
+		fmt.Println("Test 1 for adaptive concurrency passed")
+	}
+}